@@ -1,13 +1,16 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"llm-fusion-engine/internal/api/admin"
+	"llm-fusion-engine/internal/api/middleware"
 	"llm-fusion-engine/internal/api/v1"
 	"llm-fusion-engine/internal/database"
+	"llm-fusion-engine/internal/providers"
 	"llm-fusion-engine/internal/services"
+	"llm-fusion-engine/internal/util"
 	"log"
-	"net/http"
 	"strings"
 	"time"
 
@@ -15,6 +18,20 @@ import (
 )
 
 func main() {
+	logBodyLimit := flag.Int("log-body-limit", services.DefaultLogBodyLimit, "bytes of each upstream response body kept in request logs")
+	responseCacheSize := flag.Int("response-cache-size", services.DefaultResponseCacheSize, "responses kept in memory by the response cache; older ones are read from the database")
+	semanticCacheSize := flag.Int("semantic-cache-size", services.DefaultSemanticCacheSize, "prompts indexed per model by the semantic cache")
+	breakerConfig := services.DefaultCircuitBreakerConfig()
+	flag.IntVar(&breakerConfig.FailureThreshold, "breaker-failures", breakerConfig.FailureThreshold, "consecutive upstream failures that open a circuit (0 disables)")
+	flag.Float64Var(&breakerConfig.ErrorRateThreshold, "breaker-error-rate", breakerConfig.ErrorRateThreshold, "error rate (0-1) within the breaker window that opens a circuit (0 disables)")
+	flag.IntVar(&breakerConfig.MinRequests, "breaker-min-requests", breakerConfig.MinRequests, "requests within the breaker window before its error rate is evaluated")
+	flag.DurationVar(&breakerConfig.Window, "breaker-window", breakerConfig.Window, "sliding window over which the breaker error rate is measured")
+	flag.DurationVar(&breakerConfig.OpenTimeout, "breaker-cooldown", breakerConfig.OpenTimeout, "how long an open circuit waits before admitting probe requests")
+	flag.IntVar(&breakerConfig.HalfOpenMaxProbes, "breaker-probes", breakerConfig.HalfOpenMaxProbes, "concurrent probe requests admitted by a half-open circuit")
+	flag.IntVar(&breakerConfig.HalfOpenSuccessThreshold, "breaker-probe-successes", breakerConfig.HalfOpenSuccessThreshold, "successful probes that close a half-open circuit")
+	tokenizerDir := flag.String("tokenizer-dir", "", "directory with cl100k_base.tiktoken and o200k_base.tiktoken for exact OpenAI token counts")
+	flag.Parse()
+
 	startTime := time.Now()
 	fmt.Println("Initializing LLM Fusion Engine...")
 
@@ -25,21 +42,44 @@ func main() {
 	}
 
 	// 2. Initialize Services
-	keyManager := services.NewKeyManager(db)
-	providerRouter := services.NewProviderRouter(db, keyManager)
-	healthChecker := services.NewHealthChecker(db)
+	if *tokenizerDir != "" {
+		if err := util.LoadTokenizerEncodings(*tokenizerDir); err != nil {
+			log.Fatalf("Failed to load tokenizer encodings: %v", err)
+		}
+	}
+	rateLimiter := services.NewRateLimiter(services.NewMemoryRateLimitStore())
+	rateLimiter.SchedulePeriodicCleanup(time.Minute)
+	keyManager := services.NewKeyManager(db, rateLimiter)
+	sessionManager := services.NewSessionManager(db)
+	sessionManager.SchedulePeriodicCleanup(time.Hour)
+	loadBalancer := services.NewLoadBalancer()
+	circuitBreaker := services.NewCircuitBreaker(db, breakerConfig)
+	providerRouter := services.NewProviderRouter(db, keyManager, loadBalancer, circuitBreaker)
+	providerFactory := providers.DefaultRegistry()
+	transportPool := services.NewTransportPool()
+	healthChecker := services.NewHealthChecker(db, providerFactory, transportPool)
 	healthChecker.CheckAllProviders()                          // 启动时立即执行一次全面健康检查
 	healthChecker.SchedulePeriodicChecks(5 * time.Minute)      // 启动定期健康检查（每5分钟）
-	// TODO: Initialize ProviderFactory
-	// providerFactory := services.NewProviderFactory()
-	multiProviderService := services.NewMultiProviderService(providerRouter, nil, db) // Pass nil for factory for now
+	multiProviderService := services.NewMultiProviderService(providerRouter, providerFactory, keyManager, loadBalancer, circuitBreaker, transportPool, db)
+	multiProviderService.SetLogBodyLimit(*logBodyLimit)
+	responseCache := services.NewResponseCache(db, *responseCacheSize)
+	responseCache.SchedulePeriodicCleanup(time.Hour)
+	multiProviderService.SetResponseCache(responseCache)
+	multiProviderService.SetSemanticCache(services.NewSemanticCache(*semanticCacheSize))
 
 	// 3. Initialize Handlers
-	chatHandler := v1.NewChatHandler(multiProviderService, keyManager)
+	chatHandler := v1.NewChatHandler(multiProviderService, keyManager, rateLimiter)
+	embeddingsHandler := v1.NewEmbeddingsHandler(multiProviderService, keyManager, rateLimiter)
+	completionsHandler := v1.NewCompletionsHandler(multiProviderService, keyManager, rateLimiter)
+	responsesHandler := v1.NewResponsesHandler(multiProviderService, keyManager, rateLimiter)
+	messagesHandler := v1.NewMessagesHandler(multiProviderService, keyManager, rateLimiter)
+	imagesHandler := v1.NewImagesHandler(multiProviderService, keyManager, rateLimiter)
+	audioHandler := v1.NewAudioHandler(multiProviderService, keyManager, rateLimiter)
 	v1ModelHandler := v1.NewModelHandler(db)
-	authHandler := admin.NewAuthHandler(db)
+	authHandler := admin.NewAuthHandler(db, sessionManager)
+	userHandler := admin.NewUserHandler(db, sessionManager)
 	groupHandler := admin.NewGroupHandler(db)
-	statsHandler := admin.NewStatsHandler(db, startTime)
+	statsHandler := admin.NewStatsHandler(db, startTime, responseCache)
 	keyHandler := admin.NewKeyHandler(db)
 	proxyKeyHandler := admin.NewProxyKeyHandler(db)
 	logHandler := admin.NewLogHandler(db)
@@ -47,38 +87,13 @@ func main() {
 	importHandler := admin.NewImportHandler(db)
 	providerHandler := admin.NewProviderHandler(db)
 	modelHandler := admin.NewModelHandler(db)
+	modelAliasHandler := admin.NewModelAliasHandler(db)
 	modelProviderMappingHandler := admin.NewModelProviderMappingHandler(db)
-	healthHandler := admin.NewHealthHandler(db, healthChecker)
+	healthHandler := admin.NewHealthHandler(db, healthChecker, circuitBreaker)
 
 	// 4. Setup Router
 	router := gin.Default()
 
-	// Simple auth middleware
-	authMiddleware := func(c *gin.Context) {
-		token := c.GetHeader("Authorization")
-		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-			c.Abort()
-			return
-		}
-		
-		// Remove Bearer prefix if present
-		if len(token) > 7 && token[:7] == "Bearer " {
-			token = token[7:]
-		}
-		
-		// Simple token validation - in production, use proper JWT validation
-		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
-		}
-		
-		// Store token in context for later use
-		c.Set("token", token)
-		c.Next()
-	}
-	
 	// Serve static files from web/dist
 	router.Static("/assets", "./web/dist/assets")
 	router.StaticFile("/", "./web/dist/index.html")
@@ -88,6 +103,13 @@ func main() {
 	v1Group := router.Group("/v1")
 	{
 		v1Group.POST("/chat/completions", chatHandler.ChatCompletions)
+		v1Group.POST("/embeddings", embeddingsHandler.Embeddings)
+		v1Group.POST("/completions", completionsHandler.Completions)
+		v1Group.POST("/responses", responsesHandler.Responses)
+		v1Group.POST("/messages", messagesHandler.Messages)
+		v1Group.POST("/images/generations", imagesHandler.Generations)
+		v1Group.POST("/audio/transcriptions", audioHandler.Transcriptions)
+		v1Group.POST("/audio/speech", audioHandler.Speech)
 		v1Group.GET("/models", v1ModelHandler.GetModels)
 	}
 
@@ -95,11 +117,13 @@ func main() {
 	authGroup := router.Group("/api/auth")
 	{
 		authGroup.POST("/login", authHandler.Login)
+		authGroup.POST("/refresh", authHandler.Refresh)
 	}
 
 	// Admin API for management
 	adminGroup := router.Group("/api/admin")
-	adminGroup.Use(authMiddleware) // Protect all admin routes
+	adminGroup.Use(middleware.AdminAuth(sessionManager)) // Protect all admin routes
+	adminGroup.Use(middleware.Authorize(admin.RoutePermissions)) // Enforce role permissions per route
 	{
 		// Statistics
 		adminGroup.GET("/stats", statsHandler.GetStats)
@@ -110,6 +134,10 @@ func main() {
 		adminGroup.GET("/groups/:id", groupHandler.GetGroup)
 		adminGroup.PUT("/groups/:id", groupHandler.UpdateGroup)
 		adminGroup.DELETE("/groups/:id", groupHandler.DeleteGroup)
+		adminGroup.GET("/groups/:id/providers", groupHandler.GetGroupProviders)
+		adminGroup.PUT("/groups/:id/providers", groupHandler.SetGroupProviders)
+		adminGroup.GET("/groups/:id/aliases", groupHandler.GetModelAliases)
+		adminGroup.PUT("/groups/:id/aliases", groupHandler.UpdateModelAliases)
 		
 		// Model Mappings
 		adminGroup.POST("/model-provider-mappings", modelProviderMappingHandler.CreateModelProviderMapping)
@@ -164,11 +192,28 @@ func main() {
 		adminGroup.DELETE("/models/:id", modelHandler.DeleteModel)
 		adminGroup.POST("/models/:id/clone", modelHandler.CloneModel)
 
+		// Model Aliases
+		adminGroup.POST("/model-aliases", modelAliasHandler.CreateModelAlias)
+		adminGroup.GET("/model-aliases", modelAliasHandler.GetModelAliases)
+		adminGroup.PUT("/model-aliases/:id", modelAliasHandler.UpdateModelAlias)
+		adminGroup.DELETE("/model-aliases/:id", modelAliasHandler.DeleteModelAlias)
+
 		// Health Checks
 		adminGroup.POST("/health/providers/:id", healthHandler.CheckProviderHealth)
 		adminGroup.POST("/health/providers", healthHandler.CheckAllProvidersHealth)
-// User account management
-adminGroup.PUT("/account/profile", authHandler.UpdateProfile)
+		adminGroup.GET("/health/circuits", healthHandler.GetCircuitBreakers)
+		adminGroup.GET("/health/circuits/events", healthHandler.GetCircuitBreakerEvents)
+
+		// Users
+		adminGroup.GET("/users", userHandler.GetUsers)
+		adminGroup.POST("/users", userHandler.CreateUser)
+		adminGroup.PUT("/users/:id", userHandler.UpdateUser)
+		adminGroup.DELETE("/users/:id", userHandler.DeleteUser)
+
+		// User account management
+		adminGroup.GET("/account/profile", authHandler.GetProfile)
+		adminGroup.PUT("/account/profile", authHandler.UpdateProfile)
+		adminGroup.POST("/account/logout", authHandler.Logout)
 	}
 	
 	// NoRoute handler for SPA routing
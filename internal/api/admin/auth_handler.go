@@ -1,10 +1,11 @@
 package admin
 
 import (
-	"crypto/rand"
-	"encoding/base64"
+	"llm-fusion-engine/internal/api/middleware"
+	"llm-fusion-engine/internal/core"
 	"llm-fusion-engine/internal/database"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -13,12 +14,13 @@ import (
 
 // AuthHandler handles authentication requests.
 type AuthHandler struct {
-	db *gorm.DB
+	db             *gorm.DB
+	sessionManager core.ISessionManager
 }
 
 // NewAuthHandler creates a new AuthHandler.
-func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *gorm.DB, sessionManager core.ISessionManager) *AuthHandler {
+	return &AuthHandler{db: db, sessionManager: sessionManager}
 }
 
 // LoginRequest represents the login request payload.
@@ -34,10 +36,18 @@ type UpdateProfileRequest struct {
 	NewPassword string `json:"newPassword"`
 }
 
+// RefreshRequest represents the token refresh request payload.
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
 // LoginResponse represents the login response.
 type LoginResponse struct {
-	Token string      `json:"token"`
-	User  UserProfile `json:"user"`
+	Token            string      `json:"token"`
+	RefreshToken     string      `json:"refreshToken"`
+	ExpiresAt        time.Time   `json:"expiresAt"`
+	RefreshExpiresAt time.Time   `json:"refreshExpiresAt"`
+	User             UserProfile `json:"user"`
 }
 
 // UserProfile represents user profile information.
@@ -45,6 +55,17 @@ type UserProfile struct {
 	ID       uint   `json:"id"`
 	Username string `json:"username"`
 	IsAdmin  bool   `json:"isAdmin"`
+	Role     string `json:"role"`
+}
+
+// newUserProfile builds the public profile of a user.
+func newUserProfile(user *database.User) UserProfile {
+	return UserProfile{
+		ID:       user.ID,
+		Username: user.Username,
+		IsAdmin:  user.IsAdmin,
+		Role:     user.Role,
+	}
 }
 
 // Login handles user login and returns a token.
@@ -68,49 +89,84 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Generate token (simple random token for now)
-	token, err := generateToken()
+	tokens, err := h.sessionManager.CreateSession(user.ID, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
 		return
 	}
 
-	// Return user profile and token
-	c.JSON(http.StatusOK, LoginResponse{
-		Token: token,
-		User: UserProfile{
-			ID:       user.ID,
-			Username: user.Username,
-			IsAdmin:  user.IsAdmin,
-		},
-	})
+	c.JSON(http.StatusOK, newLoginResponse(tokens, &user))
 }
 
-// generateToken creates a random token for authentication.
-func generateToken() (string, error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
+// Refresh exchanges a refresh token for a new token pair.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.sessionManager.RefreshSession(req.RefreshToken, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var session database.Session
+	if err := h.db.Preload("User").First(&session, tokens.SessionID).Error; err != nil || session.User.ID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newLoginResponse(tokens, &session.User))
+}
+
+// Logout revokes the caller's current session.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	session, ok := middleware.CurrentSession(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	if err := h.sessionManager.RevokeSession(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// newLoginResponse builds the token payload returned by Login and Refresh.
+func newLoginResponse(tokens *core.SessionTokens, user *database.User) LoginResponse {
+	return LoginResponse{
+		Token:            tokens.AccessToken,
+		RefreshToken:     tokens.RefreshToken,
+		ExpiresAt:        tokens.ExpiresAt,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+		User:             newUserProfile(user),
 	}
-	return base64.URLEncoding.EncodeToString(b), nil
 }
 
 // GetProfile returns the current user's profile.
 func (h *AuthHandler) GetProfile(c *gin.Context) {
-	// For now, return a mock profile
-	// In production, this should verify the token and return actual user data
-	c.JSON(http.StatusOK, UserProfile{
-		ID:       1,
-		Username: "admin",
-		IsAdmin:  true,
-	})
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserProfile(user))
 }
 
 // UpdateProfile handles updating the user's profile.
 func (h *AuthHandler) UpdateProfile(c *gin.Context) {
-	// In a real app, you'd get the user ID from the token.
-	// For now, we'll assume we're updating the admin user (ID 1).
-	userID := uint(1)
+	currentUser, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	userID := currentUser.ID
 
 	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -125,6 +181,7 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	// Verify current password if a new password is being set
+	passwordChanged := false
 	if req.NewPassword != "" {
 		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid current password"})
@@ -136,6 +193,7 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 			return
 		}
 		user.Password = string(hashedPassword)
+		passwordChanged = true
 	}
 
 	// Update username if provided
@@ -148,5 +206,17 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	// A password change signs out every other session of this user.
+	if passwordChanged {
+		var keepSessionID uint
+		if session, ok := middleware.CurrentSession(c); ok {
+			keepSessionID = session.ID
+		}
+		if err := h.sessionManager.RevokeUserSessions(user.ID, keepSessionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Profile updated but failed to sign out other sessions"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
 }
\ No newline at end of file
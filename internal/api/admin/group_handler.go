@@ -2,7 +2,11 @@ package admin
 
 import (
 	"encoding/json"
+	"fmt"
+	"llm-fusion-engine/internal/api/middleware"
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/database"
+	"llm-fusion-engine/internal/util"
 	"net/http"
 	"strconv"
 
@@ -108,13 +112,100 @@ func (h *GroupHandler) UpdateGroup(c *gin.Context) {
 // DeleteGroup deletes a group.
 func (h *GroupHandler) DeleteGroup(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.db.Delete(&database.Group{}, id).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// Memberships are removed for good, so that the unique group/provider index stays free
+		if err := tx.Unscoped().Where("group_id = ?", id).Delete(&database.GroupProvider{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&database.Group{}, id).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
 }
 
+// GetGroupProviders lists the providers assigned to a group.
+func (h *GroupHandler) GetGroupProviders(c *gin.Context) {
+	var group database.Group
+	id := c.Param("id")
+	if err := h.db.First(&group, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	var members []database.GroupProvider
+	if err := h.db.Where("group_id = ?", group.ID).Preload("Provider").Order("provider_id").Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve group providers"})
+		return
+	}
+
+	canReadSecrets := middleware.HasPermission(c, constants.PermSecretsRead)
+	providers := make([]database.Provider, 0, len(members))
+	for _, member := range members {
+		if !canReadSecrets {
+			member.Provider.Config = redactProviderConfig(member.Provider.Config)
+		}
+		providers = append(providers, member.Provider)
+	}
+	c.JSON(http.StatusOK, providers)
+}
+
+// SetGroupProviders replaces the providers assigned to a group.
+func (h *GroupHandler) SetGroupProviders(c *gin.Context) {
+	var group database.Group
+	id := c.Param("id")
+	if err := h.db.First(&group, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	var req struct {
+		ProviderIDs []uint `json:"providerIds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seen := make(map[uint]bool, len(req.ProviderIDs))
+	var providerIDs []uint
+	for _, providerID := range req.ProviderIDs {
+		if !seen[providerID] {
+			seen[providerID] = true
+			providerIDs = append(providerIDs, providerID)
+		}
+	}
+	if len(providerIDs) > 0 {
+		var count int64
+		h.db.Model(&database.Provider{}).Where("id IN ?", providerIDs).Count(&count)
+		if count != int64(len(providerIDs)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more providers do not exist"})
+			return
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// Hard delete: soft-deleted rows would still hold the unique group/provider index
+		if err := tx.Unscoped().Where("group_id = ?", group.ID).Delete(&database.GroupProvider{}).Error; err != nil {
+			return err
+		}
+		for _, providerID := range providerIDs {
+			if err := tx.Create(&database.GroupProvider{GroupID: group.ID, ProviderID: providerID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group providers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group providers updated successfully"})
+}
+
 // GetModelAliases retrieves the model aliases for a group.
 func (h *GroupHandler) GetModelAliases(c *gin.Context) {
 	var group database.Group
@@ -151,6 +242,12 @@ func (h *GroupHandler) UpdateModelAliases(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	for alias, target := range req.Aliases {
+		if err := util.ValidateModelAlias(alias, target); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid alias %q: %v", alias, err)})
+			return
+		}
+	}
 
 	aliasesJSON, err := json.Marshal(req.Aliases)
 	if err != nil {
@@ -1,6 +1,8 @@
 package admin
 
 import (
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
 	"llm-fusion-engine/internal/services"
 	"net/http"
 	"strconv"
@@ -13,11 +15,12 @@ import (
 type HealthHandler struct {
 	db *gorm.DB
 	healthChecker *services.HealthChecker
+	circuitBreaker core.ICircuitBreaker
 }
 
 // NewHealthHandler creates a new HealthHandler.
-func NewHealthHandler(db *gorm.DB, healthChecker *services.HealthChecker) *HealthHandler {
-	return &HealthHandler{db: db, healthChecker: healthChecker}
+func NewHealthHandler(db *gorm.DB, healthChecker *services.HealthChecker, circuitBreaker core.ICircuitBreaker) *HealthHandler {
+	return &HealthHandler{db: db, healthChecker: healthChecker, circuitBreaker: circuitBreaker}
 }
 
 // CheckProviderHealth handles a health check for a single provider.
@@ -44,4 +47,50 @@ func (h *HealthHandler) CheckProviderHealth(c *gin.Context) {
 func (h *HealthHandler) CheckAllProvidersHealth(c *gin.Context) {
 	go h.healthChecker.CheckAllProviders()
 	c.JSON(http.StatusAccepted, gin.H{"message": "Health check for all providers has been initiated."})
-}
\ No newline at end of file
+}
+// GetCircuitBreakers returns the live state of every provider and mapping circuit breaker.
+func (h *HealthHandler) GetCircuitBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.circuitBreaker.Snapshot()})
+}
+
+// GetCircuitBreakerEvents retrieves the circuit breaker transition history with pagination.
+func (h *HealthHandler) GetCircuitBreakerEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := h.db.Model(&database.CircuitBreakerEvent{})
+	if providerID := c.Query("providerId"); providerID != "" {
+		query = query.Where("provider_id = ?", providerID)
+	}
+	if mappingID := c.Query("mappingId"); mappingID != "" {
+		query = query.Where("mapping_id = ?", mappingID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count circuit breaker events"})
+		return
+	}
+
+	var events []database.CircuitBreakerEvent
+	if err := query.Order("timestamp DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve circuit breaker events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": events,
+		"pagination": gin.H{
+			"page":      page,
+			"pageSize":  pageSize,
+			"total":     total,
+			"totalPage": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
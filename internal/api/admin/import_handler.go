@@ -365,22 +365,7 @@ func (h *ImportHandler) processModelProviderMappingsSheet(f *excelize.File, shee
 			continue
 		}
 
-		// Parse boolean values
-		toolCall := false
-		if toolCallIdx != -1 && len(row) > toolCallIdx {
-			toolCall = parseBool(row[toolCallIdx])
-		}
-
-		structuredOutput := false
-		if structuredOutputIdx != -1 && len(row) > structuredOutputIdx {
-			structuredOutput = parseBool(row[structuredOutputIdx])
-		}
-
-		image := false
-		if imageIdx != -1 && len(row) > imageIdx {
-			image = parseBool(row[imageIdx])
-		}
-
+		// Parse boolean values; capability cells left empty do not restrict routing
 		enabled := true
 		if enabledIdx != -1 && len(row) > enabledIdx {
 			enabled = parseBool(row[enabledIdx])
@@ -398,9 +383,9 @@ func (h *ImportHandler) processModelProviderMappingsSheet(f *excelize.File, shee
 			ModelID:          model.ID,
 			ProviderID:       provider.ID,
 			ProviderModel:    row[providerModelIdx],
-			ToolCall:         &toolCall,
-			StructuredOutput: &structuredOutput,
-			Image:            &image,
+			ToolCall:         parseOptionalBool(row, toolCallIdx),
+			StructuredOutput: parseOptionalBool(row, structuredOutputIdx),
+			Image:            parseOptionalBool(row, imageIdx),
 			Weight:           weight,
 			Enabled:          enabled,
 		}
@@ -426,4 +411,13 @@ func findColumnIndex(headers []string, columnName string) int {
 func parseBool(value string) bool {
 	val := strings.ToLower(strings.TrimSpace(value))
 	return val == "true" || val == "1" || val == "yes" || val == "t"
+}
+
+// parseOptionalBool reads a boolean cell, returning nil when the column or the value is missing.
+func parseOptionalBool(row []string, idx int) *bool {
+	if idx == -1 || len(row) <= idx || strings.TrimSpace(row[idx]) == "" {
+		return nil
+	}
+	value := parseBool(row[idx])
+	return &value
 }
\ No newline at end of file
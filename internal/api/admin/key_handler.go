@@ -1,6 +1,8 @@
 package admin
 
 import (
+	"llm-fusion-engine/internal/api/middleware"
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/database"
 	"net/http"
 	"strconv"
@@ -72,6 +74,12 @@ func (h *KeyHandler) GetKeys(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve keys"})
 		return
 	}
+
+	if !middleware.HasPermission(c, constants.PermSecretsRead) {
+		for i := range keys {
+			keys[i].Key = maskSecret(keys[i].Key)
+		}
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"data": keys,
@@ -92,6 +100,9 @@ func (h *KeyHandler) GetKey(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
 		return
 	}
+	if !middleware.HasPermission(c, constants.PermSecretsRead) {
+		key.Key = maskSecret(key.Key)
+	}
 	c.JSON(http.StatusOK, key)
 }
 
@@ -108,6 +119,10 @@ func (h *KeyHandler) UpdateKey(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if key.IsHealthy {
+		// Re-enabling a key by hand cancels any automatic cool-down
+		key.UnhealthyUntil = nil
+	}
 
 	h.db.Save(&key)
 	c.JSON(http.StatusOK, key)
@@ -1,6 +1,8 @@
 package admin
 
 import (
+	"llm-fusion-engine/internal/api/middleware"
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/database"
 	"net/http"
 	"strconv"
@@ -41,7 +43,7 @@ func (h *LogHandler) GetLogs(c *gin.Context) {
 
 	// Apply filters if provided
 	if model := c.Query("model"); model != "" {
-		query = query.Where("model = ?", model)
+		query = query.Where("model = ? OR requested_model = ? OR resolved_model = ?", model, model, model)
 	}
 	if status := c.Query("status"); status != "" {
 		statusCode, _ := strconv.Atoi(status)
@@ -50,6 +52,13 @@ func (h *LogHandler) GetLogs(c *gin.Context) {
 	if provider := c.Query("provider"); provider != "" {
 		query = query.Where("provider = ?", provider)
 	}
+	if requestID := c.Query("requestId"); requestID != "" {
+		// Every attempt of one client request: its first attempt and the retries under it
+		query = query.Where("id = ? OR parent_id = ?", requestID, requestID)
+	}
+	if cacheHit := c.Query("cacheHit"); cacheHit != "" {
+		query = query.Where("cache_hit = ?", cacheHit == "true")
+	}
 	
 	// Count total records
 	if err := query.Count(&total).Error; err != nil {
@@ -62,6 +71,12 @@ func (h *LogHandler) GetLogs(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve logs"})
 		return
 	}
+
+	if !middleware.HasPermission(c, constants.PermSecretsRead) {
+		for i := range logs {
+			logs[i].ProxyKey = maskSecret(logs[i].ProxyKey)
+		}
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"data": logs,
@@ -86,6 +101,9 @@ func (h *LogHandler) GetLog(c *gin.Context) {
 		}
 		return
 	}
+	if !middleware.HasPermission(c, constants.PermSecretsRead) {
+		log.ProxyKey = maskSecret(log.ProxyKey)
+	}
 	c.JSON(http.StatusOK, log)
 }
 
@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"llm-fusion-engine/internal/database"
+	"llm-fusion-engine/internal/util"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ModelAliasHandler handles CRUD operations for global model aliases.
+type ModelAliasHandler struct {
+	db *gorm.DB
+}
+
+// NewModelAliasHandler creates a new ModelAliasHandler.
+func NewModelAliasHandler(db *gorm.DB) *ModelAliasHandler {
+	return &ModelAliasHandler{db: db}
+}
+
+// CreateModelAlias creates a new global model alias.
+func (h *ModelAliasHandler) CreateModelAlias(c *gin.Context) {
+	var alias database.ModelAlias
+	if err := c.ShouldBindJSON(&alias); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := util.ValidateModelAlias(alias.Alias, alias.Target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Create(&alias).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create model alias"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alias)
+}
+
+// GetModelAliases retrieves all global model aliases with pagination.
+func (h *ModelAliasHandler) GetModelAliases(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	var aliases []database.ModelAlias
+	var total int64
+
+	// Count total records
+	if err := h.db.Model(&database.ModelAlias{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count model aliases"})
+		return
+	}
+
+	// Get paginated records
+	if err := h.db.Order("alias").Offset(offset).Limit(pageSize).Find(&aliases).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve model aliases"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": aliases,
+		"pagination": gin.H{
+			"page":      page,
+			"pageSize":  pageSize,
+			"total":     total,
+			"totalPage": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// UpdateModelAlias updates an existing global model alias.
+func (h *ModelAliasHandler) UpdateModelAlias(c *gin.Context) {
+	var alias database.ModelAlias
+	id := c.Param("id")
+	if err := h.db.First(&alias, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Model alias not found"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(&alias); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := util.ValidateModelAlias(alias.Alias, alias.Target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Save(&alias).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update model alias"})
+		return
+	}
+	c.JSON(http.StatusOK, alias)
+}
+
+// DeleteModelAlias deletes a global model alias.
+func (h *ModelAliasHandler) DeleteModelAlias(c *gin.Context) {
+	id := c.Param("id")
+	// Hard delete: a soft-deleted alias would keep its name taken in the unique index
+	if err := h.db.Unscoped().Delete(&database.ModelAlias{}, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete model alias"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Model alias deleted successfully"})
+}
@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/database"
 	"net/http"
 	"strconv"
@@ -26,6 +27,14 @@ func (h *ModelHandler) CreateModel(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validLoadBalancePolicy(model.LoadBalancePolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid load balance policy"})
+		return
+	}
+	if model.StreamFailover != "" && !constants.StreamFailoverMode(model.StreamFailover).IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stream failover mode"})
+		return
+	}
 
 	if err := h.db.Create(&model).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create model"})
@@ -85,6 +94,14 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validLoadBalancePolicy(model.LoadBalancePolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid load balance policy"})
+		return
+	}
+	if model.StreamFailover != "" && !constants.StreamFailoverMode(model.StreamFailover).IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stream failover mode"})
+		return
+	}
 
 	h.db.Save(&model)
 	c.JSON(http.StatusOK, model)
@@ -133,4 +150,8 @@ func (h *ModelHandler) CloneModel(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, clonedModel)
-}
\ No newline at end of file
+}
+// validLoadBalancePolicy accepts an empty policy (use the default) or a known one.
+func validLoadBalancePolicy(policy string) bool {
+	return policy == "" || constants.LoadBalancePolicy(policy).IsValid()
+}
@@ -75,7 +75,7 @@ func (h *ModelMappingHandler) GetModelMappings(c *gin.Context) {
 			providerInfo = mapping.Provider.Name + " (health: " + mapping.Provider.HealthStatus + ")"
 		}
 		log.Printf("[ModelMappings] #%d: Model=%s, Provider=%s, ProviderModel=%s",
-			i+1, mapping.Model, providerInfo, mapping.ProviderModel)
+			i+1, mapping.Model.Name, providerInfo, mapping.ProviderModel)
 	}
 	
 	// 记录返回给前端的完整响应
@@ -1,6 +1,8 @@
 package admin
 
 import (
+	"llm-fusion-engine/internal/api/middleware"
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/database"
 	"net/http"
 	"strconv"
@@ -122,6 +124,14 @@ func (h *ModelProviderMappingHandler) DeleteModel(c *gin.Context) {
 
 // --- ModelProviderMapping CRUD ---
 
+// redactMappingProvider masks the credentials in the preloaded provider config of a mapping
+// unless the caller may read secrets.
+func redactMappingProvider(c *gin.Context, mapping *database.ModelProviderMapping) {
+	if !middleware.HasPermission(c, constants.PermSecretsRead) {
+		mapping.Provider.Config = redactProviderConfig(mapping.Provider.Config)
+	}
+}
+
 // CreateModelProviderMapping creates a new mapping between a model and a provider.
 func (h *ModelProviderMappingHandler) CreateModelProviderMapping(c *gin.Context) {
 	var mapping database.ModelProviderMapping
@@ -149,6 +159,7 @@ func (h *ModelProviderMappingHandler) CreateModelProviderMapping(c *gin.Context)
 
 	// Preload related data for the response
 	h.db.Preload("Model").Preload("Provider").First(&mapping, mapping.ID)
+	redactMappingProvider(c, &mapping)
 	c.JSON(http.StatusOK, mapping)
 }
 
@@ -182,6 +193,9 @@ func (h *ModelProviderMappingHandler) GetModelProviderMappings(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve model provider mappings"})
 		return
 	}
+	for i := range mappings {
+		redactMappingProvider(c, &mappings[i])
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": mappings,
@@ -202,6 +216,7 @@ func (h *ModelProviderMappingHandler) GetModelProviderMapping(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Model provider mapping not found"})
 		return
 	}
+	redactMappingProvider(c, &mapping)
 	c.JSON(http.StatusOK, mapping)
 }
 
@@ -222,6 +237,7 @@ func (h *ModelProviderMappingHandler) UpdateModelProviderMapping(c *gin.Context)
 	h.db.Save(&mapping)
 	// Preload related data for the response
 	h.db.Preload("Model").Preload("Provider").First(&mapping, mapping.ID)
+	redactMappingProvider(c, &mapping)
 	c.JSON(http.StatusOK, mapping)
 }
 
@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"encoding/json"
+	"llm-fusion-engine/internal/api/middleware"
+	"llm-fusion-engine/internal/constants"
+	"net/url"
+	"strings"
+)
+
+// RoutePermissions is the access policy for every route under /api/admin.
+// Routes that are not listed here are only reachable by owners.
+var RoutePermissions = middleware.RoutePermissions{
+	// Statistics
+	"GET /api/admin/stats": constants.PermStatsRead,
+
+	// Groups
+	"POST /api/admin/groups":              constants.PermGroupsWrite,
+	"GET /api/admin/groups":               constants.PermGroupsRead,
+	"GET /api/admin/groups/:id":           constants.PermGroupsRead,
+	"PUT /api/admin/groups/:id":           constants.PermGroupsWrite,
+	"DELETE /api/admin/groups/:id":        constants.PermGroupsWrite,
+	"GET /api/admin/groups/:id/providers": constants.PermGroupsRead,
+	"PUT /api/admin/groups/:id/providers": constants.PermGroupsWrite,
+	"GET /api/admin/groups/:id/aliases":   constants.PermGroupsRead,
+	"PUT /api/admin/groups/:id/aliases":   constants.PermGroupsWrite,
+
+	// Model Mappings
+	"POST /api/admin/model-provider-mappings":           constants.PermModelsWrite,
+	"GET /api/admin/model-provider-mappings":            constants.PermModelsRead,
+	"GET /api/admin/model-provider-mappings/:id":        constants.PermModelsRead,
+	"PUT /api/admin/model-provider-mappings/:id":        constants.PermModelsWrite,
+	"DELETE /api/admin/model-provider-mappings/:id":     constants.PermModelsWrite,
+	"GET /api/admin/model-provider-mappings/:id/health": constants.PermHealthRead,
+	"GET /api/admin/model-provider-mappings/health/all": constants.PermHealthRead,
+
+	// Keys (Provider API Keys)
+	"POST /api/admin/keys":       constants.PermKeysWrite,
+	"GET /api/admin/keys":        constants.PermKeysRead,
+	"GET /api/admin/keys/:id":    constants.PermKeysRead,
+	"PUT /api/admin/keys/:id":    constants.PermKeysWrite,
+	"DELETE /api/admin/keys/:id": constants.PermKeysWrite,
+
+	// Proxy Keys (User Access Keys)
+	"POST /api/admin/proxy-keys":       constants.PermProxyKeysWrite,
+	"GET /api/admin/proxy-keys":        constants.PermProxyKeysRead,
+	"GET /api/admin/proxy-keys/:id":    constants.PermProxyKeysRead,
+	"PUT /api/admin/proxy-keys/:id":    constants.PermProxyKeysWrite,
+	"DELETE /api/admin/proxy-keys/:id": constants.PermProxyKeysWrite,
+
+	// Logs
+	"GET /api/admin/logs":     constants.PermLogsRead,
+	"GET /api/admin/logs/:id": constants.PermLogsRead,
+	"DELETE /api/admin/logs":  constants.PermLogsDelete,
+
+	// Export / Import
+	"GET /api/admin/export/all":      constants.PermConfigExport,
+	"GET /api/admin/export/template": constants.PermConfigExport,
+	"POST /api/admin/import/all":     constants.PermConfigImport,
+	"POST /api/admin/import/excel":   constants.PermConfigImport,
+
+	// Providers
+	"POST /api/admin/providers":                   constants.PermProvidersWrite,
+	"GET /api/admin/providers":                    constants.PermProvidersRead,
+	"GET /api/admin/providers/:id":                constants.PermProvidersRead,
+	"PUT /api/admin/providers/:id":                constants.PermProvidersWrite,
+	"DELETE /api/admin/providers/:id":             constants.PermProvidersWrite,
+	"GET /api/admin/providers/:id/models":         constants.PermProvidersRead,
+	"POST /api/admin/providers/:id/models/import": constants.PermModelsWrite,
+
+	// Models
+	"POST /api/admin/models":           constants.PermModelsWrite,
+	"GET /api/admin/models":            constants.PermModelsRead,
+	"GET /api/admin/models/:id":        constants.PermModelsRead,
+	"PUT /api/admin/models/:id":        constants.PermModelsWrite,
+	"DELETE /api/admin/models/:id":     constants.PermModelsWrite,
+	"POST /api/admin/models/:id/clone": constants.PermModelsWrite,
+
+	// Model Aliases
+	"POST /api/admin/model-aliases":       constants.PermModelsWrite,
+	"GET /api/admin/model-aliases":        constants.PermModelsRead,
+	"PUT /api/admin/model-aliases/:id":    constants.PermModelsWrite,
+	"DELETE /api/admin/model-aliases/:id": constants.PermModelsWrite,
+
+	// Health Checks
+	"POST /api/admin/health/providers/:id":  constants.PermHealthCheck,
+	"POST /api/admin/health/providers":      constants.PermHealthCheck,
+	"GET /api/admin/health/circuits":        constants.PermHealthRead,
+	"GET /api/admin/health/circuits/events": constants.PermHealthRead,
+
+	// Users
+	"GET /api/admin/users":        constants.PermUsersManage,
+	"POST /api/admin/users":       constants.PermUsersManage,
+	"PUT /api/admin/users/:id":    constants.PermUsersManage,
+	"DELETE /api/admin/users/:id": constants.PermUsersManage,
+
+	// User account management
+	"GET /api/admin/account/profile": constants.PermAccountSelf,
+	"PUT /api/admin/account/profile": constants.PermAccountSelf,
+	"POST /api/admin/account/logout": constants.PermAccountSelf,
+}
+
+// secretConfigFields are the provider config keys that hold credentials.
+var secretConfigFields = []string{"apikey", "api_key", "key", "secret", "token", "password", "authorization"}
+
+// maskSecret hides all but the last four characters of a credential.
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", 8) + secret[len(secret)-4:]
+}
+
+// redactProviderConfig masks credential fields in a provider's JSON config.
+// Configs that cannot be parsed are hidden entirely rather than leaked.
+func redactProviderConfig(configJSON string) string {
+	if configJSON == "" {
+		return configJSON
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return "{}"
+	}
+	for key, value := range config {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		lower := strings.ToLower(key)
+		if lower == "proxy" {
+			// Keep the proxy host visible but hide any credentials in its userinfo
+			if proxyURL, err := url.Parse(str); err == nil {
+				config[key] = proxyURL.Redacted()
+			} else {
+				config[key] = maskSecret(str)
+			}
+			continue
+		}
+		for _, field := range secretConfigFields {
+			if lower == field || strings.HasSuffix(lower, field) {
+				config[key] = maskSecret(str)
+				break
+			}
+		}
+	}
+	redacted, err := json.Marshal(config)
+	if err != nil {
+		return "{}"
+	}
+	return string(redacted)
+}
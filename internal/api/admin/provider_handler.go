@@ -3,6 +3,8 @@ package admin
 import (
 	"context"
 	"fmt"
+	"llm-fusion-engine/internal/api/middleware"
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/database"
 	"llm-fusion-engine/internal/providers"
 	"net/http"
@@ -70,6 +72,12 @@ func (h *ProviderHandler) GetProviders(c *gin.Context) {
 		return
 	}
 
+	if !middleware.HasPermission(c, constants.PermSecretsRead) {
+		for i := range providers {
+			providers[i].Config = redactProviderConfig(providers[i].Config)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": providers,
 		"pagination": gin.H{
@@ -89,6 +97,9 @@ func (h *ProviderHandler) GetProvider(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
 		return
 	}
+	if !middleware.HasPermission(c, constants.PermSecretsRead) {
+		provider.Config = redactProviderConfig(provider.Config)
+	}
 	c.JSON(http.StatusOK, provider)
 }
 
@@ -180,7 +191,10 @@ func (h *ProviderHandler) GetProviderModels(c *gin.Context) {
 	
 	// If fetching from API failed, fall back to default list
 	if fetchError != nil || len(models) == 0 {
-		models = getDefaultModels(provider.Type)
+		models = providers.DefaultRegistry().DefaultModels(provider.Type)
+		if models == nil {
+			models = []string{}
+		}
 		
 		// Log the error for debugging but don't fail the request
 		if fetchError != nil {
@@ -201,36 +215,6 @@ func (h *ProviderHandler) GetProviderModels(c *gin.Context) {
 	})
 }
 
-// getDefaultModels returns a default list of models for a given provider type
-func getDefaultModels(providerType string) []string {
-	switch providerType {
-	case "openai":
-		return []string{
-			"gpt-4", "gpt-4-turbo", "gpt-4o", "gpt-4o-mini",
-			"gpt-3.5-turbo", "gpt-3.5-turbo-16k",
-			"o1-preview", "o1-mini",
-		}
-	case "anthropic":
-		return []string{
-			"claude-3-opus-20240229",
-			"claude-3-sonnet-20240229",
-			"claude-3-haiku-20240307",
-			"claude-3-5-sonnet-20240620",
-			"claude-2.1", "claude-2.0",
-		}
-	case "gemini":
-		return []string{
-			"gemini-pro",
-			"gemini-pro-vision",
-			"gemini-1.5-pro-latest",
-			"gemini-1.5-flash-latest",
-			"gemini-ultra",
-		}
-	default:
-		return []string{}
-	}
-}
-
 // ImportProviderModels imports models for a specific provider.
 func (h *ProviderHandler) ImportProviderModels(c *gin.Context) {
 	id := c.Param("id")
@@ -1,6 +1,8 @@
 package admin
 
 import (
+	"llm-fusion-engine/internal/api/middleware"
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/database"
 	"net/http"
 	"strconv"
@@ -26,6 +28,10 @@ func (h *ProxyKeyHandler) CreateProxyKey(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validLoadBalancePolicy(proxyKey.LoadBalancePolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid load balance policy"})
+		return
+	}
 
 	if err := h.db.Create(&proxyKey).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create proxy key"})
@@ -64,6 +70,12 @@ func (h *ProxyKeyHandler) GetProxyKeys(c *gin.Context) {
 		return
 	}
 
+	if !middleware.HasPermission(c, constants.PermSecretsRead) {
+		for i := range proxyKeys {
+			proxyKeys[i].Key = maskSecret(proxyKeys[i].Key)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": proxyKeys,
 		"pagination": gin.H{
@@ -83,6 +95,9 @@ func (h *ProxyKeyHandler) GetProxyKey(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Proxy key not found"})
 		return
 	}
+	if !middleware.HasPermission(c, constants.PermSecretsRead) {
+		proxyKey.Key = maskSecret(proxyKey.Key)
+	}
 	c.JSON(http.StatusOK, proxyKey)
 }
 
@@ -94,13 +109,27 @@ func (h *ProxyKeyHandler) UpdateProxyKey(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Proxy key not found"})
 		return
 	}
+	storedKey := proxyKey.Key
 
 	if err := c.ShouldBindJSON(&proxyKey); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validLoadBalancePolicy(proxyKey.LoadBalancePolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid load balance policy"})
+		return
+	}
+
+	canReadSecrets := middleware.HasPermission(c, constants.PermSecretsRead)
+	if !canReadSecrets {
+		// Callers that only see masked keys would otherwise save the mask over the real key
+		proxyKey.Key = storedKey
+	}
 
 	h.db.Save(&proxyKey)
+	if !canReadSecrets {
+		proxyKey.Key = maskSecret(proxyKey.Key)
+	}
 	c.JSON(http.StatusOK, proxyKey)
 }
 
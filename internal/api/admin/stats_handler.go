@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"llm-fusion-engine/internal/core"
 	"llm-fusion-engine/internal/database"
 	"net/http"
 	"time"
@@ -11,13 +12,14 @@ import (
 
 // StatsHandler handles statistics endpoints.
 type StatsHandler struct {
-	db        *gorm.DB
-	startTime time.Time
+	db            *gorm.DB
+	startTime     time.Time
+	responseCache core.IResponseCache
 }
 
-// NewStatsHandler creates a new StatsHandler.
-func NewStatsHandler(db *gorm.DB, startTime time.Time) *StatsHandler {
-	return &StatsHandler{db: db, startTime: startTime}
+// NewStatsHandler creates a new StatsHandler. The response cache may be nil.
+func NewStatsHandler(db *gorm.DB, startTime time.Time, responseCache core.IResponseCache) *StatsHandler {
+	return &StatsHandler{db: db, startTime: startTime, responseCache: responseCache}
 }
 
 // GetStats returns overall system statistics for the last 24 hours.
@@ -83,5 +85,20 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 		"startTime":         h.startTime,
 	}
 
+	// Response cache lookups since startup
+	if h.responseCache != nil {
+		cacheStats := h.responseCache.Stats()
+		var hitRate float64
+		if lookups := cacheStats.Hits + cacheStats.Misses; lookups > 0 {
+			hitRate = (float64(cacheStats.Hits) / float64(lookups)) * 100
+		}
+		stats["cache"] = gin.H{
+			"hits":    cacheStats.Hits,
+			"misses":  cacheStats.Misses,
+			"hitRate": hitRate,
+			"entries": cacheStats.Entries,
+		}
+	}
+
 	c.JSON(http.StatusOK, stats)
 }
\ No newline at end of file
@@ -0,0 +1,180 @@
+package admin
+
+import (
+	"llm-fusion-engine/internal/api/middleware"
+	"llm-fusion-engine/internal/constants"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// UserHandler handles management of admin console users and their roles.
+type UserHandler struct {
+	db             *gorm.DB
+	sessionManager core.ISessionManager
+}
+
+// NewUserHandler creates a new UserHandler.
+func NewUserHandler(db *gorm.DB, sessionManager core.ISessionManager) *UserHandler {
+	return &UserHandler{db: db, sessionManager: sessionManager}
+}
+
+// CreateUserRequest represents the create user request payload.
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
+
+// UpdateUserRequest represents the update user request payload.
+type UpdateUserRequest struct {
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// GetUsers retrieves all users.
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	var users []database.User
+	if err := h.db.Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		return
+	}
+
+	profiles := make([]UserProfile, 0, len(users))
+	for i := range users {
+		profiles = append(profiles, newUserProfile(&users[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": profiles})
+}
+
+// CreateUser creates a new user with the given role.
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := constants.Role(req.Role)
+	if !role.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	user := database.User{
+		Username: req.Username,
+		Password: string(hashedPassword),
+		IsAdmin:  role == constants.RoleOwner,
+		Role:     role.String(),
+	}
+	if err := h.db.Create(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserProfile(&user))
+}
+
+// UpdateUser changes a user's role and/or password. Existing sessions of the user are revoked.
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	var user database.User
+	id := c.Param("id")
+	if err := h.db.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Role != "" {
+		role := constants.Role(req.Role)
+		if !role.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+			return
+		}
+		if constants.Role(user.Role) == constants.RoleOwner && role != constants.RoleOwner && h.isLastOwner(user.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot demote the last owner"})
+			return
+		}
+		user.Role = role.String()
+		user.IsAdmin = role == constants.RoleOwner
+	}
+
+	if req.Password != "" {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+		user.Password = string(hashedPassword)
+	}
+
+	if err := h.db.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	// Force the user to sign in again so the new role or password takes effect,
+	// unless the caller is editing their own account.
+	var keepSessionID uint
+	if session, ok := middleware.CurrentSession(c); ok && session.UserID == user.ID {
+		keepSessionID = session.ID
+	}
+	if err := h.sessionManager.RevokeUserSessions(user.ID, keepSessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User updated but failed to revoke their sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserProfile(&user))
+}
+
+// DeleteUser deletes a user and revokes their sessions.
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	var user database.User
+	id := c.Param("id")
+	if err := h.db.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if current, ok := middleware.CurrentUser(c); ok && current.ID == user.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete your own account"})
+		return
+	}
+	if constants.Role(user.Role) == constants.RoleOwner && h.isLastOwner(user.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last owner"})
+		return
+	}
+
+	if err := h.db.Delete(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+	if err := h.sessionManager.RevokeUserSessions(user.ID, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User deleted but failed to revoke their sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+// isLastOwner reports whether userID is the only remaining owner.
+func (h *UserHandler) isLastOwner(userID uint) bool {
+	var count int64
+	h.db.Model(&database.User{}).Where("role = ? AND id <> ?", constants.RoleOwner.String(), userID).Count(&count)
+	return count == 0
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys set by AdminAuth for downstream handlers.
+const (
+	ContextKeyUser    = "user"
+	ContextKeySession = "session"
+)
+
+// AdminAuth validates the bearer token against the session store and
+// stores the resolved session and user in the gin context.
+func AdminAuth(sessionManager core.ISessionManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := BearerToken(c)
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
+			c.Abort()
+			return
+		}
+
+		session, err := sessionManager.ValidateSession(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Set(ContextKeySession, session)
+		c.Set(ContextKeyUser, &session.User)
+		c.Next()
+	}
+}
+
+// BearerToken extracts the token from the Authorization header, with or without the Bearer prefix.
+func BearerToken(c *gin.Context) string {
+	token := strings.TrimSpace(c.GetHeader("Authorization"))
+	if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	return token
+}
+
+// CurrentUser returns the authenticated user stored by AdminAuth.
+func CurrentUser(c *gin.Context) (*database.User, bool) {
+	value, exists := c.Get(ContextKeyUser)
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(*database.User)
+	return user, ok
+}
+
+// CurrentSession returns the active session stored by AdminAuth.
+func CurrentSession(c *gin.Context) (*database.Session, bool) {
+	value, exists := c.Get(ContextKeySession)
+	if !exists {
+		return nil, false
+	}
+	session, ok := value.(*database.Session)
+	return session, ok
+}
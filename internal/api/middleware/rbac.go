@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"llm-fusion-engine/internal/constants"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoutePermissions maps "METHOD /full/route/path" to the permission it requires.
+type RoutePermissions map[string]constants.Permission
+
+// Authorize enforces the route policy for the authenticated user. It must run after AdminAuth.
+// Routes missing from the policy are restricted to owners so new endpoints fail closed.
+func Authorize(policy RoutePermissions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+			c.Abort()
+			return
+		}
+
+		role := constants.Role(user.Role)
+		perm, known := policy[c.Request.Method+" "+c.FullPath()]
+		if (!known && role != constants.RoleOwner) || (known && !role.Can(perm)) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      "Insufficient permissions",
+				"role":       role,
+				"permission": perm,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the authenticated user's role grants perm.
+func HasPermission(c *gin.Context, perm constants.Permission) bool {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return false
+	}
+	return constants.Role(user.Role).Can(perm)
+}
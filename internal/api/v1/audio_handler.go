@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAudioUploadSize is the largest audio file accepted for transcription, matching OpenAI's limit.
+const maxAudioUploadSize = 25 << 20
+
+// AudioHandler handles audio transcription and text-to-speech requests.
+type AudioHandler struct {
+	service     core.IMultiProviderService
+	keyManager  core.IKeyManager
+	rateLimiter core.IRateLimiter
+}
+
+// NewAudioHandler creates a new AudioHandler.
+func NewAudioHandler(service core.IMultiProviderService, keyManager core.IKeyManager, rateLimiter core.IRateLimiter) *AudioHandler {
+	return &AudioHandler{service: service, keyManager: keyManager, rateLimiter: rateLimiter}
+}
+
+// Transcriptions is the handler for the /v1/audio/transcriptions endpoint. The multipart form
+// is read into memory and sent upstream with the model rewritten for the selected mapping.
+func (h *AudioHandler) Transcriptions(c *gin.Context) {
+	// 1. Parse and validate the multipart form
+	requestBody, param, err := readTranscriptionForm(c)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": gin.H{
+				"message": fmt.Sprintf("audio file exceeds the maximum size of %d MB", maxAudioUploadSize>>20),
+				"type":    "invalid_request_error",
+				"param":   "file",
+				"code":    nil,
+			}})
+			return
+		}
+		writeInvalidRequest(c, param, err.Error())
+		return
+	}
+
+	// 2. Authenticate, rate limit and proxy the request
+	stream, _ := requestBody["stream"].(bool)
+	serveProxied(c, h.keyManager, h.rateLimiter, bearerProxyKey(c), requestBody, h.service.ProcessTranscriptionHttpAsync, stream)
+}
+
+// Speech is the handler for the /v1/audio/speech endpoint. The audio is streamed to the
+// client as the upstream produces it.
+func (h *AudioHandler) Speech(c *gin.Context) {
+	// 1. Parse and validate request body
+	var requestBody map[string]interface{}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	for _, field := range []string{"model", "input", "voice"} {
+		if value, ok := requestBody[field].(string); !ok || value == "" {
+			writeInvalidRequest(c, field, fmt.Sprintf("%s must be a non-empty string", field))
+			return
+		}
+	}
+
+	// 2. Authenticate, rate limit and proxy the request
+	serveProxied(c, h.keyManager, h.rateLimiter, bearerProxyKey(c), requestBody, h.service.ProcessSpeechHttpAsync, true)
+}
+
+// readTranscriptionForm reads a transcription upload into a request body: the audio as a
+// *core.FileUpload under "file", single form fields as strings and repeated ones as arrays.
+// On a validation error it also returns the offending parameter.
+func readTranscriptionForm(c *gin.Context) (map[string]interface{}, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAudioUploadSize+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, "file", err
+	}
+	files := form.File["file"]
+	if len(files) != 1 {
+		return nil, "file", fmt.Errorf("exactly one audio file must be uploaded as file")
+	}
+	if files[0].Size > maxAudioUploadSize {
+		return nil, "file", &http.MaxBytesError{Limit: maxAudioUploadSize}
+	}
+	file, err := files[0].Open()
+	if err != nil {
+		return nil, "file", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "file", err
+	}
+
+	requestBody := map[string]interface{}{
+		"file": &core.FileUpload{Filename: files[0].Filename, ContentType: files[0].Header.Get("Content-Type"), Data: data},
+	}
+	for name, values := range form.Value {
+		if len(values) == 1 && !strings.HasSuffix(name, "[]") {
+			requestBody[name] = values[0]
+			continue
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = value
+		}
+		requestBody[name] = items
+	}
+	if model, ok := requestBody["model"].(string); !ok || model == "" {
+		return nil, "model", fmt.Errorf("you must provide a model parameter")
+	}
+	if stream, ok := requestBody["stream"].(string); ok {
+		requestBody["stream"] = stream == "true"
+	}
+	return requestBody, "", nil
+}
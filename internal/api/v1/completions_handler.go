@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompletionsHandler handles legacy completion requests.
+type CompletionsHandler struct {
+	service     core.IMultiProviderService
+	keyManager  core.IKeyManager
+	rateLimiter core.IRateLimiter
+}
+
+// NewCompletionsHandler creates a new CompletionsHandler.
+func NewCompletionsHandler(service core.IMultiProviderService, keyManager core.IKeyManager, rateLimiter core.IRateLimiter) *CompletionsHandler {
+	return &CompletionsHandler{service: service, keyManager: keyManager, rateLimiter: rateLimiter}
+}
+
+// Completions is the handler for the /v1/completions endpoint.
+func (h *CompletionsHandler) Completions(c *gin.Context) {
+	// 1. Parse and validate request body
+	var requestBody map[string]interface{}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if param, err := validateCompletionsRequest(requestBody); err != nil {
+		writeInvalidRequest(c, param, err.Error())
+		return
+	}
+
+	// 2. Authenticate, rate limit and proxy the request
+	stream, _ := requestBody["stream"].(bool)
+	serveProxied(c, h.keyManager, h.rateLimiter, bearerProxyKey(c), requestBody, h.service.ProcessCompletionsHttpAsync, stream)
+}
+
+// validateCompletionsRequest checks the fields of a completion request and returns the name
+// of the offending parameter with the error.
+func validateCompletionsRequest(requestBody map[string]interface{}) (string, error) {
+	if model, ok := requestBody["model"].(string); !ok || model == "" {
+		return "model", fmt.Errorf("you must provide a model parameter")
+	}
+	switch prompt := requestBody["prompt"].(type) {
+	case string:
+	case []interface{}:
+		if len(prompt) == 0 {
+			return "prompt", fmt.Errorf("prompt must not be an empty array")
+		}
+		if err := validateCompletionPrompts(prompt); err != nil {
+			return "prompt", err
+		}
+	default:
+		return "prompt", fmt.Errorf("prompt must be a string, an array of strings, an array of tokens or an array of token arrays")
+	}
+	return "", nil
+}
+
+// validateCompletionPrompts checks that a prompt array holds only texts, only tokens, or only
+// token arrays.
+func validateCompletionPrompts(prompts []interface{}) error {
+	switch prompts[0].(type) {
+	case string:
+		for i, prompt := range prompts {
+			if _, ok := prompt.(string); !ok {
+				return fmt.Errorf("prompt[%d] must be a string", i)
+			}
+		}
+	case float64:
+		if !isTokenArray(prompts) {
+			return fmt.Errorf("prompt must contain only integer tokens")
+		}
+	case []interface{}:
+		for i, prompt := range prompts {
+			tokens, ok := prompt.([]interface{})
+			if !ok || len(tokens) == 0 || !isTokenArray(tokens) {
+				return fmt.Errorf("prompt[%d] must be a non-empty array of integer tokens", i)
+			}
+		}
+	default:
+		return fmt.Errorf("prompt must be a string, an array of strings, an array of tokens or an array of token arrays")
+	}
+	return nil
+}
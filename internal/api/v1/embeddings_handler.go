@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmbeddingsHandler handles embeddings requests.
+type EmbeddingsHandler struct {
+	service     core.IMultiProviderService
+	keyManager  core.IKeyManager
+	rateLimiter core.IRateLimiter
+}
+
+// NewEmbeddingsHandler creates a new EmbeddingsHandler.
+func NewEmbeddingsHandler(service core.IMultiProviderService, keyManager core.IKeyManager, rateLimiter core.IRateLimiter) *EmbeddingsHandler {
+	return &EmbeddingsHandler{service: service, keyManager: keyManager, rateLimiter: rateLimiter}
+}
+
+// Embeddings is the handler for the /v1/embeddings endpoint.
+func (h *EmbeddingsHandler) Embeddings(c *gin.Context) {
+	// 1. Parse and validate request body
+	var requestBody map[string]interface{}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if param, err := validateEmbeddingsRequest(requestBody); err != nil {
+		writeInvalidRequest(c, param, err.Error())
+		return
+	}
+
+	// 2. Authenticate, rate limit and proxy the request
+	serveProxied(c, h.keyManager, h.rateLimiter, bearerProxyKey(c), requestBody, h.service.ProcessEmbeddingsHttpAsync, false)
+}
+
+// validateEmbeddingsRequest checks the fields of an embeddings request and returns the name
+// of the offending parameter with the error.
+func validateEmbeddingsRequest(requestBody map[string]interface{}) (string, error) {
+	if model, ok := requestBody["model"].(string); !ok || model == "" {
+		return "model", fmt.Errorf("you must provide a model parameter")
+	}
+
+	switch input := requestBody["input"].(type) {
+	case string:
+		if input == "" {
+			return "input", fmt.Errorf("input must not be empty")
+		}
+	case []interface{}:
+		if len(input) == 0 {
+			return "input", fmt.Errorf("input must not be an empty array")
+		}
+		if err := validateEmbeddingInputs(input); err != nil {
+			return "input", err
+		}
+	default:
+		return "input", fmt.Errorf("input must be a string, an array of strings, an array of tokens or an array of token arrays")
+	}
+
+	if format, exists := requestBody["encoding_format"]; exists {
+		if format != "float" && format != "base64" {
+			return "encoding_format", fmt.Errorf("encoding_format must be 'float' or 'base64'")
+		}
+	}
+	if dimensions, exists := requestBody["dimensions"]; exists {
+		value, ok := dimensions.(float64)
+		if !ok || value < 1 || value != float64(int(value)) {
+			return "dimensions", fmt.Errorf("dimensions must be a positive integer")
+		}
+	}
+	return "", nil
+}
+
+// validateEmbeddingInputs checks that an input array holds only texts, only tokens, or only
+// token arrays.
+func validateEmbeddingInputs(items []interface{}) error {
+	switch items[0].(type) {
+	case string:
+		for i, item := range items {
+			if text, ok := item.(string); !ok || text == "" {
+				return fmt.Errorf("input[%d] must be a non-empty string", i)
+			}
+		}
+	case float64:
+		if !isTokenArray(items) {
+			return fmt.Errorf("input must contain only integer tokens")
+		}
+	case []interface{}:
+		for i, item := range items {
+			tokens, ok := item.([]interface{})
+			if !ok || len(tokens) == 0 || !isTokenArray(tokens) {
+				return fmt.Errorf("input[%d] must be a non-empty array of integer tokens", i)
+			}
+		}
+	default:
+		return fmt.Errorf("input must be a string, an array of strings, an array of tokens or an array of token arrays")
+	}
+	return nil
+}
+
+func isTokenArray(items []interface{}) bool {
+	for _, item := range items {
+		token, ok := item.(float64)
+		if !ok || token < 0 || token != float64(int(token)) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeInvalidRequest rejects a request in OpenAI's error format.
+func writeInvalidRequest(c *gin.Context, param, message string) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    "invalid_request_error",
+			"param":   param,
+			"code":    nil,
+		},
+	})
+}
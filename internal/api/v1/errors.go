@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"errors"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest is the non-standard status nginx uses for requests the client abandoned.
+const statusClientClosedRequest = 499
+
+// writeServiceError reports a failed proxied request. Routing failures and requests no adapter
+// could translate carry their own status and are returned in OpenAI's error format so SDK
+// clients can surface the reason.
+func writeServiceError(c *gin.Context, err error) {
+	var routeErr *core.RouteError
+	if errors.As(err, &routeErr) {
+		errorType := "invalid_request_error"
+		if routeErr.Status >= http.StatusInternalServerError {
+			errorType = "service_unavailable"
+		}
+		c.JSON(routeErr.Status, gin.H{
+			"error": gin.H{
+				"message": routeErr.Message,
+				"type":    errorType,
+				"param":   "model",
+				"code":    routeErr.Code,
+			},
+		})
+		return
+	}
+	var invalid *core.InvalidRequestError
+	if errors.As(err, &invalid) {
+		writeInvalidRequest(c, invalid.Param, invalid.Message)
+		return
+	}
+	switch {
+	case errors.Is(err, core.ErrClientAborted):
+		// Nobody is listening; record the nginx-style status for access logs
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	case errors.Is(err, core.ErrRequestTimeout):
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "timeout",
+				"param":   nil,
+				"code":    "request_timeout",
+			},
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImagesHandler handles image generation requests.
+type ImagesHandler struct {
+	service     core.IMultiProviderService
+	keyManager  core.IKeyManager
+	rateLimiter core.IRateLimiter
+}
+
+// NewImagesHandler creates a new ImagesHandler.
+func NewImagesHandler(service core.IMultiProviderService, keyManager core.IKeyManager, rateLimiter core.IRateLimiter) *ImagesHandler {
+	return &ImagesHandler{service: service, keyManager: keyManager, rateLimiter: rateLimiter}
+}
+
+// Generations is the handler for the /v1/images/generations endpoint.
+func (h *ImagesHandler) Generations(c *gin.Context) {
+	// 1. Parse and validate request body
+	var requestBody map[string]interface{}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if param, err := validateImageGenerationRequest(requestBody); err != nil {
+		writeInvalidRequest(c, param, err.Error())
+		return
+	}
+
+	// 2. Authenticate, rate limit and proxy the request
+	stream, _ := requestBody["stream"].(bool)
+	serveProxied(c, h.keyManager, h.rateLimiter, bearerProxyKey(c), requestBody, h.service.ProcessImageGenerationHttpAsync, stream)
+}
+
+// validateImageGenerationRequest checks the fields of an image generation request and returns
+// the name of the offending parameter with the error.
+func validateImageGenerationRequest(requestBody map[string]interface{}) (string, error) {
+	if model, ok := requestBody["model"].(string); !ok || model == "" {
+		return "model", fmt.Errorf("you must provide a model parameter")
+	}
+	if prompt, ok := requestBody["prompt"].(string); !ok || prompt == "" {
+		return "prompt", fmt.Errorf("prompt must be a non-empty string")
+	}
+	if n, exists := requestBody["n"]; exists {
+		value, ok := n.(float64)
+		if !ok || value < 1 || value != float64(int(value)) {
+			return "n", fmt.Errorf("n must be a positive integer")
+		}
+	}
+	return "", nil
+}
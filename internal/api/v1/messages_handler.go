@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/providers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MessagesHandler serves the Anthropic Messages API so clients built on the Anthropic SDK can
+// use the gateway. Requests are translated to chat completions and routed through the same
+// mappings as /v1/chat/completions, so any provider type can answer them.
+type MessagesHandler struct {
+	service     core.IMultiProviderService
+	keyManager  core.IKeyManager
+	rateLimiter core.IRateLimiter
+}
+
+// NewMessagesHandler creates a new MessagesHandler.
+func NewMessagesHandler(service core.IMultiProviderService, keyManager core.IKeyManager, rateLimiter core.IRateLimiter) *MessagesHandler {
+	return &MessagesHandler{service: service, keyManager: keyManager, rateLimiter: rateLimiter}
+}
+
+// Messages is the handler for the /v1/messages endpoint.
+func (h *MessagesHandler) Messages(c *gin.Context) {
+	// Every error is reported in Anthropic's format, including those of the shared helpers
+	c.Writer = &anthropicErrorWriter{ResponseWriter: c.Writer}
+
+	// 1. Parse and translate request body
+	var requestBody map[string]interface{}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, providers.AnthropicErrorBody("invalid_request_error", "Invalid request body"))
+		return
+	}
+	if model, ok := requestBody["model"].(string); !ok || model == "" {
+		c.JSON(http.StatusBadRequest, providers.AnthropicErrorBody("invalid_request_error", "model: field required"))
+		return
+	}
+	chatBody, err := providers.ConvertAnthropicToOpenAI(requestBody)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, providers.AnthropicErrorBody("invalid_request_error", err.Error()))
+		return
+	}
+	isStreaming, _ := chatBody["stream"].(bool)
+	if isStreaming {
+		// The closing message_delta carries usage, so OpenAI-compatible upstreams must report it
+		chatBody["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+
+	// 2. Authenticate, rate limit and proxy the request. Anthropic clients send the key as x-api-key.
+	proxyKey := c.GetHeader("x-api-key")
+	if proxyKey == "" {
+		proxyKey = bearerProxyKey(c)
+	}
+	if proxyKey == "" {
+		c.JSON(http.StatusUnauthorized, providers.AnthropicErrorBody("authentication_error", "x-api-key header is required"))
+		return
+	}
+	process := func(c *gin.Context, requestBody map[string]interface{}, proxyKey string) (*http.Response, error) {
+		resp, err := h.service.ProcessChatCompletionHttpAsync(c, requestBody, proxyKey)
+		if err == nil {
+			providers.ConvertOpenAIResponseToAnthropic(resp, isStreaming)
+		}
+		return resp, err
+	}
+	serveProxied(c, h.keyManager, h.rateLimiter, proxyKey, chatBody, process, isStreaming)
+}
+
+// anthropicErrorWriter rewrites OpenAI-style error bodies written by the shared v1 helpers
+// (rate limiting, routing and service errors) into Anthropic's error envelope.
+type anthropicErrorWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *anthropicErrorWriter) Write(data []byte) (int, error) {
+	if w.Status() < http.StatusBadRequest {
+		return w.ResponseWriter.Write(data)
+	}
+	converted, ok := anthropicErrorFromOpenAI(w.Status(), data)
+	if !ok {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, err := w.ResponseWriter.Write(converted); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *anthropicErrorWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// anthropicErrorFromOpenAI converts {"error": "..."} or {"error": {"message": "..."}} into
+// Anthropic's format. Bodies already in that format are left alone.
+func anthropicErrorFromOpenAI(status int, data []byte) ([]byte, bool) {
+	var body struct {
+		Type  string          `json:"type"`
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(data, &body) != nil || body.Type == "error" || len(body.Error) == 0 {
+		return nil, false
+	}
+	var message string
+	if json.Unmarshal(body.Error, &message) != nil {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body.Error, &detail) != nil {
+			return nil, false
+		}
+		message = detail.Message
+	}
+	if message == "" {
+		message = fmt.Sprintf("request failed with status %d", status)
+	}
+	converted, err := json.Marshal(providers.AnthropicErrorBody(providers.AnthropicErrorType(status), message))
+	return converted, err == nil
+}
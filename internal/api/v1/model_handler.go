@@ -16,11 +16,11 @@ func NewModelHandler(db *gorm.DB) *ModelHandler {
 	return &ModelHandler{db: db}
 }
 
-// GetModels returns a list of available models.
+// GetModels returns a list of available models. Disabled models are not listed.
 // This is a public endpoint and does not require authentication.
 func (h *ModelHandler) GetModels(c *gin.Context) {
 	var models []database.Model
-	if err := h.db.Find(&models).Error; err != nil {
+	if err := h.db.Where("enabled = ?", true).Find(&models).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve models"})
 		return
 	}
@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"io"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/util"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// processFunc sends a validated request upstream through the multi-provider service.
+type processFunc func(c *gin.Context, requestBody map[string]interface{}, proxyKey string) (*http.Response, error)
+
+// bearerProxyKey returns the proxy key sent in the Authorization header.
+func bearerProxyKey(c *gin.Context) string {
+	return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
+
+// serveProxied authenticates the proxy key, enforces its rate limits, sends the request through
+// process and copies the response to the client, as an event stream when streaming is set.
+// The service captures the body as it passes through, so it is not buffered here.
+func serveProxied(c *gin.Context, keyManager core.IKeyManager, rateLimiter core.IRateLimiter, proxyKey string, requestBody map[string]interface{}, process processFunc, streaming bool) {
+	if proxyKey == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is missing"})
+		return
+	}
+	key, err := keyManager.ValidateProxyKeyAsync(proxyKey)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		return
+	}
+
+	// Enforce the key's RPM/TPM limits, charging the estimated token usage up front
+	reservation, ok := enforceRateLimit(c, rateLimiter, key, util.EstimateRequestTokens(requestBody))
+	if !ok {
+		return
+	}
+
+	resp, err := process(c, requestBody, proxyKey)
+	if err != nil {
+		// Nothing was consumed upstream, so release the estimated tokens
+		if rateLimiter != nil && reservation != nil {
+			rateLimiter.Reconcile(reservation, 0)
+		}
+		writeServiceError(c, err)
+		return
+	}
+	// Deferred first so it runs after the body is closed and its capture has completed
+	defer reconcileUsage(c, rateLimiter, reservation)
+	defer resp.Body.Close()
+	stripUpstreamRateLimitHeaders(resp.Header)
+
+	if streaming {
+		NewTransparentStreamingActionResult(resp).ExecuteResultAsync(c)
+		return
+	}
+	for key, values := range resp.Header {
+		for _, value := range values {
+			c.Header(key, value)
+		}
+	}
+	c.Status(resp.StatusCode)
+	io.Copy(c.Writer, resp.Body)
+}
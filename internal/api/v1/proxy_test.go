@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubKeyManager struct {
+	core.IKeyManager
+}
+
+func (stubKeyManager) ValidateProxyKeyAsync(proxyKey string) (*database.ProxyKey, error) {
+	if proxyKey != "sk-valid" {
+		return nil, errors.New("invalid key")
+	}
+	return &database.ProxyKey{Key: proxyKey}, nil
+}
+
+// stubRateLimiter admits requests while allowed is set and records reconciled charges.
+type stubRateLimiter struct {
+	core.IRateLimiter
+	allowed    bool
+	reconciled []int
+}
+
+func (l *stubRateLimiter) Reserve(key *database.ProxyKey, estimatedTokens int) *core.RateLimitDecision {
+	if !l.allowed {
+		return &core.RateLimitDecision{LimitedBy: "requests", RequestLimit: 1}
+	}
+	return &core.RateLimitDecision{Allowed: true, Reservation: &core.RateLimitReservation{EstimatedTokens: estimatedTokens}}
+}
+
+func (l *stubRateLimiter) Reconcile(reservation *core.RateLimitReservation, actualTokens int) {
+	l.reconciled = append(l.reconciled, actualTokens)
+}
+
+// streamRecorder adds the CloseNotifier that gin's Context.Stream requires.
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (streamRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func TestServeProxied(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := func(err error) processFunc {
+		return func(c *gin.Context, requestBody map[string]interface{}, proxyKey string) (*http.Response, error) {
+			if err != nil {
+				return nil, err
+			}
+			header := http.Header{}
+			header.Set("Content-Type", "application/json")
+			header.Set("X-Ratelimit-Remaining-Requests", "42")
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(`{"ok":true}`))}, nil
+		}
+	}
+
+	tests := []struct {
+		name           string
+		proxyKey       string
+		allowed        bool
+		process        processFunc
+		streaming      bool
+		wantStatus     int
+		wantBody       string
+		wantReconciled []int
+	}{
+		{"missing key", "", true, upstream(nil), false, http.StatusUnauthorized, "Authorization header is missing", nil},
+		{"invalid key", "sk-other", true, upstream(nil), false, http.StatusUnauthorized, "Invalid API key", nil},
+		{"rate limited", "sk-valid", false, upstream(nil), false, http.StatusTooManyRequests, "rate_limit_exceeded", nil},
+		{"upstream failure releases the estimate", "sk-valid", true, upstream(core.ErrRequestTimeout), false, http.StatusGatewayTimeout, "request_timeout", []int{0}},
+		{"response", "sk-valid", true, upstream(nil), false, http.StatusOK, `{"ok":true}`, nil},
+		{"streamed response", "sk-valid", true, upstream(nil), true, http.StatusOK, `{"ok":true}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &stubRateLimiter{allowed: tt.allowed}
+			router := gin.New()
+			router.POST("/", func(c *gin.Context) {
+				serveProxied(c, stubKeyManager{}, limiter, tt.proxyKey, map[string]interface{}{"model": "m"}, tt.process, tt.streaming)
+			})
+			w := streamRecorder{httptest.NewRecorder()}
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("response %d %s, want %d containing %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if fmt.Sprint(limiter.reconciled) != fmt.Sprint(tt.wantReconciled) {
+				t.Errorf("reconciled %v, want %v", limiter.reconciled, tt.wantReconciled)
+			}
+			if w.Code == http.StatusOK && w.Header().Get("X-Ratelimit-Remaining-Requests") != "" {
+				t.Error("upstream rate limit headers were passed to the client")
+			}
+		})
+	}
+}
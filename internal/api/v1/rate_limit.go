@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
+	"llm-fusion-engine/internal/util"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// enforceRateLimit charges the request against the proxy key's RPM/TPM limits and sets
+// OpenAI-style x-ratelimit-* headers. When a limit is exceeded it writes an OpenAI-compatible
+// 429 response and returns ok=false; otherwise it returns the reservation to reconcile later.
+func enforceRateLimit(c *gin.Context, limiter core.IRateLimiter, key *database.ProxyKey, estimatedTokens int) (*core.RateLimitReservation, bool) {
+	if limiter == nil {
+		return nil, true
+	}
+	decision := limiter.Reserve(key, estimatedTokens)
+	setRateLimitHeaders(c, decision)
+	if decision.Allowed {
+		return decision.Reservation, true
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+	var message string
+	if decision.LimitedBy == "tokens" {
+		message = fmt.Sprintf("Rate limit reached for tokens per min (TPM): Limit %d, Used %d, Requested %d. Please try again in %s.",
+			decision.TokenLimit, decision.TokenLimit-decision.TokensRemaining, decision.RequestedTokens, formatRateLimitReset(decision.RetryAfter))
+	} else {
+		message = fmt.Sprintf("Rate limit reached for requests per min (RPM): Limit %d, Used %d, Requested 1. Please try again in %s.",
+			decision.RequestLimit, decision.RequestLimit-decision.RequestsRemaining, formatRateLimitReset(decision.RetryAfter))
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    decision.LimitedBy,
+			"param":   nil,
+			"code":    "rate_limit_exceeded",
+		},
+	})
+	return nil, false
+}
+
+// reconcileUsage replaces the up-front token estimate of an admitted request with the usage
+// of the upstream responses it consumed. It must run after the response bodies are closed.
+func reconcileUsage(c *gin.Context, limiter core.IRateLimiter, reservation *core.RateLimitReservation) {
+	if limiter == nil || reservation == nil {
+		return
+	}
+	value, exists := c.Get(core.ResponseCapturesKey)
+	if !exists {
+		return
+	}
+	captures, _ := value.([]*util.ResponseCapture)
+	totalTokens := 0
+	for _, capture := range captures {
+		totalTokens += capture.Usage().TotalTokens
+	}
+	if totalTokens > 0 {
+		limiter.Reconcile(reservation, totalTokens)
+	}
+}
+
+// setRateLimitHeaders reports the limits that are configured for the key.
+func setRateLimitHeaders(c *gin.Context, decision *core.RateLimitDecision) {
+	if decision.RequestLimit > 0 {
+		c.Header("x-ratelimit-limit-requests", strconv.Itoa(decision.RequestLimit))
+		c.Header("x-ratelimit-remaining-requests", strconv.Itoa(decision.RequestsRemaining))
+		c.Header("x-ratelimit-reset-requests", formatRateLimitReset(decision.RequestsReset))
+	}
+	if decision.TokenLimit > 0 {
+		c.Header("x-ratelimit-limit-tokens", strconv.Itoa(decision.TokenLimit))
+		c.Header("x-ratelimit-remaining-tokens", strconv.Itoa(decision.TokensRemaining))
+		c.Header("x-ratelimit-reset-tokens", formatRateLimitReset(decision.TokensReset))
+	}
+}
+
+// formatRateLimitReset renders a duration the way OpenAI does, e.g. "1s", "6m0s" or "20ms".
+func formatRateLimitReset(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(time.Second).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// stripUpstreamRateLimitHeaders drops the provider's own x-ratelimit-* headers, which describe
+// the gateway's upstream account rather than the client's proxy key.
+func stripUpstreamRateLimitHeaders(header http.Header) {
+	for name := range header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ratelimit-") {
+			header.Del(name)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponsesHandler handles OpenAI Responses API requests.
+type ResponsesHandler struct {
+	service     core.IMultiProviderService
+	keyManager  core.IKeyManager
+	rateLimiter core.IRateLimiter
+}
+
+// NewResponsesHandler creates a new ResponsesHandler.
+func NewResponsesHandler(service core.IMultiProviderService, keyManager core.IKeyManager, rateLimiter core.IRateLimiter) *ResponsesHandler {
+	return &ResponsesHandler{service: service, keyManager: keyManager, rateLimiter: rateLimiter}
+}
+
+// Responses is the handler for the /v1/responses endpoint.
+func (h *ResponsesHandler) Responses(c *gin.Context) {
+	// 1. Parse and validate request body
+	var requestBody map[string]interface{}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if param, err := validateResponsesRequest(requestBody); err != nil {
+		writeInvalidRequest(c, param, err.Error())
+		return
+	}
+
+	// 2. Authenticate, rate limit and proxy the request
+	stream, _ := requestBody["stream"].(bool)
+	serveProxied(c, h.keyManager, h.rateLimiter, bearerProxyKey(c), requestBody, h.service.ProcessResponsesHttpAsync, stream)
+}
+
+// validateResponsesRequest checks the fields of a Responses API request and returns the name
+// of the offending parameter with the error.
+func validateResponsesRequest(requestBody map[string]interface{}) (string, error) {
+	if model, ok := requestBody["model"].(string); !ok || model == "" {
+		return "model", fmt.Errorf("you must provide a model parameter")
+	}
+	switch input := requestBody["input"].(type) {
+	case string:
+	case []interface{}:
+		for i, raw := range input {
+			item, ok := raw.(map[string]interface{})
+			if !ok {
+				return fmt.Sprintf("input[%d]", i), fmt.Errorf("input items must be objects")
+			}
+			if itemType, _ := item["type"].(string); itemType != "message" && (itemType != "" || item["role"] == nil) {
+				continue
+			}
+			switch role, _ := item["role"].(string); role {
+			case "user", "assistant", "system", "developer":
+			default:
+				return fmt.Sprintf("input[%d].role", i), fmt.Errorf("unsupported message role %q", role)
+			}
+		}
+	default:
+		return "input", fmt.Errorf("input must be a string or an array of input items")
+	}
+	return "", nil
+}
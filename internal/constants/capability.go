@@ -0,0 +1,29 @@
+package constants
+
+// Capability 定义模型映射可以服务的请求能力
+type Capability string
+
+const (
+	// CapabilityToolCall 工具调用（请求携带 tools/functions 或工具消息）
+	CapabilityToolCall Capability = "tool_call"
+
+	// CapabilityStructuredOutput 结构化输出（response_format 为 json_schema）
+	CapabilityStructuredOutput Capability = "structured_output"
+
+	// CapabilityImageInput 图像输入（消息中包含图片）
+	CapabilityImageInput Capability = "image_input"
+
+	// CapabilityImageGeneration 图像生成（/v1/images/generations）
+	CapabilityImageGeneration Capability = "image_generation"
+
+	// CapabilityTranscription 音频转写（/v1/audio/transcriptions）
+	CapabilityTranscription Capability = "audio_transcription"
+
+	// CapabilitySpeech 文本转语音（/v1/audio/speech）
+	CapabilitySpeech Capability = "audio_speech"
+)
+
+// String 返回能力的字符串表示
+func (c Capability) String() string {
+	return string(c)
+}
@@ -0,0 +1,20 @@
+package constants
+
+// CircuitState 定义熔断器状态类型
+type CircuitState string
+
+const (
+	// CircuitClosed 闭合状态，请求正常通过
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen 断开状态，请求被拒绝直到冷却时间结束
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen 半开状态，仅放行少量探测请求
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// String 返回熔断器状态的字符串表示
+func (s CircuitState) String() string {
+	return string(s)
+}
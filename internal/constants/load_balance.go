@@ -0,0 +1,37 @@
+package constants
+
+// LoadBalancePolicy 定义同一优先级内多个模型-提供商映射之间的负载均衡策略
+type LoadBalancePolicy string
+
+const (
+	// LoadBalanceFailover 严格故障转移，始终优先使用排序靠前的映射
+	LoadBalanceFailover LoadBalancePolicy = "failover"
+
+	// LoadBalanceWeightedRandom 按权重随机选择
+	LoadBalanceWeightedRandom LoadBalancePolicy = "weighted_random"
+
+	// LoadBalanceRoundRobin 平滑加权轮询
+	LoadBalanceRoundRobin LoadBalancePolicy = "round_robin"
+
+	// LoadBalanceLeastLatency 优先选择近期延迟最低的映射
+	LoadBalanceLeastLatency LoadBalancePolicy = "least_latency"
+
+	// LoadBalanceLeastInFlight 优先选择当前进行中请求最少的映射
+	LoadBalanceLeastInFlight LoadBalancePolicy = "least_in_flight"
+)
+
+// IsValid 检查负载均衡策略是否有效
+func (p LoadBalancePolicy) IsValid() bool {
+	switch p {
+	case LoadBalanceFailover, LoadBalanceWeightedRandom, LoadBalanceRoundRobin,
+		LoadBalanceLeastLatency, LoadBalanceLeastInFlight:
+		return true
+	default:
+		return false
+	}
+}
+
+// String 返回负载均衡策略的字符串表示
+func (p LoadBalancePolicy) String() string {
+	return string(p)
+}
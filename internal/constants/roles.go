@@ -0,0 +1,98 @@
+package constants
+
+// Role 定义管理后台用户角色
+type Role string
+
+const (
+	// RoleOwner 所有者，拥有全部权限（包括用户管理）
+	RoleOwner Role = "owner"
+
+	// RoleOperator 运维人员，可管理提供商、模型、密钥等配置
+	RoleOperator Role = "operator"
+
+	// RoleViewer 只读用户，可查看统计、日志和配置，但看不到原始密钥
+	RoleViewer Role = "viewer"
+
+	// RoleBilling 计费人员，可查看统计与日志并管理代理密钥额度
+	RoleBilling Role = "billing"
+)
+
+// IsValid 检查角色是否有效
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleOwner, RoleOperator, RoleViewer, RoleBilling:
+		return true
+	default:
+		return false
+	}
+}
+
+// String 返回角色的字符串表示
+func (r Role) String() string {
+	return string(r)
+}
+
+// Can 检查角色是否拥有指定权限
+func (r Role) Can(p Permission) bool {
+	if r == RoleOwner {
+		return true
+	}
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Permission 定义管理接口的权限点
+type Permission string
+
+const (
+	PermStatsRead      Permission = "stats:read"
+	PermLogsRead       Permission = "logs:read"
+	PermLogsDelete     Permission = "logs:delete"
+	PermGroupsRead     Permission = "groups:read"
+	PermGroupsWrite    Permission = "groups:write"
+	PermProvidersRead  Permission = "providers:read"
+	PermProvidersWrite Permission = "providers:write"
+	PermModelsRead     Permission = "models:read"
+	PermModelsWrite    Permission = "models:write"
+	PermKeysRead       Permission = "keys:read"
+	PermKeysWrite      Permission = "keys:write"
+	PermProxyKeysRead  Permission = "proxy_keys:read"
+	PermProxyKeysWrite Permission = "proxy_keys:write"
+	PermHealthRead     Permission = "health:read"
+	PermHealthCheck    Permission = "health:check"
+	PermConfigExport   Permission = "config:export"
+	PermConfigImport   Permission = "config:import"
+	PermSecretsRead    Permission = "secrets:read"
+	PermUsersManage    Permission = "users:manage"
+	PermAccountSelf    Permission = "account:self"
+)
+
+// rolePermissions 定义除所有者外各角色拥有的权限
+var rolePermissions = map[Role][]Permission{
+	RoleOperator: {
+		PermStatsRead, PermLogsRead, PermLogsDelete,
+		PermGroupsRead, PermGroupsWrite,
+		PermProvidersRead, PermProvidersWrite,
+		PermModelsRead, PermModelsWrite,
+		PermKeysRead, PermKeysWrite,
+		PermProxyKeysRead, PermProxyKeysWrite,
+		PermHealthRead, PermHealthCheck,
+		PermConfigExport, PermConfigImport,
+		PermSecretsRead, PermAccountSelf,
+	},
+	RoleViewer: {
+		PermStatsRead, PermLogsRead,
+		PermGroupsRead, PermProvidersRead, PermModelsRead,
+		PermKeysRead, PermProxyKeysRead, PermHealthRead,
+		PermAccountSelf,
+	},
+	RoleBilling: {
+		PermStatsRead, PermLogsRead,
+		PermProxyKeysRead, PermProxyKeysWrite,
+		PermAccountSelf,
+	},
+}
@@ -0,0 +1,30 @@
+package constants
+
+// StreamFailoverMode 定义流式响应在 [DONE] 之前中断时的故障转移策略
+type StreamFailoverMode string
+
+const (
+	// StreamFailoverOff 不做故障转移，上游中断即结束客户端流（默认）
+	StreamFailoverOff StreamFailoverMode = "off"
+
+	// StreamFailoverRetry 仅在尚未向客户端输出任何内容时，切换到下一个映射重新请求
+	StreamFailoverRetry StreamFailoverMode = "retry"
+
+	// StreamFailoverResume 将已输出的内容作为 assistant 前缀发送给下一个映射，从中断处继续生成
+	StreamFailoverResume StreamFailoverMode = "resume"
+)
+
+// IsValid 检查流式故障转移策略是否有效
+func (m StreamFailoverMode) IsValid() bool {
+	switch m {
+	case StreamFailoverOff, StreamFailoverRetry, StreamFailoverResume:
+		return true
+	default:
+		return false
+	}
+}
+
+// String 返回流式故障转移策略的字符串表示
+func (m StreamFailoverMode) String() string {
+	return string(m)
+}
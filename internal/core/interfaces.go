@@ -1,6 +1,10 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/database"
 	"net/http"
 	"time"
@@ -10,18 +14,92 @@ import (
 
 // ProviderRouteResult defines the result of a routing decision.
 type ProviderRouteResult struct {
-	Group         *database.Group
-	Provider      *database.Provider
-	ApiKey        string
-	ResolvedModel string
-	RetryCount    int
-	RetryAfter    time.Duration
+	Group          *database.Group
+	Provider       *database.Provider
+	ApiKey         string
+	MappingID      uint                  // ID of the ModelProviderMapping that was selected
+	ApiKeyID       uint                  // ID of the ApiKey row used; 0 when the provider config key is used
+	KeyReservation *RateLimitReservation // Usage charged to the ApiKey row's own limits
+	ResolvedModel  string
+	Model          string // Model name the request was routed as, after alias resolution
+	RetryCount     int
+	RetryAfter     time.Duration
+}
+
+// RouteError explains why no provider could be selected for a request. Status is the HTTP
+// status to return to the client (404 for unknown or disabled models, 400 when no mapping of
+// the model supports what the request needs, 503 when the model exists but none of its
+// providers is currently usable) and Code an OpenAI-style error code.
+type RouteError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *RouteError) Error() string {
+	return e.Message
+}
+
+// InvalidRequestError reports a request that an adapter cannot translate because it is
+// malformed. The client is at fault, so no other provider is tried and Param names the field.
+type InvalidRequestError struct {
+	Param   string
+	Message string
+}
+
+func (e *InvalidRequestError) Error() string {
+	return e.Message
 }
 
 // IProviderRouter is responsible for routing a request to the appropriate provider group.
 type IProviderRouter interface {
 	// RouteRequestAsync selects a provider based on the model, proxy key, and failover/load-balancing strategies.
-	RouteRequestAsync(model, proxyKey string, excludedProviders []uint) (*ProviderRouteResult, error)
+	// estimatedTokens is charged against the TPM limit of the selected provider API key.
+	// The context cancels the lookups when the client goes away. Mappings that lack one of the
+	// required capabilities, or of those the request body needs (tools, structured output,
+	// image inputs), are not considered.
+	RouteRequestAsync(ctx context.Context, model, proxyKey string, excludedProviders []uint, estimatedTokens int, requestBody map[string]interface{}, required []constants.Capability) (*ProviderRouteResult, error)
+}
+
+// ILoadBalancer orders a model's candidate mappings and tracks the per-mapping load it balances on.
+type ILoadBalancer interface {
+	// Order returns the mappings in the order they should be tried: by provider priority tier,
+	// and within a tier by the policy. poolKey identifies the candidate pool for stateful policies.
+	Order(policy string, poolKey string, mappings []database.ModelProviderMapping) []database.ModelProviderMapping
+	// OrderGroups orders the groups a proxy key may route through according to its group balance policy.
+	OrderGroups(policy string, poolKey string, groups []database.Group, weights map[uint]int) []database.Group
+	// BeginRequest marks a request to the mapping as in flight; the returned func ends it.
+	BeginRequest(mappingID uint) func()
+	// RecordLatency feeds an observed upstream latency into the mapping's moving average.
+	RecordLatency(mappingID uint, latency time.Duration)
+}
+
+// CircuitStatus is a snapshot of one circuit breaker, as exposed by the admin health API.
+type CircuitStatus struct {
+	Scope               string     `json:"scope"` // "provider" or "mapping"
+	ProviderID          uint       `json:"providerId"`
+	MappingID           uint       `json:"mappingId"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Requests            int        `json:"requests"` // Outcomes inside the error-rate window
+	Failures            int        `json:"failures"`
+	ErrorRate           float64    `json:"errorRate"`
+	OpenedAt            *time.Time `json:"openedAt"`
+	RetryAt             *time.Time `json:"retryAt"` // When an open circuit starts admitting probes
+}
+
+// ICircuitBreaker tracks live request outcomes per provider and per model-provider mapping
+// and stops routing to them while they keep failing.
+type ICircuitBreaker interface {
+	// Allow reports whether a request may be sent through the mapping; the breakers of both the
+	// provider and the mapping must admit it. In half-open state this claims a probe slot.
+	Allow(providerID, mappingID uint) bool
+	// Release returns a probe slot claimed by Allow when the request was not sent after all.
+	Release(providerID, mappingID uint)
+	// Record feeds the outcome of a request into the provider's and the mapping's breakers.
+	Record(providerID, mappingID uint, success bool)
+	// Snapshot returns the state of every breaker that has seen traffic.
+	Snapshot() []CircuitStatus
 }
 
 // IKeyManager manages the API keys for different provider groups.
@@ -29,11 +107,190 @@ type IKeyManager interface {
 	// ValidateProxyKeyAsync checks if a proxy key is valid and returns it.
 	ValidateProxyKeyAsync(proxyKey string) (*database.ProxyKey, error)
 	UpdateLogTokens(requestID string, promptTokens, completionTokens, totalTokens int)
+	// SelectProviderKey picks a usable ApiKey row of the provider, or returns a nil key if it has none.
+	SelectProviderKey(provider *database.Provider, estimatedTokens int) (*database.ApiKey, *RateLimitReservation, error)
+	// ReportKeyFailure disables an ApiKey row for a cool-down period if the upstream error was caused by the key.
+	ReportKeyFailure(apiKeyID uint, statusCode int, header http.Header, body []byte) bool
+	// ReconcileKeyUsage replaces the estimated token charge of a SelectProviderKey reservation with the actual usage.
+	ReconcileKeyUsage(reservation *RateLimitReservation, actualTokens int)
+}
+
+// RateLimitReservation records the usage charged to a proxy key or provider API key for one
+// admitted request, so it can be corrected once the actual token count is known.
+type RateLimitReservation struct {
+	Subject         string // e.g. "proxykey:3" or "apikey:7"
+	ReservedAt      time.Time
+	EstimatedTokens int
+}
+
+// RateLimitDecision is the outcome of a rate limit check, including the state reported
+// to clients in x-ratelimit-* headers. A zero limit means the dimension is unlimited.
+type RateLimitDecision struct {
+	Allowed           bool
+	LimitedBy         string // "requests" or "tokens" when rejected
+	RequestLimit      int
+	RequestsRemaining int
+	RequestsReset     time.Duration
+	TokenLimit        int
+	TokensRemaining   int
+	TokensReset       time.Duration
+	RequestedTokens   int
+	RetryAfter        time.Duration
+	Reservation       *RateLimitReservation // Set when Allowed
+}
+
+// IRateLimiter enforces per-key request (RPM) and token (TPM) limits.
+type IRateLimiter interface {
+	// Reserve checks the proxy key's limits and, if the request fits, charges one request and estimatedTokens.
+	Reserve(key *database.ProxyKey, estimatedTokens int) *RateLimitDecision
+	// ReserveApiKey does the same for an upstream provider API key.
+	ReserveApiKey(key *database.ApiKey, estimatedTokens int) *RateLimitDecision
+	// Reconcile replaces the estimated token charge of a reservation with the actual usage.
+	Reconcile(reservation *RateLimitReservation, actualTokens int)
+}
+
+// IRateLimitStore holds sliding-window usage counters for the rate limiter.
+// The default store is in-memory; a shared store lets several instances enforce the same limits.
+type IRateLimitStore interface {
+	// Add records amount (which may be negative) against key at the given time.
+	Add(key string, at time.Time, amount int64)
+	// Usage returns the total recorded for key in the window ending at now,
+	// and the time of the oldest usage still inside the window.
+	Usage(key string, now time.Time, window time.Duration) (total int64, oldest time.Time)
+	// Prune discards usage recorded before the given time.
+	Prune(before time.Time)
+}
+
+// CachedResponse is a successful upstream response kept by the response cache. Body holds
+// what the client received, either a JSON document or a complete SSE stream.
+type CachedResponse struct {
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// ResponseCacheStats counts response cache lookups since startup.
+type ResponseCacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"` // Responses held in memory
+}
+
+// IResponseCache stores the responses of deterministic requests so that repeated requests are
+// answered without an upstream call.
+type IResponseCache interface {
+	// Get returns the unexpired response stored under key, counting a hit or a miss.
+	Get(key string) (*CachedResponse, bool)
+	// Set stores a response under key until its ExpiresAt.
+	Set(key string, response *CachedResponse)
+	// Stats returns the lookup counters.
+	Stats() ResponseCacheStats
+}
+
+// ISemanticCache indexes the prompt embeddings of cached chat completions per model, so that a
+// request similar enough to an earlier one can be answered with the earlier response.
+type ISemanticCache interface {
+	// Search returns the unexpired response whose embedding is most similar to vector among
+	// those added for the model and context, with its cosine similarity.
+	Search(model, context string, vector []float64) (*CachedResponse, float64)
+	// Add indexes a response under the embedding of its prompt.
+	Add(model, context string, vector []float64, response *CachedResponse)
+}
+
+// SessionTokens is the token pair handed to an admin client after login or refresh.
+type SessionTokens struct {
+	SessionID        uint
+	AccessToken      string
+	RefreshToken     string
+	ExpiresAt        time.Time
+	RefreshExpiresAt time.Time
+}
+
+// ISessionManager issues, validates and revokes admin sessions.
+type ISessionManager interface {
+	// CreateSession issues a new token pair for the given user.
+	CreateSession(userID uint, clientIP, userAgent string) (*SessionTokens, error)
+	// ValidateSession resolves an access token to an active session with its user preloaded.
+	ValidateSession(accessToken string) (*database.Session, error)
+	// RefreshSession exchanges a refresh token for a new token pair, revoking the old one.
+	RefreshSession(refreshToken, clientIP, userAgent string) (*SessionTokens, error)
+	// RevokeSession revokes a single session.
+	RevokeSession(sessionID uint) error
+	// RevokeUserSessions revokes all sessions of a user except keepSessionID.
+	RevokeUserSessions(userID uint, keepSessionID uint) error
+}
+
+// ErrOperationNotSupported is returned by an IProvider for operations its upstream API does not offer.
+var ErrOperationNotSupported = errors.New("operation not supported by this provider type")
+
+// ErrClientAborted is returned when the client disconnected before the request completed.
+var ErrClientAborted = errors.New("client closed the request")
+
+// ErrRequestTimeout is returned when a request exceeds its model's total timeout.
+var ErrRequestTimeout = errors.New("request exceeded the model timeout")
+
+// ResponseCapturesKey is the gin context key under which the service leaves the
+// []*util.ResponseCapture of every successful upstream response of a request, so the
+// handler can reconcile the proxy key's token charge once the responses are consumed.
+const ResponseCapturesKey = "responseCaptures"
+
+// FileUpload is a file received in a multipart request, kept in memory so the request can
+// be replayed on another provider. It is logged by name and size only.
+type FileUpload struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// MarshalJSON describes the file without its content.
+func (f *FileUpload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"filename": f.Filename, "contentType": f.ContentType, "size": len(f.Data)})
+}
+
+// ProviderEndpoint carries the per-request connection settings of a configured provider instance.
+type ProviderEndpoint struct {
+	BaseURL string
+	ApiKey  string
+	Config  map[string]interface{} // The provider's parsed JSON config
+	Client  *http.Client
 }
 
 // IProvider represents a specific LLM provider (e.g., OpenAI, Anthropic).
+// Implementations accept OpenAI-format request bodies and return OpenAI-format responses,
+// translating to and from the provider's native API as needed.
 type IProvider interface {
-	// TODO: Define methods for making chat completion requests.
+	// Type returns the provider type name this implementation is registered under.
+	Type() string
+	// ChatCompletion sends a non-streaming chat completion request.
+	ChatCompletion(ctx context.Context, endpoint *ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error)
+	// StreamChatCompletion sends a streaming chat completion request; the response body is an OpenAI SSE stream.
+	StreamChatCompletion(ctx context.Context, endpoint *ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error)
+	// Embeddings sends an embeddings request.
+	Embeddings(ctx context.Context, endpoint *ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error)
+	// Completions sends a legacy /v1/completions request; the response is a text_completion,
+	// streamed as SSE when the request sets stream.
+	Completions(ctx context.Context, endpoint *ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error)
+	// Responses sends an OpenAI Responses API request; the response is a response object, or a
+	// stream of Responses events when the request sets stream.
+	Responses(ctx context.Context, endpoint *ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error)
+	// ImageGeneration sends an image generation request; the response is an OpenAI images response.
+	ImageGeneration(ctx context.Context, endpoint *ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error)
+	// AudioTranscription sends a transcription request. The body holds the form fields and the
+	// audio as a *FileUpload under "file"; it is sent upstream as multipart/form-data.
+	AudioTranscription(ctx context.Context, endpoint *ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error)
+	// Speech sends a text-to-speech request; the response body is the audio.
+	Speech(ctx context.Context, endpoint *ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error)
+	// ListModels fetches the model IDs available from the provider's API.
+	ListModels(ctx context.Context, endpoint *ProviderEndpoint) ([]string, error)
+	// DefaultModels returns a static fallback catalog. The first entry is a low-cost model used for health probes.
+	DefaultModels() []string
+}
+
+// ITransportPool hands out pooled HTTP clients for upstream provider calls.
+type ITransportPool interface {
+	// Client returns the shared client for the provider, built from the transport settings
+	// in its config (connection limits, HTTP/2, dial/TLS timeouts, proxy, CA and client certificate).
+	Client(provider *database.Provider, config map[string]interface{}) (*http.Client, error)
 }
 
 // IProviderFactory creates instances of IProvider.
@@ -49,10 +306,42 @@ type IMultiProviderService interface {
 		requestBody map[string]interface{},
 		proxyKey string,
 	) (*http.Response, error)
+	ProcessEmbeddingsHttpAsync(
+		c *gin.Context,
+		requestBody map[string]interface{},
+		proxyKey string,
+	) (*http.Response, error)
+	ProcessCompletionsHttpAsync(
+		c *gin.Context,
+		requestBody map[string]interface{},
+		proxyKey string,
+	) (*http.Response, error)
+	ProcessResponsesHttpAsync(
+		c *gin.Context,
+		requestBody map[string]interface{},
+		proxyKey string,
+	) (*http.Response, error)
+	ProcessImageGenerationHttpAsync(
+		c *gin.Context,
+		requestBody map[string]interface{},
+		proxyKey string,
+	) (*http.Response, error)
+	ProcessTranscriptionHttpAsync(
+		c *gin.Context,
+		requestBody map[string]interface{},
+		proxyKey string,
+	) (*http.Response, error)
+	ProcessSpeechHttpAsync(
+		c *gin.Context,
+		requestBody map[string]interface{},
+		proxyKey string,
+	) (*http.Response, error)
 	LogRequest(
 		requestID string,
 		requestBody map[string]interface{},
 		proxyKey string,
+		requestedModel string,
+		resolvedModel string,
 		providerName string,
 		requestUrl string,
 		response *http.Response,
@@ -17,7 +17,7 @@ func InitDatabase(dsn string) (*gorm.DB, error) {
 	}
 
 	// Auto-migrate the schema
-	err = DB.AutoMigrate(&User{}, &ProxyKey{}, &Group{}, &Provider{}, &ApiKey{}, &Log{}, &Model{}, &ModelProviderMapping{})
+	err = DB.AutoMigrate(&User{}, &Session{}, &ProxyKey{}, &Group{}, &GroupProvider{}, &Provider{}, &ApiKey{}, &Log{}, &Model{}, &ModelAlias{}, &ModelProviderMapping{}, &CircuitBreakerEvent{}, &ResponseCacheEntry{})
 	if err != nil {
 		return nil, err
 	}
@@ -27,6 +27,11 @@ func InitDatabase(dsn string) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// Assign roles to users created before roles existed
+	if err := backfillUserRoles(DB); err != nil {
+		return nil, err
+	}
+
 	return DB, nil
 }
 
@@ -48,6 +53,7 @@ func createDefaultAdminUser(db *gorm.DB) error {
 			Username: "admin",
 			Password: string(hashedPassword),
 			IsAdmin:  true,
+			Role:     "owner",
 		}
 
 		if err := db.Create(&defaultUser).Error; err != nil {
@@ -56,4 +62,16 @@ func createDefaultAdminUser(db *gorm.DB) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// backfillUserRoles gives legacy admins the owner role and everyone else the viewer role.
+func backfillUserRoles(db *gorm.DB) error {
+	if err := db.Model(&User{}).
+		Where("(role IS NULL OR role = '') AND is_admin = ?", true).
+		Update("role", "owner").Error; err != nil {
+		return err
+	}
+	return db.Model(&User{}).
+		Where("role IS NULL OR role = ''").
+		Update("role", "viewer").Error
+}
@@ -1,11 +1,9 @@
 package migrations
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
-	"time"
 
 	"gorm.io/gorm"
 )
@@ -19,9 +19,26 @@ type User struct {
 	Username string `gorm:"uniqueIndex;not null"`
 	Password string `gorm:"not null"` // Hashed password
 	IsAdmin  bool   `gorm:"default:false"`
+	Role     string `gorm:"type:varchar(32)"` // owner, operator, viewer, billing; empty rows are backfilled at startup
 	ProxyKeys []ProxyKey
 }
 
+// Session is an authenticated admin session issued at login.
+// Only SHA-256 hashes of the access and refresh tokens are persisted.
+type Session struct {
+	BaseModel
+	UserID           uint       `gorm:"index;not null" json:"userId"`
+	TokenHash        string     `gorm:"uniqueIndex;not null" json:"-"`
+	RefreshTokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt        time.Time  `gorm:"index" json:"expiresAt"`
+	RefreshExpiresAt time.Time  `json:"refreshExpiresAt"`
+	RevokedAt        *time.Time `json:"revokedAt"`
+	LastUsedAt       time.Time  `json:"lastUsedAt"`
+	ClientIP         string     `json:"clientIp"`
+	UserAgent        string     `json:"userAgent"`
+	User             User       `gorm:"foreignKey:UserID" json:"-"`
+}
+
 // ProxyKey is a key used by end-users to access the API.
 type ProxyKey struct {
 	BaseModel
@@ -33,6 +50,9 @@ type ProxyKey struct {
 	GroupWeights       string `json:"groupWeights"` // JSON object for weighted balancing
 	RpmLimit           int    `json:"rpmLimit"`
 	TpmLimit           int    `json:"tpmLimit"`
+	LoadBalancePolicy  string `json:"loadBalancePolicy"` // Overrides the model's policy when set
+	CacheEnabled       bool   `json:"cacheEnabled"`      // Answer repeated deterministic requests from the response cache
+	CacheTTL           int    `json:"cacheTtl"`          // Seconds cached responses stay valid; 0 uses the default
 }
 
 // Group represents a collection of provider configurations for routing.
@@ -46,9 +66,16 @@ type Group struct {
 	LoadBalancePolicy string `gorm:"default:'failover'" json:"loadBalancePolicy"` // e.g., failover, round_robin, weighted
 }
 
+// GroupProvider assigns a provider to a group. A provider may belong to several groups.
+type GroupProvider struct {
+	BaseModel
+	GroupID    uint     `gorm:"uniqueIndex:idx_group_provider;not null" json:"groupId"`
+	ProviderID uint     `gorm:"uniqueIndex:idx_group_provider;not null" json:"providerId"`
+	Provider   Provider `gorm:"foreignKey:ProviderID" json:"provider"`
+}
+
 // Provider holds the configuration for a specific LLM provider.
-// Note: The original GroupID is removed to align with a more direct provider management model.
-// Group-based routing can be implemented at a higher level if needed.
+// Group membership is kept in GroupProvider.
 type Provider struct {
 	BaseModel
 	Name         string     `gorm:"uniqueIndex;not null" json:"name"` // e.g., "MyOpenAIInstance"
@@ -74,24 +101,70 @@ type ApiKey struct {
 	IsHealthy  bool      `gorm:"default:true" json:"isHealthy"`
 	RpmLimit   int       `json:"rpmLimit"`
 	TpmLimit   int       `json:"tpmLimit"`
+	// UnhealthyUntil is set when routing disables the key after an auth or quota error;
+	// the key is re-enabled once it passes. Keys disabled by an admin have no deadline.
+	UnhealthyUntil *time.Time `json:"unhealthyUntil"`
 }
 
 // Log records API request details for monitoring and analytics.
 type Log struct {
-	ID               string    `gorm:"primary_key" json:"id"`
-	ProxyKey         string    `gorm:"index" json:"proxy_key"`
-	Model            string    `gorm:"index" json:"model"`
-	Provider         string    `gorm:"index" json:"provider"`
-	RequestURL       string    `json:"request_url"`
-	RequestBody      string    `json:"request_body"`
-	ResponseBody     string    `json:"response_body"`
-	ResponseStatus   int       `gorm:"index" json:"response_status"`
-	IsSuccess        bool      `json:"is_success"`
-	Latency          int64     `json:"latency"` // in milliseconds
-	Timestamp        time.Time `gorm:"index" json:"timestamp"`
-	PromptTokens     int       `json:"prompt_tokens"`
-	CompletionTokens int       `json:"completion_tokens"`
-	TotalTokens      int       `json:"total_tokens"`
+	ID                string    `gorm:"primary_key" json:"id"`
+	ProxyKey          string    `gorm:"index" json:"proxy_key"`
+	Model             string    `gorm:"index" json:"model"`           // Model name sent upstream
+	RequestedModel    string    `gorm:"index" json:"requested_model"` // Model name the client asked for
+	ResolvedModel     string    `gorm:"index" json:"resolved_model"`  // Model name after alias resolution
+	Provider          string    `gorm:"index" json:"provider"`
+	RequestURL        string    `json:"request_url"`
+	RequestBody       string    `json:"request_body"`
+	ResponseBody      string    `json:"response_body"`
+	ResponseStatus    int       `gorm:"index" json:"response_status"`
+	IsSuccess         bool      `json:"is_success"`
+	Latency           int64     `json:"latency"` // in milliseconds
+	Timestamp         time.Time `gorm:"index" json:"timestamp"`
+	PromptTokens      int       `json:"prompt_tokens"`
+	CompletionTokens  int       `json:"completion_tokens"`
+	TotalTokens       int       `json:"total_tokens"`
+	ClientAborted     bool      `gorm:"index" json:"client_aborted"` // The client disconnected before the response completed
+	ResponseSize      int64     `json:"response_size"`               // Bytes of the response body passed to the client
+	ResponseTruncated bool      `json:"response_truncated"`          // ResponseBody holds only the first part of the body
+	FinishReason      string    `json:"finish_reason"`               // Finish reasons of the choices, comma separated
+	Estimated         bool      `gorm:"index" json:"estimated"`      // Token counts were estimated locally because the upstream reported none
+	Cost              float64   `json:"cost"`                        // Price of the request in USD from the model's prices
+	ParentID          string    `gorm:"index" json:"parent_id"`      // Log entry of the first attempt when this one is a retry or failover
+	Attempt           int       `json:"attempt"`                     // Number of this upstream attempt within the client request, from 1
+	CacheHit          bool      `gorm:"index" json:"cache_hit"`      // Answered from the response cache without an upstream call
+	Similarity        float64   `json:"similarity"`                  // Best semantic cache similarity of the prompt; on a hit, that of the cached prompt
+}
+
+// ResponseCacheEntry persists a response of the response cache, so that it outlives restarts
+// and eviction from memory.
+type ResponseCacheEntry struct {
+	Key         string    `gorm:"primaryKey" json:"key"` // SHA-256 of the proxy key, operation and canonical request
+	ContentType string    `json:"contentType"`
+	Body        []byte    `json:"-"`
+	ExpiresAt   time.Time `gorm:"index" json:"expiresAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ModelAlias rewrites a requested model name before routing. Group aliases take precedence
+// over these global ones. Alias may contain one "*" wildcard, which Target can reuse.
+type ModelAlias struct {
+	BaseModel
+	Alias   string `gorm:"uniqueIndex;not null" json:"alias"`
+	Target  string `gorm:"not null" json:"target"`
+	Enabled *bool  `gorm:"default:true" json:"enabled"` // A pointer so that an explicit false is stored rather than the default
+}
+
+// CircuitBreakerEvent records a circuit breaker state transition for auditing.
+type CircuitBreakerEvent struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	Scope      string    `gorm:"index;not null" json:"scope"` // "provider" or "mapping"
+	ProviderID uint      `gorm:"index" json:"providerId"`
+	MappingID  uint      `gorm:"index" json:"mappingId"` // 0 for provider-level breakers
+	FromState  string    `json:"fromState"`
+	ToState    string    `json:"toState"`
+	Reason     string    `json:"reason"`
+	Timestamp  time.Time `gorm:"index" json:"timestamp"`
 }
 
 // Model represents a user-friendly definition of a model with common configurations.
@@ -102,6 +175,28 @@ type Model struct {
 	MaxRetry int    `gorm:"default:3" json:"maxRetry"`         // Global retry limit for this model
 	Timeout  int    `gorm:"default:30" json:"timeout"`         // Global timeout in seconds for this model
 	Enabled  bool   `gorm:"default:true" json:"enabled"`       // Whether this model definition is active
+	// LoadBalancePolicy spreads requests across mappings of equal provider priority:
+	// failover, weighted_random, round_robin, least_latency or least_in_flight (empty means failover)
+	LoadBalancePolicy string `json:"loadBalancePolicy"`
+	// StreamFailover decides what happens when a stream breaks before [DONE]: off, retry
+	// (switch mapping while nothing has been sent) or resume (continue from the sent content)
+	StreamFailover string `json:"streamFailover"`
+	// RetryPolicy is a JSON object tuning retries of failed attempts (statusCodes,
+	// sameProviderRetries, backoffMs, maxBackoffMs, retryTimeouts, retryNetworkErrors); a
+	// provider config's "retryPolicy" overrides it. MaxRetry bounds the retries in total.
+	RetryPolicy string `gorm:"type:text" json:"retryPolicy"`
+	// SemanticCacheModel names the embedding model that compares the last user message with
+	// earlier cached prompts of this model; empty leaves the semantic cache off. Responses are
+	// reused from a cosine similarity of SemanticCacheThreshold (0.95 when zero).
+	SemanticCacheModel     string  `json:"semanticCacheModel"`
+	SemanticCacheThreshold float64 `json:"semanticCacheThreshold"`
+	// Prices in USD used to compute the cost of each request in its log entry; zero leaves
+	// that part of a request unpriced
+	InputPrice  float64 `json:"inputPrice"`  // Per 1M prompt tokens
+	OutputPrice float64 `json:"outputPrice"` // Per 1M completion tokens
+	ImagePrice  float64 `json:"imagePrice"`  // Per generated image
+	AudioPrice  float64 `json:"audioPrice"`  // Per minute of transcribed audio
+	SpeechPrice float64 `json:"speechPrice"` // Per 1M characters of speech input
 }
 
 // ModelProviderMapping links a Model definition to a specific Provider instance,
@@ -114,6 +209,11 @@ type ModelProviderMapping struct {
 	ToolCall         *bool  `json:"toolCall"`         // Can this model instance accept tool calls?
 	StructuredOutput *bool  `json:"structuredOutput"` // Can this model instance accept structured output requests?
 	Image            *bool  `json:"image"`            // Can this model instance accept image inputs (vision)?
+	// Modalities served besides chat. Routing for an images or audio endpoint skips mappings
+	// that set the flag to false; unset flags do not restrict routing.
+	ImageGeneration    *bool `json:"imageGeneration"`    // Can this model instance generate images?
+	AudioTranscription *bool `json:"audioTranscription"` // Can this model instance transcribe audio?
+	Speech             *bool `json:"speech"`             // Can this model instance synthesize speech?
 	Weight           int    `gorm:"default:1" json:"weight"` // Weight for load balancing among multiple provider instances for the same model
 	Enabled          bool   `gorm:"default:true" json:"enabled"` // Is this specific mapping enabled?
 	Model            Model   `gorm:"foreignKey:ModelID" json:"model"`
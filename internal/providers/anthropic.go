@@ -0,0 +1,582 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAnthropicBaseURL is used when an anthropic provider has no baseUrl configured.
+	DefaultAnthropicBaseURL = "https://api.anthropic.com"
+	// DefaultAnthropicVersion is sent as the anthropic-version header unless the provider config overrides it.
+	DefaultAnthropicVersion = "2023-06-01"
+	// defaultAnthropicMaxTokens is used when the OpenAI request omits max_tokens, which Anthropic requires.
+	defaultAnthropicMaxTokens = 4096
+)
+
+// AnthropicProvider implements core.IProvider for the Anthropic Messages API.
+// The provider config may set "anthropicVersion" to override the API version header.
+type AnthropicProvider struct{}
+
+// Type returns the provider type name.
+func (p *AnthropicProvider) Type() string {
+	return "anthropic"
+}
+
+// ChatCompletion sends a non-streaming chat completion request.
+func (p *AnthropicProvider) ChatCompletion(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.chat(ctx, endpoint, requestBody, false)
+}
+
+// StreamChatCompletion sends a streaming chat completion request.
+func (p *AnthropicProvider) StreamChatCompletion(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.chat(ctx, endpoint, requestBody, true)
+}
+
+// Embeddings is not offered by the Anthropic API.
+func (p *AnthropicProvider) Embeddings(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return nil, core.ErrOperationNotSupported
+}
+
+// Completions serves a legacy completion through the chat API.
+func (p *AnthropicProvider) Completions(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return completionsViaChat(ctx, p, endpoint, requestBody)
+}
+
+// Responses serves a Responses API request through the chat API.
+func (p *AnthropicProvider) Responses(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return responsesViaChat(ctx, p, endpoint, requestBody)
+}
+
+// ImageGeneration is not offered by the Anthropic API.
+func (p *AnthropicProvider) ImageGeneration(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return nil, core.ErrOperationNotSupported
+}
+
+// AudioTranscription is not offered by the Anthropic API.
+func (p *AnthropicProvider) AudioTranscription(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return nil, core.ErrOperationNotSupported
+}
+
+// Speech is not offered by the Anthropic API.
+func (p *AnthropicProvider) Speech(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return nil, core.ErrOperationNotSupported
+}
+
+// ListModels retrieves the list of available models from the /v1/models endpoint.
+func (p *AnthropicProvider) ListModels(ctx context.Context, endpoint *core.ProviderEndpoint) ([]string, error) {
+	url, err := resolveURL(baseURLOrDefault(endpoint, DefaultAnthropicBaseURL), "/v1/models", "/models")
+	if err != nil {
+		return nil, err
+	}
+	req, err := newJSONRequest(ctx, "GET", url+"?limit=1000", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setAnthropicHeaders(req, endpoint)
+
+	var modelsResp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := getJSON(httpClient(endpoint), req, &modelsResp); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(modelsResp.Data))
+	for _, model := range modelsResp.Data {
+		models = append(models, model.ID)
+	}
+	return models, nil
+}
+
+// DefaultModels returns the fallback model catalog.
+func (p *AnthropicProvider) DefaultModels() []string {
+	return []string{
+		"claude-3-haiku-20240307",
+		"claude-3-opus-20240229",
+		"claude-3-sonnet-20240229",
+		"claude-3-5-sonnet-20240620",
+		"claude-2.1", "claude-2.0",
+	}
+}
+
+// chat translates the OpenAI body, sends it to /v1/messages and translates the response back.
+func (p *AnthropicProvider) chat(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}, stream bool) (*http.Response, error) {
+	url, err := resolveURL(baseURLOrDefault(endpoint, DefaultAnthropicBaseURL), "/v1/messages", "/messages")
+	if err != nil {
+		return nil, err
+	}
+	payload, err := ConvertOpenAIToAnthropic(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	payload["stream"] = stream
+
+	req, err := newJSONRequest(ctx, "POST", url, payload)
+	if err != nil {
+		return nil, err
+	}
+	setAnthropicHeaders(req, endpoint)
+
+	resp, err := httpClient(endpoint).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	ConvertAnthropicResponse(resp, stream)
+	return resp, nil
+}
+
+// setAnthropicHeaders sets the API key and version headers Anthropic requires.
+func setAnthropicHeaders(req *http.Request, endpoint *core.ProviderEndpoint) {
+	version, _ := endpoint.Config["anthropicVersion"].(string)
+	if version == "" {
+		version = DefaultAnthropicVersion
+	}
+	req.Header.Set("x-api-key", endpoint.ApiKey)
+	req.Header.Set("anthropic-version", version)
+}
+
+// ConvertOpenAIToAnthropic maps an OpenAI chat completion request onto the Anthropic Messages format.
+func ConvertOpenAIToAnthropic(body map[string]interface{}) (map[string]interface{}, error) {
+	rawMessages, ok := body["messages"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("messages must be an array")
+	}
+
+	var systemParts []string
+	var messages []map[string]interface{}
+
+	// appendBlocks adds content blocks for role, merging with the previous message
+	// because Anthropic requires user and assistant turns to alternate.
+	appendBlocks := func(role string, blocks []interface{}) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(messages); n > 0 && messages[n-1]["role"] == role {
+			messages[n-1]["content"] = append(messages[n-1]["content"].([]interface{}), blocks...)
+			return
+		}
+		messages = append(messages, map[string]interface{}{"role": role, "content": blocks})
+	}
+
+	for _, raw := range rawMessages {
+		msg, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := msg["role"].(string)
+
+		switch role {
+		case "system", "developer":
+			if text := MessageText(msg["content"]); text != "" {
+				systemParts = append(systemParts, text)
+			}
+		case "tool":
+			toolCallID, _ := msg["tool_call_id"].(string)
+			appendBlocks("user", []interface{}{map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": toolCallID,
+				"content":     MessageText(msg["content"]),
+			}})
+		case "assistant":
+			blocks := anthropicContentBlocks(msg["content"])
+			if toolCalls, ok := msg["tool_calls"].([]interface{}); ok {
+				for _, tc := range toolCalls {
+					call, ok := tc.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					fn, _ := call["function"].(map[string]interface{})
+					name, _ := fn["name"].(string)
+					id, _ := call["id"].(string)
+					input := map[string]interface{}{}
+					if args, ok := fn["arguments"].(string); ok && args != "" {
+						if err := json.Unmarshal([]byte(args), &input); err != nil {
+							return nil, fmt.Errorf("invalid arguments for tool call %s: %w", id, err)
+						}
+					}
+					blocks = append(blocks, map[string]interface{}{
+						"type":  "tool_use",
+						"id":    id,
+						"name":  name,
+						"input": input,
+					})
+				}
+			}
+			appendBlocks("assistant", blocks)
+		default:
+			appendBlocks("user", anthropicContentBlocks(msg["content"]))
+		}
+	}
+
+	payload := map[string]interface{}{
+		"model":    body["model"],
+		"messages": messages,
+	}
+	if len(systemParts) > 0 {
+		payload["system"] = strings.Join(systemParts, "\n\n")
+	}
+
+	maxTokens := defaultAnthropicMaxTokens
+	if v, ok := toInt(body["max_tokens"]); ok && v > 0 {
+		maxTokens = v
+	} else if v, ok := toInt(body["max_completion_tokens"]); ok && v > 0 {
+		maxTokens = v
+	}
+	payload["max_tokens"] = maxTokens
+
+	if stops := stopSequences(body["stop"]); len(stops) > 0 {
+		payload["stop_sequences"] = stops
+	}
+	for _, key := range []string{"temperature", "top_p", "top_k", "stream"} {
+		if v, ok := body[key]; ok {
+			payload[key] = v
+		}
+	}
+	if user, ok := body["user"].(string); ok && user != "" {
+		payload["metadata"] = map[string]interface{}{"user_id": user}
+	}
+
+	if tools, ok := body["tools"].([]interface{}); ok && len(tools) > 0 {
+		var anthropicTools []interface{}
+		for _, t := range tools {
+			tool, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fn, ok := tool["function"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			schema, ok := fn["parameters"].(map[string]interface{})
+			if !ok {
+				schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+			}
+			converted := map[string]interface{}{
+				"name":         fn["name"],
+				"input_schema": schema,
+			}
+			if desc, ok := fn["description"].(string); ok && desc != "" {
+				converted["description"] = desc
+			}
+			anthropicTools = append(anthropicTools, converted)
+		}
+		payload["tools"] = anthropicTools
+
+		if choice := anthropicToolChoice(body["tool_choice"]); choice != nil {
+			if parallel, ok := body["parallel_tool_calls"].(bool); ok && !parallel {
+				choice["disable_parallel_tool_use"] = true
+			}
+			if choice["type"] == "none" {
+				delete(payload, "tools")
+			} else {
+				payload["tool_choice"] = choice
+			}
+		}
+	}
+
+	return payload, nil
+}
+
+// anthropicContentBlocks converts OpenAI message content into Anthropic content blocks.
+func anthropicContentBlocks(content interface{}) []interface{} {
+	switch v := content.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []interface{}{map[string]interface{}{"type": "text", "text": v}}
+	case []interface{}:
+		var blocks []interface{}
+		for _, item := range v {
+			part, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch part["type"] {
+			case "text":
+				if text, _ := part["text"].(string); text != "" {
+					blocks = append(blocks, map[string]interface{}{"type": "text", "text": text})
+				}
+			case "image_url":
+				var url string
+				switch img := part["image_url"].(type) {
+				case map[string]interface{}:
+					url, _ = img["url"].(string)
+				case string:
+					url = img
+				}
+				if mediaType, data, ok := parseDataURL(url); ok {
+					blocks = append(blocks, map[string]interface{}{
+						"type":   "image",
+						"source": map[string]interface{}{"type": "base64", "media_type": mediaType, "data": data},
+					})
+				} else if url != "" {
+					blocks = append(blocks, map[string]interface{}{
+						"type":   "image",
+						"source": map[string]interface{}{"type": "url", "url": url},
+					})
+				}
+			}
+		}
+		return blocks
+	default:
+		return nil
+	}
+}
+
+// anthropicToolChoice maps OpenAI's tool_choice onto Anthropic's.
+func anthropicToolChoice(choice interface{}) map[string]interface{} {
+	switch v := choice.(type) {
+	case string:
+		switch v {
+		case "auto":
+			return map[string]interface{}{"type": "auto"}
+		case "none":
+			return map[string]interface{}{"type": "none"}
+		case "required":
+			return map[string]interface{}{"type": "any"}
+		}
+	case map[string]interface{}:
+		if fn, ok := v["function"].(map[string]interface{}); ok {
+			return map[string]interface{}{"type": "tool", "name": fn["name"]}
+		}
+	}
+	return nil
+}
+
+// anthropicFinishReason maps Anthropic stop reasons onto OpenAI finish reasons.
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+// anthropicMessage is the subset of an Anthropic Messages response used for translation.
+type anthropicMessage struct {
+	ID         string `json:"id"`
+	Model      string `json:"model"`
+	StopReason string `json:"stop_reason"`
+	Content    []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		ID    string          `json:"id"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	Usage anthropicUsage `json:"usage"`
+}
+
+// anthropicUsage reports token counts; cached input tokens are billed as prompt tokens.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+func (u anthropicUsage) promptTokens() int {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// ConvertAnthropicResponse rewrites an Anthropic response in place into OpenAI format.
+// Streaming responses are translated incrementally; errors are mapped onto OpenAI's error envelope.
+func ConvertAnthropicResponse(resp *http.Response, stream bool) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		convertAnthropicError(resp)
+		return
+	}
+	if stream {
+		pipeStream(resp, translateAnthropicStream)
+		return
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+	var msg anthropicMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+
+	message := map[string]interface{}{"role": "assistant"}
+	var text strings.Builder
+	var toolCalls []interface{}
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			args := string(block.Input)
+			if args == "" {
+				args = "{}"
+			}
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"id":       block.ID,
+				"type":     "function",
+				"function": map[string]interface{}{"name": block.Name, "arguments": args},
+			})
+		}
+	}
+	message["content"] = text.String()
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+
+	replaceJSONBody(resp, map[string]interface{}{
+		"id":      chatCompletionID(msg.ID),
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   msg.Model,
+		"choices": []interface{}{
+			map[string]interface{}{
+				"index":         0,
+				"message":       message,
+				"finish_reason": anthropicFinishReason(msg.StopReason),
+			},
+		},
+		"usage": openAIUsage(msg.Usage.promptTokens(), msg.Usage.OutputTokens),
+	})
+}
+
+// convertAnthropicError maps {"type":"error","error":{...}} onto OpenAI's error envelope.
+func convertAnthropicError(resp *http.Response) {
+	raw, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var upstream struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &upstream) != nil || upstream.Error.Message == "" {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+	replaceJSONBody(resp, openAIErrorBody(upstream.Error.Message, upstream.Error.Type, resp.StatusCode))
+}
+
+// translateAnthropicStream converts Anthropic Messages SSE events into chat.completion.chunk events.
+func translateAnthropicStream(upstream io.Reader, w io.Writer) error {
+	var (
+		id      = chatCompletionID("")
+		model   string
+		created = time.Now().Unix()
+		usage   anthropicUsage
+		// toolIndex maps Anthropic content block indexes to OpenAI tool_call indexes.
+		toolIndex = map[int]int{}
+	)
+
+	err := readSSE(upstream, func(ev sseEvent) error {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(ev.Data), &data); err != nil {
+			return nil
+		}
+		eventType := ev.Event
+		if eventType == "" {
+			eventType, _ = data["type"].(string)
+		}
+
+		switch eventType {
+		case "message_start":
+			var start struct {
+				Message anthropicMessage `json:"message"`
+			}
+			json.Unmarshal([]byte(ev.Data), &start)
+			id = chatCompletionID(start.Message.ID)
+			model = start.Message.Model
+			usage = start.Message.Usage
+			return writeSSEData(w, newChatCompletionChunk(id, model, created,
+				map[string]interface{}{"role": "assistant", "content": ""}, nil))
+
+		case "content_block_start":
+			index, _ := toInt(data["index"])
+			block, _ := data["content_block"].(map[string]interface{})
+			if block["type"] != "tool_use" {
+				return nil
+			}
+			toolIdx := len(toolIndex)
+			toolIndex[index] = toolIdx
+			return writeSSEData(w, newChatCompletionChunk(id, model, created, map[string]interface{}{
+				"tool_calls": []interface{}{map[string]interface{}{
+					"index":    toolIdx,
+					"id":       block["id"],
+					"type":     "function",
+					"function": map[string]interface{}{"name": block["name"], "arguments": ""},
+				}},
+			}, nil))
+
+		case "content_block_delta":
+			index, _ := toInt(data["index"])
+			delta, _ := data["delta"].(map[string]interface{})
+			switch delta["type"] {
+			case "text_delta":
+				return writeSSEData(w, newChatCompletionChunk(id, model, created,
+					map[string]interface{}{"content": delta["text"]}, nil))
+			case "input_json_delta":
+				toolIdx, ok := toolIndex[index]
+				if !ok {
+					return nil
+				}
+				return writeSSEData(w, newChatCompletionChunk(id, model, created, map[string]interface{}{
+					"tool_calls": []interface{}{map[string]interface{}{
+						"index":    toolIdx,
+						"function": map[string]interface{}{"arguments": delta["partial_json"]},
+					}},
+				}, nil))
+			}
+
+		case "message_delta":
+			var md struct {
+				Delta struct {
+					StopReason string `json:"stop_reason"`
+				} `json:"delta"`
+				Usage anthropicUsage `json:"usage"`
+			}
+			json.Unmarshal([]byte(ev.Data), &md)
+			if md.Usage.OutputTokens > 0 {
+				usage.OutputTokens = md.Usage.OutputTokens
+			}
+			if md.Usage.InputTokens > 0 {
+				usage.InputTokens = md.Usage.InputTokens
+			}
+			// The finish chunk also carries usage so token accounting works without stream_options.
+			chunk := newChatCompletionChunk(id, model, created, map[string]interface{}{},
+				anthropicFinishReason(md.Delta.StopReason))
+			chunk["usage"] = openAIUsage(usage.promptTokens(), usage.OutputTokens)
+			return writeSSEData(w, chunk)
+
+		case "message_stop":
+			return writeSSEDone(w)
+
+		case "error":
+			errObj, _ := data["error"].(map[string]interface{})
+			message, _ := errObj["message"].(string)
+			errType, _ := errObj["type"].(string)
+			return writeSSEData(w, openAIErrorBody(message, errType, nil))
+		}
+		return nil
+	})
+	return err
+}
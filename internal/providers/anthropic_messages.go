@@ -0,0 +1,554 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Translation for the inbound /v1/messages endpoint: Anthropic Messages requests from clients
+// are mapped onto OpenAI chat completions, which every provider adapter accepts, and the chat
+// responses are mapped back into Anthropic messages and stream events.
+
+// ConvertAnthropicToOpenAI maps an Anthropic Messages request onto an OpenAI chat completion
+// request. tool_result blocks become tool messages ahead of the rest of their user turn, and
+// thinking blocks from earlier turns are dropped.
+func ConvertAnthropicToOpenAI(body map[string]interface{}) (map[string]interface{}, error) {
+	rawMessages, ok := body["messages"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("messages: field required")
+	}
+
+	var messages []interface{}
+	if system := anthropicSystemText(body["system"]); system != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": system})
+	}
+	for i, raw := range rawMessages {
+		msg, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("messages.%d: must be an object", i)
+		}
+		role, _ := msg["role"].(string)
+		switch role {
+		case "user":
+			converted, err := openAIUserMessages(msg["content"])
+			if err != nil {
+				return nil, fmt.Errorf("messages.%d: %w", i, err)
+			}
+			messages = append(messages, converted...)
+		case "assistant":
+			converted, err := openAIAssistantMessage(msg["content"])
+			if err != nil {
+				return nil, fmt.Errorf("messages.%d: %w", i, err)
+			}
+			messages = append(messages, converted)
+		default:
+			return nil, fmt.Errorf("messages.%d.role: must be \"user\" or \"assistant\"", i)
+		}
+	}
+
+	chatBody := map[string]interface{}{
+		"model":    body["model"],
+		"messages": messages,
+	}
+	if maxTokens, ok := toInt(body["max_tokens"]); ok {
+		chatBody["max_tokens"] = maxTokens
+	}
+	for _, key := range []string{"temperature", "top_p", "stream"} {
+		if v, ok := body[key]; ok {
+			chatBody[key] = v
+		}
+	}
+	if stops, ok := body["stop_sequences"].([]interface{}); ok && len(stops) > 0 {
+		chatBody["stop"] = stops
+	}
+	if metadata, ok := body["metadata"].(map[string]interface{}); ok {
+		if user, ok := metadata["user_id"].(string); ok && user != "" {
+			chatBody["user"] = user
+		}
+	}
+
+	if tools, ok := body["tools"].([]interface{}); ok && len(tools) > 0 {
+		var chatTools []interface{}
+		for i, t := range tools {
+			tool, ok := t.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("tools.%d: must be an object", i)
+			}
+			if toolType, _ := tool["type"].(string); toolType != "" && toolType != "custom" {
+				return nil, fmt.Errorf("tools.%d: server tool %q is not supported", i, toolType)
+			}
+			function := map[string]interface{}{"name": tool["name"]}
+			if schema, ok := tool["input_schema"]; ok {
+				function["parameters"] = schema
+			}
+			if desc, ok := tool["description"].(string); ok && desc != "" {
+				function["description"] = desc
+			}
+			chatTools = append(chatTools, map[string]interface{}{"type": "function", "function": function})
+		}
+		chatBody["tools"] = chatTools
+	}
+	if choice, ok := body["tool_choice"].(map[string]interface{}); ok {
+		switch choice["type"] {
+		case "auto":
+			chatBody["tool_choice"] = "auto"
+		case "any":
+			chatBody["tool_choice"] = "required"
+		case "none":
+			chatBody["tool_choice"] = "none"
+		case "tool":
+			chatBody["tool_choice"] = map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": choice["name"]}}
+		}
+		if disabled, ok := choice["disable_parallel_tool_use"].(bool); ok && disabled {
+			chatBody["parallel_tool_calls"] = false
+		}
+	}
+
+	return chatBody, nil
+}
+
+// anthropicSystemText flattens an Anthropic system prompt, a string or text blocks.
+func anthropicSystemText(system interface{}) string {
+	if blocks, ok := system.([]interface{}); ok {
+		return MessageText(blocks)
+	}
+	text, _ := system.(string)
+	return text
+}
+
+// openAIUserMessages converts an Anthropic user turn into a tool message per tool_result
+// block followed by a user message with the remaining content.
+func openAIUserMessages(content interface{}) ([]interface{}, error) {
+	blocks, ok := content.([]interface{})
+	if !ok {
+		text, _ := content.(string)
+		return []interface{}{map[string]interface{}{"role": "user", "content": text}}, nil
+	}
+
+	var messages []interface{}
+	var parts []interface{}
+	hasImage := false
+	for i, raw := range blocks {
+		block, _ := raw.(map[string]interface{})
+		switch block["type"] {
+		case "text":
+			parts = append(parts, map[string]interface{}{"type": "text", "text": block["text"]})
+		case "image":
+			url, err := anthropicImageURL(block["source"])
+			if err != nil {
+				return nil, fmt.Errorf("content.%d: %w", i, err)
+			}
+			parts = append(parts, map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": url}})
+			hasImage = true
+		case "tool_result":
+			output, ok := block["content"].(string)
+			if !ok {
+				output = MessageText(block["content"])
+			}
+			if isError, _ := block["is_error"].(bool); isError {
+				output = "Error: " + output
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":         "tool",
+				"tool_call_id": block["tool_use_id"],
+				"content":      output,
+			})
+		case "document":
+			return nil, fmt.Errorf("content.%d: document blocks are not supported", i)
+		}
+	}
+	if len(parts) > 0 {
+		if hasImage {
+			messages = append(messages, map[string]interface{}{"role": "user", "content": parts})
+		} else {
+			messages = append(messages, map[string]interface{}{"role": "user", "content": MessageText(parts)})
+		}
+	}
+	return messages, nil
+}
+
+// anthropicImageURL turns an Anthropic image source into a URL or data: URL.
+func anthropicImageURL(source interface{}) (string, error) {
+	src, _ := source.(map[string]interface{})
+	switch src["type"] {
+	case "base64":
+		mediaType, _ := src["media_type"].(string)
+		data, _ := src["data"].(string)
+		return "data:" + mediaType + ";base64," + data, nil
+	case "url":
+		url, _ := src["url"].(string)
+		return url, nil
+	}
+	return "", fmt.Errorf("image source type %v is not supported", src["type"])
+}
+
+// openAIAssistantMessage converts an Anthropic assistant turn into an assistant message with
+// its tool_use blocks as tool calls.
+func openAIAssistantMessage(content interface{}) (map[string]interface{}, error) {
+	blocks, ok := content.([]interface{})
+	if !ok {
+		text, _ := content.(string)
+		return map[string]interface{}{"role": "assistant", "content": text}, nil
+	}
+
+	var text strings.Builder
+	var toolCalls []interface{}
+	for _, raw := range blocks {
+		block, _ := raw.(map[string]interface{})
+		switch block["type"] {
+		case "text":
+			value, _ := block["text"].(string)
+			text.WriteString(value)
+		case "tool_use":
+			arguments, err := json.Marshal(block["input"])
+			if err != nil {
+				return nil, err
+			}
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"id":       block["id"],
+				"type":     "function",
+				"function": map[string]interface{}{"name": block["name"], "arguments": string(arguments)},
+			})
+		}
+	}
+	message := map[string]interface{}{"role": "assistant", "content": text.String()}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+		if text.Len() == 0 {
+			message["content"] = nil
+		}
+	}
+	return message, nil
+}
+
+// anthropicStopReason maps OpenAI finish reasons onto Anthropic stop reasons.
+func anthropicStopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	case "content_filter":
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
+
+// anthropicMessageID turns a chat completion ID into an Anthropic message ID.
+func anthropicMessageID(chatID string) string {
+	if chatID == "" {
+		return fmt.Sprintf("msg_%d", time.Now().UnixNano())
+	}
+	return "msg_" + strings.TrimPrefix(chatID, "chatcmpl-")
+}
+
+// toolInput decodes tool call arguments into an Anthropic tool_use input object.
+func toolInput(arguments string) json.RawMessage {
+	if trimmed := strings.TrimSpace(arguments); strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	return json.RawMessage("{}")
+}
+
+// ConvertOpenAIResponseToAnthropic rewrites a chat completion response in place as an
+// Anthropic message, or a chat stream as Anthropic Messages stream events.
+func ConvertOpenAIResponseToAnthropic(resp *http.Response, stream bool) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		convertOpenAIErrorToAnthropic(resp)
+		return
+	}
+	if stream {
+		pipeStream(resp, translateOpenAIStreamToAnthropic)
+		return
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var chat struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message      chatCompletionMessage `json:"message"`
+			FinishReason string                `json:"finish_reason"`
+		} `json:"choices"`
+		Usage chatUsage `json:"usage"`
+	}
+	if err != nil || json.Unmarshal(raw, &chat) != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+
+	content := []interface{}{}
+	stopReason := "end_turn"
+	if len(chat.Choices) > 0 {
+		choice := chat.Choices[0]
+		stopReason = anthropicStopReason(choice.FinishReason)
+		if text := MessageText(choice.Message.Content); text != "" {
+			content = append(content, map[string]interface{}{"type": "text", "text": text})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			content = append(content, map[string]interface{}{
+				"type":  "tool_use",
+				"id":    call.ID,
+				"name":  call.Function.Name,
+				"input": toolInput(call.Function.Arguments),
+			})
+		}
+	}
+	replaceJSONBody(resp, map[string]interface{}{
+		"id":            anthropicMessageID(chat.ID),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         chat.Model,
+		"content":       content,
+		"stop_reason":   stopReason,
+		"stop_sequence": nil,
+		"usage": map[string]interface{}{
+			"input_tokens":  chat.Usage.PromptTokens,
+			"output_tokens": chat.Usage.CompletionTokens,
+		},
+	})
+}
+
+// AnthropicErrorType returns the Anthropic error type for an HTTP status.
+func AnthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case 529:
+		return "overloaded_error"
+	}
+	if status < http.StatusInternalServerError {
+		return "invalid_request_error"
+	}
+	return "api_error"
+}
+
+// AnthropicErrorBody builds Anthropic's error envelope.
+func AnthropicErrorBody(errType, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":  "error",
+		"error": map[string]interface{}{"type": errType, "message": message},
+	}
+}
+
+// convertOpenAIErrorToAnthropic maps OpenAI's error envelope onto Anthropic's.
+func convertOpenAIErrorToAnthropic(resp *http.Response) {
+	raw, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var upstream struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &upstream) != nil || upstream.Error.Message == "" {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+	replaceJSONBody(resp, AnthropicErrorBody(AnthropicErrorType(resp.StatusCode), upstream.Error.Message))
+}
+
+// anthropicStream converts chat.completion.chunk events into Anthropic stream events. Text
+// and each tool call become content blocks; the closing message_delta is sent once the chat
+// stream has ended, when its usage is known.
+type anthropicStream struct {
+	w       io.Writer
+	started bool
+	id      string
+	model   string
+
+	blockCount   int    // Content blocks started so far
+	openBlock    string // Type of the open content block, if any
+	openToolCall int    // Chat tool_call index of the open tool_use block
+	finishReason string
+	usage        chatUsage
+}
+
+func translateOpenAIStreamToAnthropic(upstream io.Reader, w io.Writer) error {
+	as := &anthropicStream{w: w}
+	finished := false
+	err := readSSE(upstream, func(ev sseEvent) error {
+		if finished {
+			return nil
+		}
+		if ev.Data == "[DONE]" {
+			finished = true
+			return as.finish()
+		}
+		var chunk struct {
+			ID      string `json:"id"`
+			Model   string `json:"model"`
+			Choices []struct {
+				Index int `json:"index"`
+				Delta struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *chatUsage `json:"usage"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			return nil
+		}
+		if chunk.Error != nil {
+			finished = true
+			return as.emit("error", AnthropicErrorBody("api_error", chunk.Error.Message))
+		}
+		if err := as.start(chunk.ID, chunk.Model); err != nil {
+			return err
+		}
+		if chunk.Usage != nil {
+			as.usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index != 0 {
+				continue
+			}
+			if choice.Delta.Content != "" {
+				if err := as.text(choice.Delta.Content); err != nil {
+					return err
+				}
+			}
+			for _, call := range choice.Delta.ToolCalls {
+				if err := as.toolCall(call.Index, call.ID, call.Function.Name, call.Function.Arguments); err != nil {
+					return err
+				}
+			}
+			if choice.FinishReason != "" {
+				as.finishReason = choice.FinishReason
+			}
+		}
+		return nil
+	})
+	if err == nil && !finished && as.started {
+		err = as.finish()
+	}
+	return err
+}
+
+// emit writes one Anthropic event; the payload's type matches the event name.
+func (as *anthropicStream) emit(eventType string, payload map[string]interface{}) error {
+	payload["type"] = eventType
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(as.w, "event: %s\ndata: %s\n\n", eventType, data)
+	return err
+}
+
+func (as *anthropicStream) start(chatID, model string) error {
+	if as.started {
+		return nil
+	}
+	as.started = true
+	as.id = anthropicMessageID(chatID)
+	as.model = model
+	return as.emit("message_start", map[string]interface{}{
+		"message": map[string]interface{}{
+			"id":            as.id,
+			"type":          "message",
+			"role":          "assistant",
+			"model":         as.model,
+			"content":       []interface{}{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         map[string]interface{}{"input_tokens": 0, "output_tokens": 0},
+		},
+	})
+}
+
+func (as *anthropicStream) startBlock(blockType string, block map[string]interface{}) error {
+	if err := as.closeBlock(); err != nil {
+		return err
+	}
+	as.openBlock = blockType
+	as.blockCount++
+	return as.emit("content_block_start", map[string]interface{}{"index": as.blockCount - 1, "content_block": block})
+}
+
+func (as *anthropicStream) closeBlock() error {
+	if as.openBlock == "" {
+		return nil
+	}
+	as.openBlock = ""
+	return as.emit("content_block_stop", map[string]interface{}{"index": as.blockCount - 1})
+}
+
+func (as *anthropicStream) text(delta string) error {
+	if as.openBlock != "text" {
+		if err := as.startBlock("text", map[string]interface{}{"type": "text", "text": ""}); err != nil {
+			return err
+		}
+	}
+	return as.emit("content_block_delta", map[string]interface{}{
+		"index": as.blockCount - 1,
+		"delta": map[string]interface{}{"type": "text_delta", "text": delta},
+	})
+}
+
+func (as *anthropicStream) toolCall(index int, id, name, arguments string) error {
+	if as.openBlock != "tool_use" || as.openToolCall != index {
+		if id == "" && name == "" {
+			// A fragment of a call whose block has already been closed
+			return nil
+		}
+		if err := as.startBlock("tool_use", map[string]interface{}{
+			"type": "tool_use", "id": id, "name": name, "input": map[string]interface{}{},
+		}); err != nil {
+			return err
+		}
+		as.openToolCall = index
+	}
+	if arguments == "" {
+		return nil
+	}
+	return as.emit("content_block_delta", map[string]interface{}{
+		"index": as.blockCount - 1,
+		"delta": map[string]interface{}{"type": "input_json_delta", "partial_json": arguments},
+	})
+}
+
+// finish closes the open block and ends the message.
+func (as *anthropicStream) finish() error {
+	if err := as.start("", ""); err != nil {
+		return err
+	}
+	if err := as.closeBlock(); err != nil {
+		return err
+	}
+	if err := as.emit("message_delta", map[string]interface{}{
+		"delta": map[string]interface{}{"stop_reason": anthropicStopReason(as.finishReason), "stop_sequence": nil},
+		"usage": map[string]interface{}{"input_tokens": as.usage.PromptTokens, "output_tokens": as.usage.CompletionTokens},
+	}); err != nil {
+		return err
+	}
+	return as.emit("message_stop", map[string]interface{}{})
+}
@@ -0,0 +1,115 @@
+package providers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTranslateOpenAIStreamToAnthropic(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		events     []string
+		text       string
+		toolInput  string
+		stopReason string
+		usage      string // "input/output"
+	}{
+		{
+			name: "text",
+			input: sseData(
+				`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`,
+				`[DONE]`),
+			events: []string{
+				"message_start",
+				"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+				"message_delta", "message_stop",
+			},
+			text:       "Hello",
+			stopReason: "end_turn",
+			usage:      "5/2",
+		},
+		{
+			name: "text then tool call",
+			input: sseData(
+				`{"id":"chatcmpl-2","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Checking."}}]}`,
+				`{"id":"chatcmpl-2","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+				`{"id":"chatcmpl-2","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+				`{"id":"chatcmpl-2","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+				`{"id":"chatcmpl-2","model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+				`[DONE]`),
+			events: []string{
+				"message_start",
+				"content_block_start", "content_block_delta", "content_block_stop",
+				"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+				"message_delta", "message_stop",
+			},
+			text:       "Checking.",
+			toolInput:  `{"city":"Paris"}`,
+			stopReason: "tool_use",
+			usage:      "0/0",
+		},
+		{
+			name: "length without [DONE]",
+			input: sseData(
+				`{"id":"chatcmpl-3","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"cut"},"finish_reason":"length"}]}`),
+			events: []string{
+				"message_start",
+				"content_block_start", "content_block_delta", "content_block_stop",
+				"message_delta", "message_stop",
+			},
+			text:       "cut",
+			stopReason: "max_tokens",
+			usage:      "0/0",
+		},
+		{
+			name:   "error",
+			input:  sseData(`{"error":{"message":"upstream failed"}}`, `[DONE]`),
+			events: []string{"error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var types []string
+			var text, toolInput, stopReason, usage string
+			for _, ev := range runTranslator(t, translateOpenAIStreamToAnthropic, tt.input) {
+				data := eventData(t, ev)
+				if data["type"] != ev.Event {
+					t.Errorf("event %q has payload type %v", ev.Event, data["type"])
+				}
+				types = append(types, ev.Event)
+				switch ev.Event {
+				case "message_start":
+					message := data["message"].(map[string]interface{})
+					if id, _ := message["id"].(string); !strings.HasPrefix(id, "msg_") {
+						t.Errorf("message id %q", id)
+					}
+				case "content_block_delta":
+					delta := data["delta"].(map[string]interface{})
+					switch delta["type"] {
+					case "text_delta":
+						text += delta["text"].(string)
+					case "input_json_delta":
+						toolInput += delta["partial_json"].(string)
+					}
+				case "message_delta":
+					stopReason, _ = data["delta"].(map[string]interface{})["stop_reason"].(string)
+					u := data["usage"].(map[string]interface{})
+					usage = fmt.Sprintf("%v/%v", u["input_tokens"], u["output_tokens"])
+				}
+			}
+			if !reflect.DeepEqual(types, tt.events) {
+				t.Fatalf("events %q, want %q", types, tt.events)
+			}
+			if text != tt.text || toolInput != tt.toolInput || stopReason != tt.stopReason || usage != tt.usage {
+				t.Errorf("got text %q tool input %q stop reason %q usage %q", text, toolInput, stopReason, usage)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTranslateAnthropicStream(t *testing.T) {
+	const start = `{"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4","usage":{"input_tokens":10,"output_tokens":1}}}`
+
+	tests := []struct {
+		name  string
+		input string
+		want  chatStream
+	}{
+		{
+			name: "text",
+			input: sseData(start,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+				`{"type":"ping"}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+				`{"type":"message_stop"}`),
+			want: chatStream{role: "assistant", content: "Hello", finishReason: "stop", usage: "10/5/15", done: true},
+		},
+		{
+			name: "tool use after text",
+			input: sseData(start,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking."}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+				`{"type":"content_block_stop","index":1}`,
+				`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}`,
+				`{"type":"message_stop"}`),
+			want: chatStream{
+				role:         "assistant",
+				content:      "Checking.",
+				toolCalls:    []string{`toolu_1 get_weather {"city":"Paris"}`},
+				finishReason: "tool_calls",
+				usage:        "10/20/30",
+				done:         true,
+			},
+		},
+		{
+			name: "cached prompt tokens and max tokens",
+			input: sseData(
+				`{"type":"message_start","message":{"id":"msg_2","model":"claude-sonnet-4","usage":{"input_tokens":10,"cache_read_input_tokens":20,"output_tokens":1}}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"cut"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":3}}`,
+				`{"type":"message_stop"}`),
+			want: chatStream{role: "assistant", content: "cut", finishReason: "length", usage: "30/3/33", done: true},
+		},
+		{
+			name: "error event",
+			input: sseData(start,
+				`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`),
+			want: chatStream{role: "assistant", errMessage: "Overloaded"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := runTranslator(t, translateAnthropicStream, tt.input)
+			if got := summarizeChatStream(t, events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stream %+v, want %+v", got, tt.want)
+			}
+			if id, _ := eventData(t, events[0])["id"].(string); !strings.HasPrefix(id, "chatcmpl-msg_") {
+				t.Errorf("chunk id %q not derived from the message id", id)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Translation of legacy /v1/completions requests onto chat completions, for providers that
+// only offer a chat API.
+
+// completionPassthroughFields are the legacy completion parameters chat completions accept as is.
+var completionPassthroughFields = []string{
+	"model", "max_tokens", "temperature", "top_p", "n", "stream", "stream_options", "stop",
+	"presence_penalty", "frequency_penalty", "logit_bias", "user", "seed",
+}
+
+// completionsViaChat serves a legacy completion through the provider's chat API.
+func completionsViaChat(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	chatBody, err := ConvertCompletionToChat(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	stream, _ := chatBody["stream"].(bool)
+	var resp *http.Response
+	if stream {
+		resp, err = provider.StreamChatCompletion(ctx, endpoint, chatBody)
+	} else {
+		resp, err = provider.ChatCompletion(ctx, endpoint, chatBody)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ConvertChatToCompletionResponse(resp, stream)
+	return resp, nil
+}
+
+// ConvertCompletionToChat maps a legacy completion request onto a chat completion request with
+// the prompt as a single user message. Features chat cannot express (several prompts, token
+// prompts, echo, suffix, best_of) are reported as core.ErrOperationNotSupported so the request
+// can move to a provider with a native completions API.
+func ConvertCompletionToChat(body map[string]interface{}) (map[string]interface{}, error) {
+	prompt, err := completionPrompt(body["prompt"])
+	if err != nil {
+		return nil, err
+	}
+	if echo, _ := body["echo"].(bool); echo {
+		return nil, fmt.Errorf("echo requires a native completions API: %w", core.ErrOperationNotSupported)
+	}
+	if suffix, _ := body["suffix"].(string); suffix != "" {
+		return nil, fmt.Errorf("suffix requires a native completions API: %w", core.ErrOperationNotSupported)
+	}
+	if bestOf, ok := toInt(body["best_of"]); ok && bestOf > 1 {
+		return nil, fmt.Errorf("best_of requires a native completions API: %w", core.ErrOperationNotSupported)
+	}
+
+	chatBody := map[string]interface{}{
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": prompt}},
+	}
+	for _, field := range completionPassthroughFields {
+		if value, ok := body[field]; ok {
+			chatBody[field] = value
+		}
+	}
+	if logprobs, ok := toInt(body["logprobs"]); ok && logprobs > 0 {
+		chatBody["logprobs"] = true
+		chatBody["top_logprobs"] = logprobs
+	}
+	return chatBody, nil
+}
+
+// completionPrompt returns the single text prompt of a legacy completion request.
+func completionPrompt(prompt interface{}) (string, error) {
+	switch v := prompt.(type) {
+	case string:
+		return v, nil
+	case []interface{}:
+		if len(v) == 1 {
+			if text, ok := v[0].(string); ok {
+				return text, nil
+			}
+		}
+		return "", fmt.Errorf("only a single text prompt can be served through chat: %w", core.ErrOperationNotSupported)
+	case nil:
+		return "", &core.InvalidRequestError{Param: "prompt", Message: "prompt is required"}
+	}
+	return "", &core.InvalidRequestError{Param: "prompt", Message: "prompt must be a string or an array of strings"}
+}
+
+// ConvertChatToCompletionResponse rewrites a chat completion response as a text_completion.
+// Error responses are already in OpenAI format and are left alone.
+func ConvertChatToCompletionResponse(resp *http.Response, stream bool) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return
+	}
+	if stream {
+		pipeStream(resp, translateChatStreamToCompletion)
+		return
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var chat struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Index   int `json:"index"`
+			Message struct {
+				Content interface{} `json:"content"`
+			} `json:"message"`
+			FinishReason interface{} `json:"finish_reason"`
+		} `json:"choices"`
+		Usage interface{} `json:"usage"`
+	}
+	if err != nil || json.Unmarshal(raw, &chat) != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+
+	choices := make([]interface{}, 0, len(chat.Choices))
+	for _, choice := range chat.Choices {
+		choices = append(choices, map[string]interface{}{
+			"text":          MessageText(choice.Message.Content),
+			"index":         choice.Index,
+			"logprobs":      nil,
+			"finish_reason": choice.FinishReason,
+		})
+	}
+	completion := map[string]interface{}{
+		"id":      completionID(chat.ID),
+		"object":  "text_completion",
+		"created": chat.Created,
+		"model":   chat.Model,
+		"choices": choices,
+	}
+	if chat.Usage != nil {
+		completion["usage"] = chat.Usage
+	}
+	replaceJSONBody(resp, completion)
+}
+
+// translateChatStreamToCompletion converts chat.completion.chunk events into text_completion events.
+func translateChatStreamToCompletion(upstream io.Reader, w io.Writer) error {
+	return readSSE(upstream, func(ev sseEvent) error {
+		if ev.Data == "[DONE]" {
+			return writeSSEDone(w)
+		}
+		var chunk struct {
+			ID      string `json:"id"`
+			Created int64  `json:"created"`
+			Model   string `json:"model"`
+			Choices []struct {
+				Index int `json:"index"`
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason interface{} `json:"finish_reason"`
+			} `json:"choices"`
+			Usage interface{} `json:"usage"`
+			Error interface{} `json:"error"`
+		}
+		if json.Unmarshal([]byte(ev.Data), &chunk) != nil || chunk.Error != nil {
+			_, err := fmt.Fprintf(w, "data: %s\n\n", ev.Data)
+			return err
+		}
+
+		choices := make([]interface{}, 0, len(chunk.Choices))
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" && choice.FinishReason == nil {
+				// Role-only chunks have no completion counterpart
+				continue
+			}
+			choices = append(choices, map[string]interface{}{
+				"text":          choice.Delta.Content,
+				"index":         choice.Index,
+				"logprobs":      nil,
+				"finish_reason": choice.FinishReason,
+			})
+		}
+		if len(choices) == 0 && chunk.Usage == nil {
+			return nil
+		}
+		completion := map[string]interface{}{
+			"id":      completionID(chunk.ID),
+			"object":  "text_completion",
+			"created": chunk.Created,
+			"model":   chunk.Model,
+			"choices": choices,
+		}
+		if chunk.Usage != nil {
+			completion["usage"] = chunk.Usage
+		}
+		return writeSSEData(w, completion)
+	})
+}
+
+// completionID turns a chat completion ID into a legacy completion ID.
+func completionID(chatID string) string {
+	if chatID == "" {
+		return fmt.Sprintf("cmpl-%d", time.Now().UnixNano())
+	}
+	return "cmpl-" + strings.TrimPrefix(chatID, "chatcmpl-")
+}
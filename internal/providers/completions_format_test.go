@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"testing"
+)
+
+func TestTranslateChatStreamToCompletion(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		text        string
+		finish      string
+		usage       string
+		errorEvents int
+		done        bool
+	}{
+		{
+			name: "text and usage",
+			input: sseData(
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"content":"Hel"},"finish_reason":null}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":null}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{},"finish_reason":"length"}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`,
+				`[DONE]`),
+			text:   "Hello",
+			finish: "length",
+			usage:  "5/2/7",
+			done:   true,
+		},
+		{
+			name: "errors pass through",
+			input: sseData(
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":null}]}`,
+				`{"error":{"message":"upstream failed","type":"server_error"}}`),
+			text:        "Hi",
+			errorEvents: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var text, finish, usage string
+			var errorEvents int
+			var done bool
+			for _, ev := range runTranslator(t, translateChatStreamToCompletion, tt.input) {
+				if ev.Data == "[DONE]" {
+					done = true
+					continue
+				}
+				data := eventData(t, ev)
+				if _, ok := data["error"]; ok {
+					errorEvents++
+					continue
+				}
+				if data["object"] != "text_completion" {
+					t.Fatalf("unexpected object %v", data["object"])
+				}
+				if id, _ := data["id"].(string); id != "cmpl-1" {
+					t.Errorf("id %q, want cmpl-1", id)
+				}
+				if u, ok := data["usage"].(map[string]interface{}); ok {
+					usage = fmt.Sprintf("%v/%v/%v", u["prompt_tokens"], u["completion_tokens"], u["total_tokens"])
+				}
+				for _, c := range data["choices"].([]interface{}) {
+					choice := c.(map[string]interface{})
+					text += choice["text"].(string)
+					if reason, ok := choice["finish_reason"].(string); ok {
+						finish = reason
+					}
+				}
+			}
+			if text != tt.text || finish != tt.finish || usage != tt.usage || errorEvents != tt.errorEvents || done != tt.done {
+				t.Errorf("got text %q finish %q usage %q errors %d done %v", text, finish, usage, errorEvents, done)
+			}
+		})
+	}
+}
+
+func TestConvertCompletionToChat(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    map[string]interface{}
+		content string
+		invalid bool // A core.InvalidRequestError rather than core.ErrOperationNotSupported
+	}{
+		{"string prompt", map[string]interface{}{"prompt": "Say hi"}, "Say hi", false},
+		{"single prompt array", map[string]interface{}{"prompt": []interface{}{"Say hi"}}, "Say hi", false},
+		{"several prompts", map[string]interface{}{"prompt": []interface{}{"a", "b"}}, "", false},
+		{"token prompt", map[string]interface{}{"prompt": []interface{}{[]interface{}{1.0, 2.0}}}, "", false},
+		{"echo", map[string]interface{}{"prompt": "Say hi", "echo": true}, "", false},
+		{"missing prompt", map[string]interface{}{}, "", true},
+		{"object prompt", map[string]interface{}{"prompt": map[string]interface{}{}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatBody, err := ConvertCompletionToChat(tt.body)
+			if tt.content != "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				message := chatBody["messages"].([]interface{})[0].(map[string]interface{})
+				if message["role"] != "user" || message["content"] != tt.content {
+					t.Errorf("message %v, want a user message %q", message, tt.content)
+				}
+				return
+			}
+			var invalid *core.InvalidRequestError
+			switch {
+			case tt.invalid && !errors.As(err, &invalid):
+				t.Errorf("error %v, want an invalid request", err)
+			case tt.invalid && invalid.Param != "prompt":
+				t.Errorf("invalid request param %q, want prompt", invalid.Param)
+			case !tt.invalid && !errors.Is(err, core.ErrOperationNotSupported):
+				t.Errorf("error %v, want ErrOperationNotSupported", err)
+			}
+		})
+	}
+}
@@ -1,48 +1,89 @@
 package providers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"llm-fusion-engine/internal/core"
+	"net/http"
 	"time"
 )
 
 // CreateClient creates a provider client based on the provider type
 func CreateClient(providerType string, configJSON string) (ProviderClient, error) {
+	return DefaultRegistry().CreateClient(providerType, configJSON)
+}
+
+// CreateClient builds a ProviderClient for a provider instance from its JSON config,
+// backed by the registered implementation of providerType.
+func (r *Registry) CreateClient(providerType string, configJSON string) (ProviderClient, error) {
 	// Parse the config JSON
 	var configMap map[string]interface{}
 	if err := json.Unmarshal([]byte(configJSON), &configMap); err != nil {
 		return nil, fmt.Errorf("failed to parse provider config: %w", err)
 	}
-	
+
 	// Extract common config fields
 	config := ProviderConfig{
 		APIKey:     getStringFromConfig(configMap, "apiKey"),
 		BaseURL:    getStringFromConfig(configMap, "baseUrl"),
 		MaxRetries: getIntFromConfig(configMap, "maxRetries", 3),
 	}
-	
+
 	// Parse timeout
 	if timeoutSec := getIntFromConfig(configMap, "timeout", 30); timeoutSec > 0 {
 		config.Timeout = time.Duration(timeoutSec) * time.Second
 	} else {
 		config.Timeout = 30 * time.Second
 	}
-	
-	// Create client based on provider type
-	switch providerType {
-	case "openai":
-		return NewOpenAIClient(config), nil
-	case "anthropic":
-		// Anthropic doesn't have a public models API endpoint
-		// We'll need to return a static list or handle differently
-		return nil, fmt.Errorf("anthropic provider does not support dynamic model listing")
-	case "gemini":
-		// Google Gemini also doesn't have a simple models endpoint
-		return nil, fmt.Errorf("gemini provider does not support dynamic model listing")
-	default:
-		// For unknown types, try OpenAI-compatible API
-		return NewOpenAIClient(config), nil
+
+	provider, err := r.GetProvider(providerType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registryClient{
+		provider: provider,
+		config:   config,
+		endpoint: &core.ProviderEndpoint{
+			BaseURL: config.BaseURL,
+			ApiKey:  config.APIKey,
+			Config:  configMap,
+			Client:  &http.Client{Timeout: config.Timeout},
+		},
+	}, nil
+}
+
+// DefaultModels returns the static model catalog for a provider type, or nil for unregistered types.
+func (r *Registry) DefaultModels(providerType string) []string {
+	if !r.IsRegistered(providerType) {
+		return nil
+	}
+	provider, err := r.GetProvider(providerType)
+	if err != nil {
+		return nil
 	}
+	return provider.DefaultModels()
+}
+
+// registryClient adapts a core.IProvider to the ProviderClient interface.
+type registryClient struct {
+	provider core.IProvider
+	config   ProviderConfig
+	endpoint *core.ProviderEndpoint
+}
+
+// GetModels retrieves the list of available models from the provider
+func (c *registryClient) GetModels(ctx context.Context) ([]string, error) {
+	return c.provider.ListModels(ctx, c.endpoint)
+}
+
+// ValidateConfig validates the provider configuration
+func (c *registryClient) ValidateConfig() error {
+	if c.config.APIKey == "" {
+		return fmt.Errorf("API key is required")
+	}
+	return nil
 }
 
 // Helper functions to extract values from config map
@@ -65,4 +106,4 @@ func getIntFromConfig(config map[string]interface{}, key string, defaultVal int)
 		}
 	}
 	return defaultVal
-}
\ No newline at end of file
+}
@@ -0,0 +1,694 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultGeminiBaseURL is used when a gemini provider has no baseUrl configured.
+const DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com"
+
+// GeminiProvider implements core.IProvider for the Google Gemini API.
+// The provider config may set "safetySettings" to apply to every request that doesn't carry its own.
+type GeminiProvider struct{}
+
+// Type returns the provider type name.
+func (p *GeminiProvider) Type() string {
+	return "gemini"
+}
+
+// ChatCompletion sends a non-streaming chat completion request via generateContent.
+func (p *GeminiProvider) ChatCompletion(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.chat(ctx, endpoint, requestBody, false)
+}
+
+// StreamChatCompletion sends a streaming chat completion request via streamGenerateContent.
+func (p *GeminiProvider) StreamChatCompletion(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.chat(ctx, endpoint, requestBody, true)
+}
+
+// Embeddings sends an embeddings request via batchEmbedContents; see gemini_embeddings.go.
+func (p *GeminiProvider) Embeddings(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.embed(ctx, endpoint, requestBody)
+}
+
+// Completions serves a legacy completion through the chat API.
+func (p *GeminiProvider) Completions(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return completionsViaChat(ctx, p, endpoint, requestBody)
+}
+
+// Responses serves a Responses API request through the chat API.
+func (p *GeminiProvider) Responses(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return responsesViaChat(ctx, p, endpoint, requestBody)
+}
+
+// ImageGeneration is not translated by the Gemini adapter.
+func (p *GeminiProvider) ImageGeneration(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return nil, core.ErrOperationNotSupported
+}
+
+// AudioTranscription is not translated by the Gemini adapter.
+func (p *GeminiProvider) AudioTranscription(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return nil, core.ErrOperationNotSupported
+}
+
+// Speech is not translated by the Gemini adapter.
+func (p *GeminiProvider) Speech(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return nil, core.ErrOperationNotSupported
+}
+
+// ListModels retrieves the models that support generateContent.
+func (p *GeminiProvider) ListModels(ctx context.Context, endpoint *core.ProviderEndpoint) ([]string, error) {
+	baseURL := strings.TrimSuffix(baseURLOrDefault(endpoint, DefaultGeminiBaseURL), "/")
+	if !strings.HasSuffix(baseURL, "/v1beta") && !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1beta"
+	}
+	req, err := newJSONRequest(ctx, "GET", baseURL+"/models?pageSize=1000", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-goog-api-key", endpoint.ApiKey)
+
+	var modelsResp struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	if err := getJSON(httpClient(endpoint), req, &modelsResp); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(modelsResp.Models))
+	for _, model := range modelsResp.Models {
+		for _, method := range model.SupportedGenerationMethods {
+			if method == "generateContent" {
+				models = append(models, strings.TrimPrefix(model.Name, "models/"))
+				break
+			}
+		}
+	}
+	return models, nil
+}
+
+// DefaultModels returns the fallback model catalog.
+func (p *GeminiProvider) DefaultModels() []string {
+	return []string{
+		"gemini-1.5-flash",
+		"gemini-1.5-flash-latest",
+		"gemini-1.5-pro-latest",
+		"gemini-pro",
+		"gemini-pro-vision",
+		"gemini-ultra",
+	}
+}
+
+// chat translates the OpenAI body, sends it to generateContent and translates the response back.
+func (p *GeminiProvider) chat(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}, stream bool) (*http.Response, error) {
+	model, _ := requestBody["model"].(string)
+	if model == "" {
+		return nil, fmt.Errorf("model not specified in request")
+	}
+	payload, err := ConvertOpenAIToGemini(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := payload["safetySettings"]; !ok && endpoint.Config["safetySettings"] != nil {
+		payload["safetySettings"] = endpoint.Config["safetySettings"]
+	}
+
+	url := GeminiEndpoint(baseURLOrDefault(endpoint, DefaultGeminiBaseURL), model, stream)
+	req, err := newJSONRequest(ctx, "POST", url, payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-goog-api-key", endpoint.ApiKey)
+
+	resp, err := httpClient(endpoint).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	ConvertGeminiResponse(resp, stream)
+	return resp, nil
+}
+
+// GeminiEndpoint builds the generateContent URL for model. Streams use
+// streamGenerateContent with alt=sse so the response is server-sent events.
+func GeminiEndpoint(baseURL, model string, stream bool) string {
+	baseURL = geminiAPIBase(baseURL)
+	model = strings.TrimPrefix(model, "models/")
+	if stream {
+		return fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", baseURL, model)
+	}
+	return fmt.Sprintf("%s/models/%s:generateContent", baseURL, model)
+}
+
+// geminiAPIBase appends the default API version to a base URL that has none.
+func geminiAPIBase(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if !strings.HasSuffix(baseURL, "/v1beta") && !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1beta"
+	}
+	return baseURL
+}
+
+// ConvertOpenAIToGemini maps an OpenAI chat completion request onto the Gemini generateContent format.
+func ConvertOpenAIToGemini(body map[string]interface{}) (map[string]interface{}, error) {
+	rawMessages, ok := body["messages"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("messages must be an array")
+	}
+
+	var systemParts []interface{}
+	var contents []map[string]interface{}
+	// toolNames remembers function names by tool call ID, since Gemini
+	// function responses are matched by name rather than ID.
+	toolNames := map[string]string{}
+
+	appendParts := func(role string, parts []interface{}) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(contents); n > 0 && contents[n-1]["role"] == role {
+			contents[n-1]["parts"] = append(contents[n-1]["parts"].([]interface{}), parts...)
+			return
+		}
+		contents = append(contents, map[string]interface{}{"role": role, "parts": parts})
+	}
+
+	for _, raw := range rawMessages {
+		msg, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := msg["role"].(string)
+
+		switch role {
+		case "system", "developer":
+			if text := MessageText(msg["content"]); text != "" {
+				systemParts = append(systemParts, map[string]interface{}{"text": text})
+			}
+		case "tool":
+			toolCallID, _ := msg["tool_call_id"].(string)
+			name := toolNames[toolCallID]
+			if name == "" {
+				name, _ = msg["name"].(string)
+			}
+			text := MessageText(msg["content"])
+			var response map[string]interface{}
+			if json.Unmarshal([]byte(text), &response) != nil {
+				response = map[string]interface{}{"content": text}
+			}
+			appendParts("user", []interface{}{map[string]interface{}{
+				"functionResponse": map[string]interface{}{"name": name, "response": response},
+			}})
+		case "assistant":
+			parts := geminiParts(msg["content"])
+			if toolCalls, ok := msg["tool_calls"].([]interface{}); ok {
+				for _, tc := range toolCalls {
+					call, ok := tc.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					fn, _ := call["function"].(map[string]interface{})
+					name, _ := fn["name"].(string)
+					if id, ok := call["id"].(string); ok {
+						toolNames[id] = name
+					}
+					args := map[string]interface{}{}
+					if argStr, ok := fn["arguments"].(string); ok && argStr != "" {
+						if err := json.Unmarshal([]byte(argStr), &args); err != nil {
+							return nil, fmt.Errorf("invalid arguments for tool call %s: %w", name, err)
+						}
+					}
+					parts = append(parts, map[string]interface{}{
+						"functionCall": map[string]interface{}{"name": name, "args": args},
+					})
+				}
+			}
+			appendParts("model", parts)
+		default:
+			appendParts("user", geminiParts(msg["content"]))
+		}
+	}
+
+	payload := map[string]interface{}{"contents": contents}
+	if len(systemParts) > 0 {
+		payload["systemInstruction"] = map[string]interface{}{"parts": systemParts}
+	}
+
+	generationConfig := map[string]interface{}{}
+	if v, ok := toInt(body["max_tokens"]); ok && v > 0 {
+		generationConfig["maxOutputTokens"] = v
+	} else if v, ok := toInt(body["max_completion_tokens"]); ok && v > 0 {
+		generationConfig["maxOutputTokens"] = v
+	}
+	if stops := stopSequences(body["stop"]); len(stops) > 0 {
+		generationConfig["stopSequences"] = stops
+	}
+	for openAIKey, geminiKey := range map[string]string{
+		"temperature":       "temperature",
+		"top_p":             "topP",
+		"top_k":             "topK",
+		"n":                 "candidateCount",
+		"presence_penalty":  "presencePenalty",
+		"frequency_penalty": "frequencyPenalty",
+		"seed":              "seed",
+	} {
+		if v, ok := body[openAIKey]; ok && v != nil {
+			generationConfig[geminiKey] = v
+		}
+	}
+	if format, ok := body["response_format"].(map[string]interface{}); ok {
+		switch format["type"] {
+		case "json_object":
+			generationConfig["responseMimeType"] = "application/json"
+		case "json_schema":
+			generationConfig["responseMimeType"] = "application/json"
+			if js, ok := format["json_schema"].(map[string]interface{}); ok {
+				if schema, ok := js["schema"].(map[string]interface{}); ok {
+					generationConfig["responseJsonSchema"] = schema
+				}
+			}
+		}
+	}
+	if len(generationConfig) > 0 {
+		payload["generationConfig"] = generationConfig
+	}
+
+	if tools, ok := body["tools"].([]interface{}); ok && len(tools) > 0 {
+		var declarations []interface{}
+		for _, t := range tools {
+			tool, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fn, ok := tool["function"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			decl := map[string]interface{}{"name": fn["name"]}
+			if desc, ok := fn["description"].(string); ok && desc != "" {
+				decl["description"] = desc
+			}
+			if params, ok := fn["parameters"].(map[string]interface{}); ok {
+				decl["parameters"] = cleanGeminiSchema(params)
+			}
+			declarations = append(declarations, decl)
+		}
+		payload["tools"] = []interface{}{map[string]interface{}{"functionDeclarations": declarations}}
+
+		if config := geminiToolConfig(body["tool_choice"]); config != nil {
+			payload["toolConfig"] = map[string]interface{}{"functionCallingConfig": config}
+		}
+	}
+
+	if safety, ok := body["safety_settings"]; ok {
+		payload["safetySettings"] = safety
+	}
+
+	return payload, nil
+}
+
+// geminiParts converts OpenAI message content into Gemini parts.
+func geminiParts(content interface{}) []interface{} {
+	switch v := content.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []interface{}{map[string]interface{}{"text": v}}
+	case []interface{}:
+		var parts []interface{}
+		for _, item := range v {
+			part, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch part["type"] {
+			case "text":
+				if text, _ := part["text"].(string); text != "" {
+					parts = append(parts, map[string]interface{}{"text": text})
+				}
+			case "image_url":
+				var url string
+				switch img := part["image_url"].(type) {
+				case map[string]interface{}:
+					url, _ = img["url"].(string)
+				case string:
+					url = img
+				}
+				if mediaType, data, ok := parseDataURL(url); ok {
+					parts = append(parts, map[string]interface{}{
+						"inlineData": map[string]interface{}{"mimeType": mediaType, "data": data},
+					})
+				} else if url != "" {
+					parts = append(parts, map[string]interface{}{
+						"fileData": map[string]interface{}{"mimeType": imageMimeType(url), "fileUri": url},
+					})
+				}
+			}
+		}
+		return parts
+	default:
+		return nil
+	}
+}
+
+// imageMimeType guesses an image MIME type from a URL's extension.
+func imageMimeType(url string) string {
+	ext := strings.ToLower(path.Ext(strings.SplitN(url, "?", 2)[0]))
+	switch ext {
+	case ".png":
+		return "image/png"
+	case ".webp":
+		return "image/webp"
+	case ".gif":
+		return "image/gif"
+	case ".heic":
+		return "image/heic"
+	default:
+		return "image/jpeg"
+	}
+}
+
+// cleanGeminiSchema removes JSON Schema keywords that Gemini function declarations reject.
+func cleanGeminiSchema(schema interface{}) interface{} {
+	switch v := schema.(type) {
+	case map[string]interface{}:
+		cleaned := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			switch key {
+			case "$schema", "additionalProperties", "strict", "$id", "$defs", "definitions":
+				continue
+			}
+			cleaned[key] = cleanGeminiSchema(value)
+		}
+		return cleaned
+	case []interface{}:
+		cleaned := make([]interface{}, len(v))
+		for i, value := range v {
+			cleaned[i] = cleanGeminiSchema(value)
+		}
+		return cleaned
+	default:
+		return v
+	}
+}
+
+// geminiToolConfig maps OpenAI's tool_choice onto Gemini's functionCallingConfig.
+func geminiToolConfig(choice interface{}) map[string]interface{} {
+	switch v := choice.(type) {
+	case string:
+		switch v {
+		case "auto":
+			return map[string]interface{}{"mode": "AUTO"}
+		case "none":
+			return map[string]interface{}{"mode": "NONE"}
+		case "required":
+			return map[string]interface{}{"mode": "ANY"}
+		}
+	case map[string]interface{}:
+		if fn, ok := v["function"].(map[string]interface{}); ok {
+			return map[string]interface{}{"mode": "ANY", "allowedFunctionNames": []interface{}{fn["name"]}}
+		}
+	}
+	return nil
+}
+
+// geminiFinishReason maps Gemini finish reasons onto OpenAI finish reasons.
+func geminiFinishReason(reason string, hasToolCalls bool) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	default:
+		if hasToolCalls {
+			return "tool_calls"
+		}
+		return "stop"
+	}
+}
+
+// geminiResponse is the subset of a generateContent response used for translation.
+type geminiResponse struct {
+	ResponseID   string `json:"responseId"`
+	ModelVersion string `json:"modelVersion"`
+	Candidates   []struct {
+		Index   int `json:"index"`
+		Content struct {
+			Parts []struct {
+				Text         string `json:"text"`
+				Thought      bool   `json:"thought"`
+				FunctionCall *struct {
+					Name string          `json:"name"`
+					Args json.RawMessage `json:"args"`
+				} `json:"functionCall"`
+			} `json:"parts"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *geminiUsage `json:"usageMetadata"`
+}
+
+// geminiUsage reports token counts; thinking tokens are billed as completion tokens.
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+func (u *geminiUsage) openAI() map[string]interface{} {
+	usage := openAIUsage(u.PromptTokenCount, u.CandidatesTokenCount+u.ThoughtsTokenCount)
+	if u.TotalTokenCount > 0 {
+		usage["total_tokens"] = u.TotalTokenCount
+	}
+	return usage
+}
+
+// ConvertGeminiResponse rewrites a Gemini response in place into OpenAI format.
+// Streaming responses are translated incrementally; errors are mapped onto OpenAI's error envelope.
+func ConvertGeminiResponse(resp *http.Response, stream bool) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		convertGeminiError(resp)
+		return
+	}
+	if stream {
+		pipeStream(resp, translateGeminiStream)
+		return
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+	var gr geminiResponse
+	if err := json.Unmarshal(raw, &gr); err != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+
+	choices := []interface{}{}
+	for i, cand := range gr.Candidates {
+		var text strings.Builder
+		var toolCalls []interface{}
+		for _, part := range cand.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				args := string(part.FunctionCall.Args)
+				if args == "" {
+					args = "{}"
+				}
+				toolCalls = append(toolCalls, map[string]interface{}{
+					"id":       fmt.Sprintf("call_%d_%d", i, len(toolCalls)),
+					"type":     "function",
+					"function": map[string]interface{}{"name": part.FunctionCall.Name, "arguments": args},
+				})
+			case !part.Thought:
+				text.WriteString(part.Text)
+			}
+		}
+		message := map[string]interface{}{"role": "assistant", "content": text.String()}
+		if len(toolCalls) > 0 {
+			message["tool_calls"] = toolCalls
+		}
+		choices = append(choices, map[string]interface{}{
+			"index":         i,
+			"message":       message,
+			"finish_reason": geminiFinishReason(cand.FinishReason, len(toolCalls) > 0),
+		})
+	}
+	if len(choices) == 0 && gr.PromptFeedback != nil && gr.PromptFeedback.BlockReason != "" {
+		// The prompt itself was blocked; surface it the way OpenAI reports filtered output.
+		choices = append(choices, map[string]interface{}{
+			"index":         0,
+			"message":       map[string]interface{}{"role": "assistant", "content": ""},
+			"finish_reason": "content_filter",
+		})
+	}
+
+	result := map[string]interface{}{
+		"id":      chatCompletionID(gr.ResponseID),
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   gr.ModelVersion,
+		"choices": choices,
+	}
+	if gr.UsageMetadata != nil {
+		result["usage"] = gr.UsageMetadata.openAI()
+	}
+	replaceJSONBody(resp, result)
+}
+
+// convertGeminiError maps {"error":{"code":..,"message":..,"status":..}} onto OpenAI's error envelope.
+func convertGeminiError(resp *http.Response) {
+	raw, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// Errors may arrive as an object or, from some endpoints, as a single-element array.
+	type geminiError struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	var upstream geminiError
+	if json.Unmarshal(raw, &upstream) != nil {
+		var list []geminiError
+		if json.Unmarshal(raw, &list) == nil && len(list) > 0 {
+			upstream = list[0]
+		}
+	}
+	if upstream.Error.Message == "" {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+	replaceJSONBody(resp, openAIErrorBody(upstream.Error.Message, upstream.Error.Status, upstream.Error.Code))
+}
+
+// translateGeminiStream converts streamGenerateContent SSE chunks into chat.completion.chunk events.
+// Each candidate (n > 1 maps to candidateCount) streams as the choice of its index. Finish reasons
+// and usage are held back until the upstream stream ends so the final chunks carry both.
+func translateGeminiStream(upstream io.Reader, w io.Writer) error {
+	var (
+		id            = chatCompletionID("")
+		model         string
+		created       = time.Now().Unix()
+		started       bool
+		choices       []int // Candidate indexes in the order they appeared
+		finishReasons = map[int]string{}
+		toolCalls     = map[int]int{} // Tool calls per candidate
+		callIDs       int
+		usage         *geminiUsage
+	)
+	startChoice := func(index int) error {
+		for _, seen := range choices {
+			if seen == index {
+				return nil
+			}
+		}
+		choices = append(choices, index)
+		return writeSSEData(w, geminiStreamChunk(id, model, created, index,
+			map[string]interface{}{"role": "assistant", "content": ""}, nil))
+	}
+
+	err := readSSE(upstream, func(ev sseEvent) error {
+		var gr geminiResponse
+		if err := json.Unmarshal([]byte(ev.Data), &gr); err != nil {
+			return nil
+		}
+		if !started {
+			started = true
+			id = chatCompletionID(gr.ResponseID)
+			model = gr.ModelVersion
+			if err := startChoice(0); err != nil {
+				return err
+			}
+		}
+		if gr.UsageMetadata != nil {
+			usage = gr.UsageMetadata
+		}
+		if len(gr.Candidates) == 0 && gr.PromptFeedback != nil && gr.PromptFeedback.BlockReason != "" {
+			finishReasons[0] = "content_filter"
+		}
+
+		for _, cand := range gr.Candidates {
+			if err := startChoice(cand.Index); err != nil {
+				return err
+			}
+			for _, part := range cand.Content.Parts {
+				var delta map[string]interface{}
+				switch {
+				case part.FunctionCall != nil:
+					args := string(part.FunctionCall.Args)
+					if args == "" {
+						args = "{}"
+					}
+					delta = map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+						"index":    toolCalls[cand.Index],
+						"id":       fmt.Sprintf("call_%d", callIDs),
+						"type":     "function",
+						"function": map[string]interface{}{"name": part.FunctionCall.Name, "arguments": args},
+					}}}
+					toolCalls[cand.Index]++
+					callIDs++
+				case part.Text != "" && !part.Thought:
+					delta = map[string]interface{}{"content": part.Text}
+				default:
+					continue
+				}
+				if err := writeSSEData(w, geminiStreamChunk(id, model, created, cand.Index, delta, nil)); err != nil {
+					return err
+				}
+			}
+			if cand.FinishReason != "" {
+				finishReasons[cand.Index] = cand.FinishReason
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !started {
+		return nil
+	}
+
+	for i, index := range choices {
+		reason := finishReasons[index]
+		if reason != "content_filter" {
+			reason = geminiFinishReason(reason, toolCalls[index] > 0)
+		}
+		chunk := geminiStreamChunk(id, model, created, index, map[string]interface{}{}, reason)
+		if usage != nil && i == len(choices)-1 {
+			chunk["usage"] = usage.openAI()
+		}
+		if err := writeSSEData(w, chunk); err != nil {
+			return err
+		}
+	}
+	return writeSSEDone(w)
+}
+
+// geminiStreamChunk builds a chat.completion.chunk for the choice of a candidate.
+func geminiStreamChunk(id, model string, created int64, index int, delta map[string]interface{}, finishReason interface{}) map[string]interface{} {
+	chunk := newChatCompletionChunk(id, model, created, delta, finishReason)
+	chunk["choices"].([]interface{})[0].(map[string]interface{})["index"] = index
+	return chunk
+}
@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// geminiEmbedBatchSize is the most requests batchEmbedContents accepts in one call.
+const geminiEmbedBatchSize = 100
+
+// GeminiEmbedEndpoint builds the batchEmbedContents URL for model.
+func GeminiEmbedEndpoint(baseURL, model string) string {
+	return fmt.Sprintf("%s/models/%s:batchEmbedContents", geminiAPIBase(baseURL), strings.TrimPrefix(model, "models/"))
+}
+
+// embed translates an OpenAI embeddings request into batchEmbedContents calls of up to
+// geminiEmbedBatchSize texts and answers with an OpenAI embeddings list. Gemini reports no
+// token usage, so the response carries none. Token inputs cannot be embedded by Gemini.
+func (p *GeminiProvider) embed(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	model, _ := requestBody["model"].(string)
+	if model == "" {
+		return nil, fmt.Errorf("model not specified in request")
+	}
+	texts, err := embeddingTexts(requestBody["input"])
+	if err != nil {
+		return nil, err
+	}
+	model = strings.TrimPrefix(model, "models/")
+	dimensions, hasDimensions := toInt(requestBody["dimensions"])
+
+	url := GeminiEmbedEndpoint(baseURLOrDefault(endpoint, DefaultGeminiBaseURL), model)
+	var vectors [][]float64
+	var resp *http.Response
+	for start := 0; start < len(texts); start += geminiEmbedBatchSize {
+		end := start + geminiEmbedBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		requests := make([]interface{}, 0, end-start)
+		for _, text := range texts[start:end] {
+			request := map[string]interface{}{
+				"model":   "models/" + model,
+				"content": map[string]interface{}{"parts": []interface{}{map[string]interface{}{"text": text}}},
+			}
+			if hasDimensions {
+				request["outputDimensionality"] = dimensions
+			}
+			requests = append(requests, request)
+		}
+
+		req, err := newJSONRequest(ctx, "POST", url, map[string]interface{}{"requests": requests})
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-goog-api-key", endpoint.ApiKey)
+		resp, err = httpClient(endpoint).Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			convertGeminiError(resp)
+			return resp, nil
+		}
+
+		var batch struct {
+			Embeddings []struct {
+				Values []float64 `json:"values"`
+			} `json:"embeddings"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&batch)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gemini embeddings: %w", err)
+		}
+		for _, embedding := range batch.Embeddings {
+			vectors = append(vectors, embedding.Values)
+		}
+	}
+
+	encodeBase64 := requestBody["encoding_format"] == "base64"
+	data := make([]interface{}, 0, len(vectors))
+	for i, vector := range vectors {
+		var embedding interface{} = vector
+		if encodeBase64 {
+			embedding = base64Embedding(vector)
+		}
+		data = append(data, map[string]interface{}{"object": "embedding", "index": i, "embedding": embedding})
+	}
+	replaceJSONBody(resp, map[string]interface{}{
+		"object": "list",
+		"data":   data,
+		"model":  model,
+	})
+	return resp, nil
+}
+
+// embeddingTexts normalizes an OpenAI embeddings input into a list of texts.
+func embeddingTexts(input interface{}) ([]string, error) {
+	switch v := input.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		texts := make([]string, 0, len(v))
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("gemini embeddings accept text input only: %w", core.ErrOperationNotSupported)
+			}
+			texts = append(texts, text)
+		}
+		return texts, nil
+	}
+	return nil, fmt.Errorf("input must be a string or an array of strings")
+}
+
+// base64Embedding encodes a vector the way OpenAI does for encoding_format=base64:
+// little-endian float32 values.
+func base64Embedding(vector []float64) string {
+	raw := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(raw[4*i:], math.Float32bits(float32(value)))
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
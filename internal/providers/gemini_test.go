@@ -0,0 +1,115 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranslateGeminiStream(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  chatStream
+	}{
+		{
+			name: "text",
+			input: sseData(
+				`{"responseId":"r1","modelVersion":"gemini-2.5-flash","candidates":[{"content":{"parts":[{"text":"Hel"}]}}]}`,
+				`{"candidates":[{"content":{"parts":[{"text":"lo"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":2,"totalTokenCount":6}}`),
+			want: chatStream{role: "assistant", content: "Hello", finishReason: "stop", usage: "4/2/6", done: true},
+		},
+		{
+			name: "thoughts are dropped but billed",
+			input: sseData(
+				`{"candidates":[{"content":{"parts":[{"text":"Let me think","thought":true},{"text":"Hi"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":1,"thoughtsTokenCount":4,"totalTokenCount":8}}`),
+			want: chatStream{role: "assistant", content: "Hi", finishReason: "stop", usage: "3/5/8", done: true},
+		},
+		{
+			name: "function calls",
+			input: sseData(
+				`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}},{"functionCall":{"name":"get_time"}}]},"finishReason":"STOP"}]}`),
+			want: chatStream{
+				role:         "assistant",
+				toolCalls:    []string{`call_0 get_weather {"city":"Paris"}`, `call_1 get_time {}`},
+				finishReason: "tool_calls",
+				done:         true,
+			},
+		},
+		{
+			name: "max tokens",
+			input: sseData(
+				`{"candidates":[{"content":{"parts":[{"text":"cut"}]},"finishReason":"MAX_TOKENS"}]}`),
+			want: chatStream{role: "assistant", content: "cut", finishReason: "length", done: true},
+		},
+		{
+			name:  "blocked prompt",
+			input: sseData(`{"promptFeedback":{"blockReason":"SAFETY"}}`),
+			want:  chatStream{role: "assistant", finishReason: "content_filter", done: true},
+		},
+		{
+			name:  "empty stream",
+			input: "",
+			want:  chatStream{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := runTranslator(t, translateGeminiStream, tt.input)
+			if got := summarizeChatStream(t, events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stream %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateGeminiStreamCandidates(t *testing.T) {
+	// With candidateCount > 1 the candidates' chunks arrive interleaved
+	input := sseData(
+		`{"candidates":[{"index":0,"content":{"parts":[{"text":"A1 "}]}},{"index":1,"content":{"parts":[{"text":"B1 "}]}}]}`,
+		`{"candidates":[{"index":1,"content":{"parts":[{"functionCall":{"name":"lookup","args":{}}}]},"finishReason":"STOP"}]}`,
+		`{"candidates":[{"index":0,"content":{"parts":[{"text":"A2"}]},"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":6,"totalTokenCount":10}}`)
+
+	content := map[float64]string{}
+	finish := map[float64]string{}
+	roles := map[float64]int{}
+	var usageChunks int
+	for _, ev := range runTranslator(t, translateGeminiStream, input) {
+		if ev.Data == "[DONE]" {
+			continue
+		}
+		data := eventData(t, ev)
+		if data["usage"] != nil {
+			usageChunks++
+		}
+		choice := data["choices"].([]interface{})[0].(map[string]interface{})
+		index := choice["index"].(float64)
+		delta := choice["delta"].(map[string]interface{})
+		if delta["role"] == "assistant" {
+			roles[index]++
+		}
+		if text, ok := delta["content"].(string); ok {
+			content[index] += text
+		}
+		if reason, ok := choice["finish_reason"].(string); ok {
+			finish[index] = reason
+		}
+	}
+
+	want := []struct {
+		index   float64
+		content string
+		finish  string
+	}{
+		{0, "A1 A2", "length"},
+		{1, "B1 ", "tool_calls"},
+	}
+	for _, w := range want {
+		if content[w.index] != w.content || finish[w.index] != w.finish || roles[w.index] != 1 {
+			t.Errorf("choice %v: content %q finish %q roles %d, want %q %q 1",
+				w.index, content[w.index], finish[w.index], roles[w.index], w.content, w.finish)
+		}
+	}
+	if usageChunks != 1 {
+		t.Errorf("%d chunks carry usage, want 1", usageChunks)
+	}
+}
@@ -2,35 +2,18 @@ package providers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
+	"llm-fusion-engine/internal/core"
 	"net/http"
-	"time"
+	"strings"
 )
 
-// OpenAIClient implements ProviderClient for OpenAI-compatible APIs
-type OpenAIClient struct {
-	config ProviderConfig
-	client *http.Client
-}
+// DefaultOpenAIBaseURL is used when an openai provider has no baseUrl configured.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
 
-// NewOpenAIClient creates a new OpenAI client
-func NewOpenAIClient(config ProviderConfig) *OpenAIClient {
-	if config.Timeout == 0 {
-		config.Timeout = 30 * time.Second
-	}
-	if config.BaseURL == "" {
-		config.BaseURL = "https://api.openai.com/v1"
-	}
-	
-	return &OpenAIClient{
-		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
-	}
-}
+// OpenAIProvider implements core.IProvider for OpenAI and OpenAI-compatible APIs.
+// Requests and responses are already in OpenAI format and pass through unchanged.
+type OpenAIProvider struct{}
 
 // OpenAIModelsResponse represents the response from OpenAI /v1/models endpoint
 type OpenAIModelsResponse struct {
@@ -43,46 +26,115 @@ type OpenAIModelsResponse struct {
 	Object string `json:"object"`
 }
 
-// GetModels retrieves the list of available models from OpenAI-compatible API
-func (c *OpenAIClient) GetModels(ctx context.Context) ([]string, error) {
-	url := fmt.Sprintf("%s/models", c.config.BaseURL)
-	
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// Type returns the provider type name.
+func (p *OpenAIProvider) Type() string {
+	return "openai"
+}
+
+// ChatCompletion sends a non-streaming chat completion request.
+func (p *OpenAIProvider) ChatCompletion(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.post(ctx, endpoint, "/v1/chat/completions", "/chat/completions", requestBody)
+}
+
+// StreamChatCompletion sends a streaming chat completion request.
+func (p *OpenAIProvider) StreamChatCompletion(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.post(ctx, endpoint, "/v1/chat/completions", "/chat/completions", requestBody)
+}
+
+// Embeddings sends an embeddings request.
+func (p *OpenAIProvider) Embeddings(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.post(ctx, endpoint, "/v1/embeddings", "/embeddings", requestBody)
+}
+
+// Completions sends a legacy completion request. OpenAI-compatible backends that dropped the
+// endpoint can set "completionsApi": false to have prompts served through chat instead.
+func (p *OpenAIProvider) Completions(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	if enabled, ok := endpoint.Config["completionsApi"].(bool); ok && !enabled {
+		return completionsViaChat(ctx, p, endpoint, requestBody)
+	}
+	return p.post(ctx, endpoint, "/v1/completions", "/completions", requestBody)
+}
+
+// Responses sends a Responses API request. OpenAI itself serves it natively; other compatible
+// backends are translated onto chat completions unless their config sets "responsesApi": true.
+func (p *OpenAIProvider) Responses(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	native := endpoint.BaseURL == "" || strings.Contains(endpoint.BaseURL, "api.openai.com")
+	if enabled, ok := endpoint.Config["responsesApi"].(bool); ok {
+		native = enabled
+	}
+	if !native {
+		return responsesViaChat(ctx, p, endpoint, requestBody)
+	}
+	return p.post(ctx, endpoint, "/v1/responses", "/responses", requestBody)
+}
+
+// ImageGeneration sends an image generation request.
+func (p *OpenAIProvider) ImageGeneration(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.post(ctx, endpoint, "/v1/images/generations", "/images/generations", requestBody)
+}
+
+// AudioTranscription uploads the audio and its form fields as multipart/form-data.
+func (p *OpenAIProvider) AudioTranscription(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	url, err := resolveURL(baseURLOrDefault(endpoint, DefaultOpenAIBaseURL), "/v1/audio/transcriptions", "/audio/transcriptions")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+	req, err := newMultipartRequest(ctx, url, requestBody)
+	if err != nil {
+		return nil, err
 	}
-	
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
-	req.Header.Set("Content-Type", "application/json")
-	
-	resp, err := c.client.Do(req)
+	req.Header.Set("Authorization", "Bearer "+endpoint.ApiKey)
+	return httpClient(endpoint).Do(req)
+}
+
+// Speech sends a text-to-speech request; the audio streams back as the response body.
+func (p *OpenAIProvider) Speech(ctx context.Context, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	return p.post(ctx, endpoint, "/v1/audio/speech", "/audio/speech", requestBody)
+}
+
+// ListModels retrieves the list of available models from the /v1/models endpoint.
+func (p *OpenAIProvider) ListModels(ctx context.Context, endpoint *core.ProviderEndpoint) ([]string, error) {
+	url, err := resolveURL(baseURLOrDefault(endpoint, DefaultOpenAIBaseURL), "/v1/models", "/models")
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch models: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	req, err := newJSONRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	
+	req.Header.Set("Authorization", "Bearer "+endpoint.ApiKey)
+
 	var modelsResp OpenAIModelsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&modelsResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := getJSON(httpClient(endpoint), req, &modelsResp); err != nil {
+		return nil, err
 	}
-	
+
 	models := make([]string, 0, len(modelsResp.Data))
 	for _, model := range modelsResp.Data {
 		models = append(models, model.ID)
 	}
-	
 	return models, nil
 }
 
-// ValidateConfig validates the provider configuration
-func (c *OpenAIClient) ValidateConfig() error {
-	if c.config.APIKey == "" {
-		return fmt.Errorf("API key is required")
+// DefaultModels returns the fallback model catalog.
+func (p *OpenAIProvider) DefaultModels() []string {
+	return []string{
+		"gpt-3.5-turbo", "gpt-3.5-turbo-16k",
+		"gpt-4", "gpt-4-turbo", "gpt-4o", "gpt-4o-mini",
+		"o1-preview", "o1-mini",
+	}
+}
+
+// post sends an OpenAI-format JSON body with bearer authentication.
+func (p *OpenAIProvider) post(ctx context.Context, endpoint *core.ProviderEndpoint, fullPath, pathAfterV1 string, requestBody map[string]interface{}) (*http.Response, error) {
+	url, err := resolveURL(baseURLOrDefault(endpoint, DefaultOpenAIBaseURL), fullPath, pathAfterV1)
+	if err != nil {
+		return nil, err
 	}
-	return nil
-}
\ No newline at end of file
+	req, err := newJSONRequest(ctx, "POST", url, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+endpoint.ApiKey)
+	return httpClient(endpoint).Do(req)
+}
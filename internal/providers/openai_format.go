@@ -0,0 +1,221 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Helpers shared by the adapters that translate native provider APIs
+// into OpenAI chat.completion / chat.completion.chunk payloads.
+
+// maxSSELineSize bounds a single SSE line; tool-call arguments and inline images can be large.
+const maxSSELineSize = 4 * 1024 * 1024
+
+// sseEvent is one parsed server-sent event.
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// readSSE parses an SSE stream and calls handle for every event that carries data.
+// It stops early if handle returns an error.
+func readSSE(r io.Reader, handle func(sseEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+
+	var event string
+	var data []string
+	flush := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		ev := sseEvent{Event: event, Data: strings.Join(data, "\n")}
+		event, data = "", nil
+		return handle(ev)
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// writeSSEData writes v as a single OpenAI-style "data:" event.
+func writeSSEData(w io.Writer, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", payload)
+	return err
+}
+
+// writeSSEDone terminates an OpenAI-style stream.
+func writeSSEDone(w io.Writer) error {
+	_, err := io.WriteString(w, "data: [DONE]\n\n")
+	return err
+}
+
+// newChatCompletionChunk builds a chat.completion.chunk with a single choice.
+func newChatCompletionChunk(id, model string, created int64, delta map[string]interface{}, finishReason interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      id,
+		"object":  "chat.completion.chunk",
+		"created": created,
+		"model":   model,
+		"choices": []interface{}{
+			map[string]interface{}{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finishReason,
+			},
+		},
+	}
+}
+
+// openAIUsage builds an OpenAI usage object.
+func openAIUsage(promptTokens, completionTokens int) map[string]interface{} {
+	return map[string]interface{}{
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"total_tokens":      promptTokens + completionTokens,
+	}
+}
+
+// openAIErrorBody wraps an upstream error message in OpenAI's error envelope.
+func openAIErrorBody(message, errType string, code interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errType,
+			"code":    code,
+		},
+	}
+}
+
+// replaceJSONBody swaps the response body for the JSON encoding of v and fixes the framing headers.
+func replaceJSONBody(resp *http.Response, v interface{}) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(payload))
+	resp.ContentLength = int64(len(payload))
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(payload)))
+}
+
+// pipeStream replaces the response body with the output of translate, which reads
+// the original upstream body and writes an OpenAI-style SSE stream.
+func pipeStream(resp *http.Response, translate func(upstream io.Reader, w io.Writer) error) {
+	upstream := resp.Body
+	pr, pw := io.Pipe()
+	go func() {
+		defer upstream.Close()
+		pw.CloseWithError(translate(upstream, pw))
+	}()
+
+	resp.Body = pr
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	resp.Header.Set("Content-Type", "text/event-stream")
+}
+
+// parseDataURL splits a data: URL into its media type and base64 payload.
+func parseDataURL(url string) (mediaType, data string, ok bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return "", "", false
+	}
+	meta, payload, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !found || !strings.HasSuffix(meta, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(meta, ";base64"), payload, true
+}
+
+// MessageText flattens OpenAI message content (a string or an array of parts) into plain text.
+func MessageText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var parts []string
+		for _, item := range v {
+			if part, ok := item.(map[string]interface{}); ok {
+				if text, ok := part["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	default:
+		return ""
+	}
+}
+
+// stopSequences normalizes OpenAI's "stop" (a string or array) into a list.
+func stopSequences(stop interface{}) []string {
+	switch v := stop.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []interface{}:
+		var out []string
+		for _, s := range v {
+			if str, ok := s.(string); ok && str != "" {
+				out = append(out, str)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// toInt converts a decoded JSON number to int.
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	case json.Number:
+		i, err := n.Int64()
+		return int(i), err == nil
+	default:
+		return 0, false
+	}
+}
+
+// chatCompletionID generates an OpenAI-style completion ID when the upstream ID is missing.
+func chatCompletionID(upstreamID string) string {
+	if upstreamID != "" {
+		return "chatcmpl-" + upstreamID
+	}
+	return fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+}
@@ -0,0 +1,154 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// sseData builds an SSE stream with one data event per payload.
+func sseData(payloads ...string) string {
+	var b strings.Builder
+	for _, payload := range payloads {
+		fmt.Fprintf(&b, "data: %s\n\n", payload)
+	}
+	return b.String()
+}
+
+// runTranslator feeds input through a stream translator and parses the events it wrote.
+func runTranslator(t *testing.T, translate func(upstream io.Reader, w io.Writer) error, input string) []sseEvent {
+	t.Helper()
+	var out bytes.Buffer
+	if err := translate(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("translate: %v", err)
+	}
+	var events []sseEvent
+	if err := readSSE(&out, func(ev sseEvent) error {
+		events = append(events, ev)
+		return nil
+	}); err != nil {
+		t.Fatalf("reading translated stream: %v", err)
+	}
+	return events
+}
+
+// eventData decodes the JSON payload of an event.
+func eventData(t *testing.T, ev sseEvent) map[string]interface{} {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(ev.Data), &data); err != nil {
+		t.Fatalf("event %q has invalid data %q: %v", ev.Event, ev.Data, err)
+	}
+	return data
+}
+
+// chatStream summarizes a translated chat.completion.chunk stream.
+type chatStream struct {
+	role         string
+	content      string
+	toolCalls    []string // "id name arguments", by tool call index
+	finishReason string
+	usage        string // "prompt/completion/total"
+	errMessage   string
+	done         bool
+}
+
+func summarizeChatStream(t *testing.T, events []sseEvent) chatStream {
+	t.Helper()
+	var summary chatStream
+	type toolCall struct{ id, name, arguments string }
+	calls := map[int]*toolCall{}
+	for _, ev := range events {
+		if ev.Data == "[DONE]" {
+			summary.done = true
+			continue
+		}
+		data := eventData(t, ev)
+		if errObj, ok := data["error"].(map[string]interface{}); ok {
+			summary.errMessage, _ = errObj["message"].(string)
+			continue
+		}
+		if data["object"] != "chat.completion.chunk" {
+			t.Fatalf("unexpected object %v", data["object"])
+		}
+		if usage, ok := data["usage"].(map[string]interface{}); ok {
+			summary.usage = fmt.Sprintf("%v/%v/%v", usage["prompt_tokens"], usage["completion_tokens"], usage["total_tokens"])
+		}
+		for _, c := range data["choices"].([]interface{}) {
+			choice := c.(map[string]interface{})
+			delta := choice["delta"].(map[string]interface{})
+			if role, ok := delta["role"].(string); ok {
+				summary.role = role
+			}
+			if content, ok := delta["content"].(string); ok {
+				summary.content += content
+			}
+			if reason, ok := choice["finish_reason"].(string); ok {
+				summary.finishReason = reason
+			}
+			deltaCalls, _ := delta["tool_calls"].([]interface{})
+			for _, dc := range deltaCalls {
+				deltaCall := dc.(map[string]interface{})
+				index := int(deltaCall["index"].(float64))
+				call := calls[index]
+				if call == nil {
+					call = &toolCall{}
+					calls[index] = call
+				}
+				if id, ok := deltaCall["id"].(string); ok {
+					call.id = id
+				}
+				function, _ := deltaCall["function"].(map[string]interface{})
+				if name, ok := function["name"].(string); ok {
+					call.name = name
+				}
+				arguments, _ := function["arguments"].(string)
+				call.arguments += arguments
+			}
+		}
+	}
+	indexes := make([]int, 0, len(calls))
+	for index := range calls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		call := calls[index]
+		summary.toolCalls = append(summary.toolCalls, call.id+" "+call.name+" "+call.arguments)
+	}
+	return summary
+}
+
+func TestReadSSE(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []sseEvent
+	}{
+		{"data only", "data: {}\n\n", []sseEvent{{Data: "{}"}}},
+		{"named event", "event: ping\ndata: {}\n\n", []sseEvent{{Event: "ping", Data: "{}"}}},
+		{"multi-line data", "data: a\ndata: b\n\n", []sseEvent{{Data: "a\nb"}}},
+		{"CRLF and comments", ": keep-alive\r\ndata:x\r\n\r\n", []sseEvent{{Data: "x"}}},
+		{"event without data is dropped", "event: ping\n\ndata: y\n\n", []sseEvent{{Data: "y"}}},
+		{"unterminated last event", "data: a\n\ndata: b", []sseEvent{{Data: "a"}, {Data: "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []sseEvent
+			if err := readSSE(strings.NewReader(tt.input), func(ev sseEvent) error {
+				got = append(got, ev)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
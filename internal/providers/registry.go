@@ -0,0 +1,82 @@
+package providers
+
+import (
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry implements core.IProviderFactory by mapping provider type names to constructors.
+// Unregistered types fall back to the OpenAI-compatible implementation, since most
+// self-hosted and proxy backends speak that protocol.
+type Registry struct {
+	mu           sync.RWMutex
+	constructors map[string]func() core.IProvider
+	fallback     string
+}
+
+// NewRegistry creates an empty registry that falls back to fallbackType for unknown types.
+func NewRegistry(fallbackType string) *Registry {
+	return &Registry{
+		constructors: make(map[string]func() core.IProvider),
+		fallback:     strings.ToLower(fallbackType),
+	}
+}
+
+// Register adds (or replaces) the constructor for a provider type.
+func (r *Registry) Register(providerType string, constructor func() core.IProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.constructors[strings.ToLower(providerType)] = constructor
+}
+
+// GetProvider returns the provider implementation for providerType.
+func (r *Registry) GetProvider(providerType string) (core.IProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if constructor, ok := r.constructors[strings.ToLower(providerType)]; ok {
+		return constructor(), nil
+	}
+	if constructor, ok := r.constructors[r.fallback]; ok {
+		return constructor(), nil
+	}
+	return nil, fmt.Errorf("unsupported provider type: %s", providerType)
+}
+
+// IsRegistered reports whether providerType has its own implementation (ignoring the fallback).
+func (r *Registry) IsRegistered(providerType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.constructors[strings.ToLower(providerType)]
+	return ok
+}
+
+// Types returns the registered provider type names in sorted order.
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.constructors))
+	for t := range r.constructors {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+var (
+	defaultRegistry     *Registry
+	defaultRegistryOnce sync.Once
+)
+
+// DefaultRegistry returns the process-wide registry with all built-in provider types.
+func DefaultRegistry() *Registry {
+	defaultRegistryOnce.Do(func() {
+		defaultRegistry = NewRegistry("openai")
+		defaultRegistry.Register("openai", func() core.IProvider { return &OpenAIProvider{} })
+		defaultRegistry.Register("anthropic", func() core.IProvider { return &AnthropicProvider{} })
+		defaultRegistry.Register("gemini", func() core.IProvider { return &GeminiProvider{} })
+	})
+	return defaultRegistry
+}
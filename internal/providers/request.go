@@ -0,0 +1,155 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/core"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultClientTimeout applies when an endpoint is used without an explicit HTTP client.
+const defaultClientTimeout = 30 * time.Second
+
+// resolveURL joins a provider base URL with an API path. fullPath is the complete
+// path (e.g. "/v1/chat/completions") and pathAfterV1 the part after the version
+// prefix, so base URLs may be a bare domain, end in /v1, or already be complete.
+func resolveURL(baseURL, fullPath, pathAfterV1 string) (string, error) {
+	if baseURL == "" {
+		return "", errors.New("baseUrl is not configured for the provider")
+	}
+
+	// Normalize base URL
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	// Case 1: URL is already complete
+	if strings.HasSuffix(baseURL, fullPath) {
+		return baseURL, nil
+	}
+
+	// Case 2: URL ends with /v1
+	if strings.HasSuffix(baseURL, "/v1") {
+		return baseURL + pathAfterV1, nil
+	}
+
+	// Case 3: Bare domain or other path
+	return baseURL + fullPath, nil
+}
+
+// baseURLOrDefault returns the endpoint's base URL, or the vendor's public API when none is configured.
+func baseURLOrDefault(endpoint *core.ProviderEndpoint, defaultURL string) string {
+	if endpoint.BaseURL != "" {
+		return endpoint.BaseURL
+	}
+	return defaultURL
+}
+
+// newJSONRequest builds a request with a JSON-encoded body.
+func newJSONRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewBuffer(jsonBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// newMultipartRequest builds a POST request with a multipart/form-data body. *core.FileUpload
+// values become file parts, arrays become repeated fields and other values are formatted as text.
+func newMultipartRequest(ctx context.Context, url string, fields map[string]interface{}) (*http.Request, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeMultipartField(writer, name, fields[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req, nil
+}
+
+func writeMultipartField(writer *multipart.Writer, name string, value interface{}) error {
+	switch v := value.(type) {
+	case *core.FileUpload:
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, name, strings.ReplaceAll(v.Filename, `"`, "")))
+		contentType := v.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		_, err = part.Write(v.Data)
+		return err
+	case []interface{}:
+		for _, item := range v {
+			if err := writeMultipartField(writer, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case nil:
+		return nil
+	default:
+		return writer.WriteField(name, fmt.Sprint(v))
+	}
+}
+
+// httpClient returns the endpoint's client, or a default one.
+func httpClient(endpoint *core.ProviderEndpoint) *http.Client {
+	if endpoint.Client != nil {
+		return endpoint.Client
+	}
+	return &http.Client{Timeout: defaultClientTimeout}
+}
+
+// getJSON performs a GET request and decodes a 200 response into out.
+func getJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
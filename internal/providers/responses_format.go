@@ -0,0 +1,665 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Translation of OpenAI Responses API requests onto chat completions, for providers without a
+// native /v1/responses endpoint. Requests are stateless: previous_response_id and the built-in
+// tools need the upstream's own Responses API.
+
+// responsesPassthroughFields are the Responses parameters chat completions accept as is.
+var responsesPassthroughFields = []string{
+	"model", "stream", "temperature", "top_p", "user", "parallel_tool_calls", "top_logprobs",
+}
+
+// responsesViaChat serves a Responses API request through the provider's chat API.
+func responsesViaChat(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+	chatBody, err := ConvertResponsesToChat(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	stream, _ := chatBody["stream"].(bool)
+	var resp *http.Response
+	if stream {
+		// The completed event carries usage, so ask OpenAI-compatible upstreams to report it
+		if enabled, ok := endpoint.Config["streamUsage"].(bool); !ok || enabled {
+			chatBody["stream_options"] = map[string]interface{}{"include_usage": true}
+		}
+		resp, err = provider.StreamChatCompletion(ctx, endpoint, chatBody)
+	} else {
+		resp, err = provider.ChatCompletion(ctx, endpoint, chatBody)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ConvertChatToResponsesResponse(resp, stream)
+	return resp, nil
+}
+
+// ConvertResponsesToChat maps a Responses API request onto a chat completion request.
+// Instructions become a system message and input items become chat messages; function calls
+// and their outputs become assistant tool calls and tool messages. Features chat cannot
+// express are reported as core.ErrOperationNotSupported.
+func ConvertResponsesToChat(body map[string]interface{}) (map[string]interface{}, error) {
+	if id, _ := body["previous_response_id"].(string); id != "" {
+		return nil, fmt.Errorf("previous_response_id requires a native Responses API: %w", core.ErrOperationNotSupported)
+	}
+
+	var messages []interface{}
+	if instructions, _ := body["instructions"].(string); instructions != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": instructions})
+	}
+	switch input := body["input"].(type) {
+	case string:
+		messages = append(messages, map[string]interface{}{"role": "user", "content": input})
+	case []interface{}:
+		converted, err := responsesInputMessages(input)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, converted...)
+	case nil:
+		return nil, &core.InvalidRequestError{Param: "input", Message: "input is required"}
+	default:
+		return nil, &core.InvalidRequestError{Param: "input", Message: "input must be a string or an array of input items"}
+	}
+
+	chatBody := map[string]interface{}{"messages": messages}
+	for _, field := range responsesPassthroughFields {
+		if value, ok := body[field]; ok {
+			chatBody[field] = value
+		}
+	}
+	if maxTokens, ok := toInt(body["max_output_tokens"]); ok {
+		chatBody["max_tokens"] = maxTokens
+	}
+	if reasoning, ok := body["reasoning"].(map[string]interface{}); ok {
+		if effort, ok := reasoning["effort"].(string); ok {
+			chatBody["reasoning_effort"] = effort
+		}
+	}
+	if text, ok := body["text"].(map[string]interface{}); ok {
+		if format, ok := text["format"].(map[string]interface{}); ok {
+			if responseFormat := chatResponseFormat(format); responseFormat != nil {
+				chatBody["response_format"] = responseFormat
+			}
+		}
+	}
+	if tools, ok := body["tools"].([]interface{}); ok && len(tools) > 0 {
+		chatTools, err := chatToolsFromResponses(tools)
+		if err != nil {
+			return nil, err
+		}
+		chatBody["tools"] = chatTools
+	}
+	if choice, ok := body["tool_choice"]; ok {
+		chatChoice, err := chatToolChoiceFromResponses(choice)
+		if err != nil {
+			return nil, err
+		}
+		chatBody["tool_choice"] = chatChoice
+	}
+	return chatBody, nil
+}
+
+// responsesInputMessages converts Responses input items into chat messages. Consecutive
+// function calls are collected into the tool_calls of one assistant message.
+func responsesInputMessages(items []interface{}) ([]interface{}, error) {
+	var messages []interface{}
+	var toolCallMessage map[string]interface{}
+	for i, raw := range items {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, &core.InvalidRequestError{Param: fmt.Sprintf("input[%d]", i), Message: "input items must be objects"}
+		}
+		itemType, _ := item["type"].(string)
+		if itemType == "" && item["role"] != nil {
+			itemType = "message"
+		}
+
+		switch itemType {
+		case "message":
+			message, err := chatMessageFromResponses(item)
+			var invalid *core.InvalidRequestError
+			if errors.As(err, &invalid) {
+				return nil, &core.InvalidRequestError{Param: fmt.Sprintf("input[%d].%s", i, invalid.Param), Message: invalid.Message}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("input[%d]: %w", i, err)
+			}
+			messages = append(messages, message)
+			toolCallMessage = nil
+		case "function_call":
+			call := map[string]interface{}{
+				"id":   item["call_id"],
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":      item["name"],
+					"arguments": item["arguments"],
+				},
+			}
+			if toolCallMessage == nil {
+				toolCallMessage = map[string]interface{}{"role": "assistant", "content": nil, "tool_calls": []interface{}{}}
+				messages = append(messages, toolCallMessage)
+			}
+			toolCallMessage["tool_calls"] = append(toolCallMessage["tool_calls"].([]interface{}), call)
+		case "function_call_output":
+			output, ok := item["output"].(string)
+			if !ok {
+				output = MessageText(item["output"])
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":         "tool",
+				"tool_call_id": item["call_id"],
+				"content":      output,
+			})
+			toolCallMessage = nil
+		case "reasoning":
+			// Reasoning from an earlier turn cannot be replayed to a chat API
+		default:
+			return nil, fmt.Errorf("input item type %q requires a native Responses API: %w", itemType, core.ErrOperationNotSupported)
+		}
+	}
+	return messages, nil
+}
+
+// chatMessageFromResponses converts a Responses message item into a chat message.
+func chatMessageFromResponses(item map[string]interface{}) (map[string]interface{}, error) {
+	role, _ := item["role"].(string)
+	switch role {
+	case "developer":
+		role = "system"
+	case "user", "assistant", "system":
+	default:
+		return nil, &core.InvalidRequestError{Param: "role", Message: fmt.Sprintf("unsupported message role %q", role)}
+	}
+
+	parts, ok := item["content"].([]interface{})
+	if !ok {
+		text, _ := item["content"].(string)
+		return map[string]interface{}{"role": role, "content": text}, nil
+	}
+
+	var chatParts []interface{}
+	hasImage := false
+	for _, raw := range parts {
+		part, _ := raw.(map[string]interface{})
+		switch part["type"] {
+		case "input_text", "output_text":
+			chatParts = append(chatParts, map[string]interface{}{"type": "text", "text": part["text"]})
+		case "refusal":
+			chatParts = append(chatParts, map[string]interface{}{"type": "text", "text": part["refusal"]})
+		case "input_image":
+			imageURL, _ := part["image_url"].(string)
+			if imageURL == "" {
+				return nil, fmt.Errorf("input_image by file_id requires a native Responses API: %w", core.ErrOperationNotSupported)
+			}
+			image := map[string]interface{}{"url": imageURL}
+			if detail, ok := part["detail"].(string); ok {
+				image["detail"] = detail
+			}
+			chatParts = append(chatParts, map[string]interface{}{"type": "image_url", "image_url": image})
+			hasImage = true
+		default:
+			return nil, fmt.Errorf("content part type %v requires a native Responses API: %w", part["type"], core.ErrOperationNotSupported)
+		}
+	}
+	if !hasImage {
+		// Plain text keeps the message acceptable to every chat adapter and to assistant turns
+		return map[string]interface{}{"role": role, "content": MessageText(chatParts)}, nil
+	}
+	return map[string]interface{}{"role": role, "content": chatParts}, nil
+}
+
+// chatToolsFromResponses converts Responses function tools, which are flat, into chat tools.
+func chatToolsFromResponses(tools []interface{}) ([]interface{}, error) {
+	chatTools := make([]interface{}, 0, len(tools))
+	for _, raw := range tools {
+		tool, _ := raw.(map[string]interface{})
+		if tool["type"] != "function" {
+			return nil, fmt.Errorf("tool type %v requires a native Responses API: %w", tool["type"], core.ErrOperationNotSupported)
+		}
+		function := map[string]interface{}{"name": tool["name"]}
+		for _, field := range []string{"description", "parameters", "strict"} {
+			if value, ok := tool[field]; ok && value != nil {
+				function[field] = value
+			}
+		}
+		chatTools = append(chatTools, map[string]interface{}{"type": "function", "function": function})
+	}
+	return chatTools, nil
+}
+
+// chatToolChoiceFromResponses converts a Responses tool_choice into its chat form.
+func chatToolChoiceFromResponses(choice interface{}) (interface{}, error) {
+	switch v := choice.(type) {
+	case string:
+		return v, nil
+	case map[string]interface{}:
+		if v["type"] == "function" {
+			return map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": v["name"]}}, nil
+		}
+	}
+	return nil, fmt.Errorf("tool_choice %v requires a native Responses API: %w", choice, core.ErrOperationNotSupported)
+}
+
+// chatResponseFormat converts a Responses text.format into a chat response_format, or nil for plain text.
+func chatResponseFormat(format map[string]interface{}) map[string]interface{} {
+	switch format["type"] {
+	case "json_object":
+		return map[string]interface{}{"type": "json_object"}
+	case "json_schema":
+		schema := map[string]interface{}{}
+		for _, field := range []string{"name", "description", "schema", "strict"} {
+			if value, ok := format[field]; ok {
+				schema[field] = value
+			}
+		}
+		return map[string]interface{}{"type": "json_schema", "json_schema": schema}
+	}
+	return nil
+}
+
+// chatCompletionMessage is the part of a chat completion choice a response is built from.
+type chatCompletionMessage struct {
+	Content   interface{} `json:"content"`
+	ToolCalls []struct {
+		ID       string `json:"id"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+}
+
+// chatUsage is an OpenAI chat usage object.
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ConvertChatToResponsesResponse rewrites a chat completion response as a Responses API
+// response object, or a chat stream as a stream of Responses events. Error responses are
+// already in OpenAI format and are left alone.
+func ConvertChatToResponsesResponse(resp *http.Response, stream bool) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return
+	}
+	if stream {
+		pipeStream(resp, translateChatStreamToResponses)
+		return
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var chat struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message      chatCompletionMessage `json:"message"`
+			FinishReason string                `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *chatUsage `json:"usage"`
+	}
+	if err != nil || json.Unmarshal(raw, &chat) != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		return
+	}
+
+	responseID := responseObjectID(chat.ID)
+	output := []interface{}{}
+	finishReason := ""
+	if len(chat.Choices) > 0 {
+		choice := chat.Choices[0]
+		finishReason = choice.FinishReason
+		if text := MessageText(choice.Message.Content); text != "" {
+			output = append(output, responseMessageItem("msg_"+responseID, text, "completed"))
+		}
+		for i, call := range choice.Message.ToolCalls {
+			output = append(output, responseFunctionCallItem(fmt.Sprintf("fc_%s_%d", responseID, i), call.ID, call.Function.Name, call.Function.Arguments, "completed"))
+		}
+	}
+	replaceJSONBody(resp, responseObject(responseID, chat.Model, chat.Created, output, finishReason, chat.Usage, true))
+}
+
+// responseObject builds a Responses API response object. A length finish ends the response
+// as incomplete.
+func responseObject(id, model string, created int64, output []interface{}, finishReason string, usage *chatUsage, done bool) map[string]interface{} {
+	status := "in_progress"
+	var incompleteDetails interface{}
+	if done {
+		status = "completed"
+		if finishReason == "length" {
+			status = "incomplete"
+			incompleteDetails = map[string]interface{}{"reason": "max_output_tokens"}
+		}
+	}
+	response := map[string]interface{}{
+		"id":                 id,
+		"object":             "response",
+		"created_at":         created,
+		"status":             status,
+		"model":              model,
+		"output":             output,
+		"incomplete_details": incompleteDetails,
+		"usage":              nil,
+	}
+	if usage != nil {
+		total := usage.TotalTokens
+		if total == 0 {
+			total = usage.PromptTokens + usage.CompletionTokens
+		}
+		response["usage"] = map[string]interface{}{
+			"input_tokens":  usage.PromptTokens,
+			"output_tokens": usage.CompletionTokens,
+			"total_tokens":  total,
+		}
+	}
+	return response
+}
+
+// responseMessageItem builds an assistant message output item.
+func responseMessageItem(id, text, status string) map[string]interface{} {
+	content := []interface{}{}
+	if status == "completed" {
+		content = append(content, responseTextPart(text))
+	}
+	return map[string]interface{}{
+		"type":    "message",
+		"id":      id,
+		"status":  status,
+		"role":    "assistant",
+		"content": content,
+	}
+}
+
+func responseTextPart(text string) map[string]interface{} {
+	return map[string]interface{}{"type": "output_text", "text": text, "annotations": []interface{}{}}
+}
+
+// responseFunctionCallItem builds a function_call output item.
+func responseFunctionCallItem(id, callID, name, arguments, status string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "function_call",
+		"id":        id,
+		"call_id":   callID,
+		"name":      name,
+		"arguments": arguments,
+		"status":    status,
+	}
+}
+
+// responseObjectID turns a chat completion ID into a response ID.
+func responseObjectID(chatID string) string {
+	if chatID == "" {
+		return fmt.Sprintf("resp_%d", time.Now().UnixNano())
+	}
+	return "resp_" + strings.TrimPrefix(chatID, "chatcmpl-")
+}
+
+// responsesStream converts chat.completion.chunk events into Responses streaming events. Text
+// becomes one message item and every tool call a function_call item; items are closed and
+// the response completed once the chat stream ends, when its usage is known.
+type responsesStream struct {
+	w        io.Writer
+	sequence int
+	started  bool
+
+	id      string
+	model   string
+	created int64
+
+	output       []interface{} // Finished output items
+	nextIndex    int           // output_index of the next item
+	message      *responsesStreamItem
+	toolCalls    map[int]*responsesStreamItem
+	toolOrder    []int
+	finishReason string
+	usage        *chatUsage
+}
+
+// responsesStreamItem is an output item still being streamed.
+type responsesStreamItem struct {
+	id          string
+	outputIndex int
+	callID      string
+	name        string
+	text        strings.Builder
+}
+
+func translateChatStreamToResponses(upstream io.Reader, w io.Writer) error {
+	rs := &responsesStream{w: w, toolCalls: make(map[int]*responsesStreamItem)}
+	finished := false
+	err := readSSE(upstream, func(ev sseEvent) error {
+		if finished {
+			return nil
+		}
+		if ev.Data == "[DONE]" {
+			finished = true
+			return rs.finish()
+		}
+		var chunk struct {
+			ID      string `json:"id"`
+			Created int64  `json:"created"`
+			Model   string `json:"model"`
+			Choices []struct {
+				Index int `json:"index"`
+				Delta struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *chatUsage `json:"usage"`
+			Error *struct {
+				Message string      `json:"message"`
+				Code    interface{} `json:"code"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			return nil
+		}
+		if chunk.Error != nil {
+			finished = true
+			return rs.emit("error", map[string]interface{}{"code": chunk.Error.Code, "message": chunk.Error.Message, "param": nil})
+		}
+		if err := rs.start(chunk.ID, chunk.Model, chunk.Created); err != nil {
+			return err
+		}
+		if chunk.Usage != nil {
+			rs.usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index != 0 {
+				// The Responses API has a single output sequence
+				continue
+			}
+			if choice.Delta.Content != "" {
+				if err := rs.appendText(choice.Delta.Content); err != nil {
+					return err
+				}
+			}
+			for _, call := range choice.Delta.ToolCalls {
+				if err := rs.appendToolCall(call.Index, call.ID, call.Function.Name, call.Function.Arguments); err != nil {
+					return err
+				}
+			}
+			if choice.FinishReason != "" {
+				rs.finishReason = choice.FinishReason
+			}
+		}
+		return nil
+	})
+	if err == nil && !finished && rs.started {
+		// Some adapters end without [DONE]
+		err = rs.finish()
+	}
+	return err
+}
+
+// emit writes one Responses event with its type and sequence number.
+func (rs *responsesStream) emit(eventType string, fields map[string]interface{}) error {
+	fields["type"] = eventType
+	fields["sequence_number"] = rs.sequence
+	rs.sequence++
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(rs.w, "event: %s\ndata: %s\n\n", eventType, payload)
+	return err
+}
+
+func (rs *responsesStream) start(chatID, model string, created int64) error {
+	if rs.started {
+		return nil
+	}
+	rs.started = true
+	rs.id = responseObjectID(chatID)
+	rs.model = model
+	rs.created = created
+	if rs.created == 0 {
+		rs.created = time.Now().Unix()
+	}
+	if err := rs.emit("response.created", map[string]interface{}{"response": rs.response(false)}); err != nil {
+		return err
+	}
+	return rs.emit("response.in_progress", map[string]interface{}{"response": rs.response(false)})
+}
+
+func (rs *responsesStream) response(done bool) map[string]interface{} {
+	return responseObject(rs.id, rs.model, rs.created, append([]interface{}{}, rs.output...), rs.finishReason, rs.usage, done)
+}
+
+func (rs *responsesStream) appendText(delta string) error {
+	if rs.message == nil {
+		rs.message = &responsesStreamItem{id: "msg_" + rs.id, outputIndex: rs.nextIndex}
+		rs.nextIndex++
+		if err := rs.emit("response.output_item.added", map[string]interface{}{
+			"output_index": rs.message.outputIndex,
+			"item":         responseMessageItem(rs.message.id, "", "in_progress"),
+		}); err != nil {
+			return err
+		}
+		if err := rs.emit("response.content_part.added", map[string]interface{}{
+			"item_id":       rs.message.id,
+			"output_index":  rs.message.outputIndex,
+			"content_index": 0,
+			"part":          responseTextPart(""),
+		}); err != nil {
+			return err
+		}
+	}
+	rs.message.text.WriteString(delta)
+	return rs.emit("response.output_text.delta", map[string]interface{}{
+		"item_id":       rs.message.id,
+		"output_index":  rs.message.outputIndex,
+		"content_index": 0,
+		"delta":         delta,
+	})
+}
+
+func (rs *responsesStream) appendToolCall(index int, callID, name, arguments string) error {
+	call, exists := rs.toolCalls[index]
+	if !exists {
+		call = &responsesStreamItem{
+			id:          fmt.Sprintf("fc_%s_%d", rs.id, index),
+			outputIndex: rs.nextIndex,
+			callID:      callID,
+			name:        name,
+		}
+		rs.nextIndex++
+		rs.toolCalls[index] = call
+		rs.toolOrder = append(rs.toolOrder, index)
+		if err := rs.emit("response.output_item.added", map[string]interface{}{
+			"output_index": call.outputIndex,
+			"item":         responseFunctionCallItem(call.id, call.callID, call.name, "", "in_progress"),
+		}); err != nil {
+			return err
+		}
+	}
+	if arguments == "" {
+		return nil
+	}
+	call.text.WriteString(arguments)
+	return rs.emit("response.function_call_arguments.delta", map[string]interface{}{
+		"item_id":      call.id,
+		"output_index": call.outputIndex,
+		"delta":        arguments,
+	})
+}
+
+// finish closes the open items and completes the response.
+func (rs *responsesStream) finish() error {
+	if !rs.started {
+		if err := rs.start("", "", 0); err != nil {
+			return err
+		}
+	}
+	if message := rs.message; message != nil {
+		text := message.text.String()
+		if err := rs.emit("response.output_text.done", map[string]interface{}{
+			"item_id":       message.id,
+			"output_index":  message.outputIndex,
+			"content_index": 0,
+			"text":          text,
+		}); err != nil {
+			return err
+		}
+		if err := rs.emit("response.content_part.done", map[string]interface{}{
+			"item_id":       message.id,
+			"output_index":  message.outputIndex,
+			"content_index": 0,
+			"part":          responseTextPart(text),
+		}); err != nil {
+			return err
+		}
+		item := responseMessageItem(message.id, text, "completed")
+		if err := rs.emit("response.output_item.done", map[string]interface{}{"output_index": message.outputIndex, "item": item}); err != nil {
+			return err
+		}
+		rs.output = append(rs.output, item)
+	}
+	for _, index := range rs.toolOrder {
+		call := rs.toolCalls[index]
+		arguments := call.text.String()
+		if err := rs.emit("response.function_call_arguments.done", map[string]interface{}{
+			"item_id":      call.id,
+			"output_index": call.outputIndex,
+			"arguments":    arguments,
+		}); err != nil {
+			return err
+		}
+		item := responseFunctionCallItem(call.id, call.callID, call.name, arguments, "completed")
+		if err := rs.emit("response.output_item.done", map[string]interface{}{"output_index": call.outputIndex, "item": item}); err != nil {
+			return err
+		}
+		rs.output = append(rs.output, item)
+	}
+
+	response := rs.response(true)
+	eventType := "response.completed"
+	if response["status"] == "incomplete" {
+		eventType = "response.incomplete"
+	}
+	return rs.emit(eventType, map[string]interface{}{"response": response})
+}
@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranslateChatStreamToResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		events []string
+		status string
+		output []string // "message text" or "function_call call_id name arguments"
+		usage  map[string]interface{}
+	}{
+		{
+			name: "text",
+			input: sseData(
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+				`{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`,
+				`[DONE]`),
+			events: []string{
+				"response.created", "response.in_progress",
+				"response.output_item.added", "response.content_part.added",
+				"response.output_text.delta", "response.output_text.delta",
+				"response.output_text.done", "response.content_part.done", "response.output_item.done",
+				"response.completed",
+			},
+			status: "completed",
+			output: []string{"message Hello"},
+			usage:  map[string]interface{}{"input_tokens": 5.0, "output_tokens": 2.0, "total_tokens": 7.0},
+		},
+		{
+			name: "text and tool call without [DONE]",
+			input: sseData(
+				`{"id":"chatcmpl-2","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"content":"Checking."}}]}`,
+				`{"id":"chatcmpl-2","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+				`{"id":"chatcmpl-2","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+				`{"id":"chatcmpl-2","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+				`{"id":"chatcmpl-2","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`),
+			events: []string{
+				"response.created", "response.in_progress",
+				"response.output_item.added", "response.content_part.added", "response.output_text.delta",
+				"response.output_item.added",
+				"response.function_call_arguments.delta", "response.function_call_arguments.delta",
+				"response.output_text.done", "response.content_part.done", "response.output_item.done",
+				"response.function_call_arguments.done", "response.output_item.done",
+				"response.completed",
+			},
+			status: "completed",
+			output: []string{"message Checking.", `function_call call_1 get_weather {"city":"Paris"}`},
+		},
+		{
+			name: "length",
+			input: sseData(
+				`{"id":"chatcmpl-3","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"content":"cut"},"finish_reason":"length"}]}`,
+				`[DONE]`),
+			events: []string{
+				"response.created", "response.in_progress",
+				"response.output_item.added", "response.content_part.added", "response.output_text.delta",
+				"response.output_text.done", "response.content_part.done", "response.output_item.done",
+				"response.incomplete",
+			},
+			status: "incomplete",
+			output: []string{"message cut"},
+		},
+		{
+			name:   "error",
+			input:  sseData(`{"error":{"message":"upstream failed","code":"server_error"}}`, `[DONE]`),
+			events: []string{"error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := runTranslator(t, translateChatStreamToResponses, tt.input)
+			var types []string
+			var last map[string]interface{}
+			for i, ev := range events {
+				last = eventData(t, ev)
+				if last["type"] != ev.Event {
+					t.Errorf("event %q has payload type %v", ev.Event, last["type"])
+				}
+				if last["sequence_number"] != float64(i) {
+					t.Errorf("event %d has sequence number %v", i, last["sequence_number"])
+				}
+				types = append(types, ev.Event)
+			}
+			if !reflect.DeepEqual(types, tt.events) {
+				t.Fatalf("events %q, want %q", types, tt.events)
+			}
+			if tt.status == "" {
+				return
+			}
+
+			response := last["response"].(map[string]interface{})
+			if response["status"] != tt.status {
+				t.Errorf("status %v, want %s", response["status"], tt.status)
+			}
+			var output []string
+			for _, o := range response["output"].([]interface{}) {
+				item := o.(map[string]interface{})
+				switch item["type"] {
+				case "message":
+					part := item["content"].([]interface{})[0].(map[string]interface{})
+					output = append(output, "message "+part["text"].(string))
+				case "function_call":
+					output = append(output, "function_call "+item["call_id"].(string)+" "+item["name"].(string)+" "+item["arguments"].(string))
+				}
+			}
+			if !reflect.DeepEqual(output, tt.output) {
+				t.Errorf("output %q, want %q", output, tt.output)
+			}
+			if usage, _ := response["usage"].(map[string]interface{}); tt.usage != nil && !reflect.DeepEqual(usage, tt.usage) {
+				t.Errorf("usage %v, want %v", usage, tt.usage)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"fmt"
+	"llm-fusion-engine/internal/constants"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CircuitBreakerConfig holds the thresholds of the circuit breakers.
+type CircuitBreakerConfig struct {
+	FailureThreshold         int           // Consecutive failures that open a circuit (0 disables)
+	ErrorRateThreshold       float64       // Error rate within Window that opens a circuit, 0-1 (0 disables)
+	MinRequests              int           // Outcomes required within Window before the error rate is evaluated
+	Window                   time.Duration // Sliding window for the error rate
+	OpenTimeout              time.Duration // How long a circuit stays open before admitting probes
+	HalfOpenMaxProbes        int           // Concurrent probe requests admitted while half-open
+	HalfOpenSuccessThreshold int           // Successful probes needed to close the circuit again
+}
+
+// DefaultCircuitBreakerConfig returns the thresholds used when none are configured.
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold:         5,
+		ErrorRateThreshold:       0.5,
+		MinRequests:              20,
+		Window:                   time.Minute,
+		OpenTimeout:              30 * time.Second,
+		HalfOpenMaxProbes:        1,
+		HalfOpenSuccessThreshold: 2,
+	}
+}
+
+// CircuitBreaker implements core.ICircuitBreaker with one breaker per provider and one per
+// model-provider mapping. State lives in memory; every transition is written to the
+// circuit_breaker_events table for auditing.
+type CircuitBreaker struct {
+	db     *gorm.DB
+	config CircuitBreakerConfig
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+type breaker struct {
+	scope               string
+	providerID          uint
+	mappingID           uint
+	state               constants.CircuitState
+	consecutiveFailures int
+	outcomes            []outcomeBucket
+	openedAt            time.Time
+	probesInFlight      int
+	probeSuccesses      int
+}
+
+type outcomeBucket struct {
+	second    int64
+	successes int
+	failures  int
+}
+
+type circuitTransition struct {
+	breaker *breaker
+	from    constants.CircuitState
+	to      constants.CircuitState
+	reason  string
+}
+
+// NewCircuitBreaker creates a CircuitBreaker with the given thresholds. Values that would keep
+// a circuit from ever closing again fall back to their defaults.
+func NewCircuitBreaker(db *gorm.DB, config CircuitBreakerConfig) *CircuitBreaker {
+	defaults := DefaultCircuitBreakerConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.HalfOpenMaxProbes < 1 {
+		config.HalfOpenMaxProbes = defaults.HalfOpenMaxProbes
+	}
+	if config.HalfOpenSuccessThreshold < 1 {
+		config.HalfOpenSuccessThreshold = defaults.HalfOpenSuccessThreshold
+	}
+	return &CircuitBreaker{
+		db:       db,
+		config:   config,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Allow reports whether a request may be sent through the mapping.
+func (cb *CircuitBreaker) Allow(providerID, mappingID uint) bool {
+	now := time.Now()
+	var transitions []circuitTransition
+
+	cb.mu.Lock()
+	provider := cb.breakerFor("provider", providerID, 0)
+	mapping := cb.breakerFor("mapping", providerID, mappingID)
+	providerOK, providerProbe := cb.admit(provider, now, &transitions)
+	mappingOK := false
+	if providerOK {
+		mappingOK, _ = cb.admit(mapping, now, &transitions)
+		if !mappingOK && providerProbe {
+			provider.probesInFlight--
+		}
+	}
+	cb.mu.Unlock()
+
+	cb.recordTransitions(transitions)
+	return providerOK && mappingOK
+}
+
+// Release returns probe slots claimed by Allow when the request was not sent.
+func (cb *CircuitBreaker) Release(providerID, mappingID uint) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	for _, b := range []*breaker{cb.breakerFor("provider", providerID, 0), cb.breakerFor("mapping", providerID, mappingID)} {
+		if b.state == constants.CircuitHalfOpen && b.probesInFlight > 0 {
+			b.probesInFlight--
+		}
+	}
+}
+
+// Record feeds the outcome of a request into the provider's and the mapping's breakers.
+func (cb *CircuitBreaker) Record(providerID, mappingID uint, success bool) {
+	now := time.Now()
+	var transitions []circuitTransition
+
+	cb.mu.Lock()
+	cb.observe(cb.breakerFor("provider", providerID, 0), now, success, &transitions)
+	cb.observe(cb.breakerFor("mapping", providerID, mappingID), now, success, &transitions)
+	cb.mu.Unlock()
+
+	cb.recordTransitions(transitions)
+}
+
+// Snapshot returns the state of every breaker that has seen traffic.
+func (cb *CircuitBreaker) Snapshot() []core.CircuitStatus {
+	now := time.Now()
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	statuses := make([]core.CircuitStatus, 0, len(cb.breakers))
+	for _, b := range cb.breakers {
+		requests, failures := cb.windowCounts(b, now)
+		status := core.CircuitStatus{
+			Scope:               b.scope,
+			ProviderID:          b.providerID,
+			MappingID:           b.mappingID,
+			State:               b.state.String(),
+			ConsecutiveFailures: b.consecutiveFailures,
+			Requests:            requests,
+			Failures:            failures,
+		}
+		if requests > 0 {
+			status.ErrorRate = float64(failures) / float64(requests)
+		}
+		if b.state != constants.CircuitClosed {
+			openedAt := b.openedAt
+			retryAt := b.openedAt.Add(cb.config.OpenTimeout)
+			status.OpenedAt = &openedAt
+			status.RetryAt = &retryAt
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].ProviderID != statuses[j].ProviderID {
+			return statuses[i].ProviderID < statuses[j].ProviderID
+		}
+		return statuses[i].MappingID < statuses[j].MappingID
+	})
+	return statuses
+}
+
+// breakerFor returns the breaker for a scope, creating a closed one on first use. Callers hold cb.mu.
+func (cb *CircuitBreaker) breakerFor(scope string, providerID, mappingID uint) *breaker {
+	key := fmt.Sprintf("provider:%d", providerID)
+	if scope == "mapping" {
+		key = fmt.Sprintf("mapping:%d", mappingID)
+	}
+	b, ok := cb.breakers[key]
+	if !ok {
+		b = &breaker{scope: scope, providerID: providerID, mappingID: mappingID, state: constants.CircuitClosed}
+		cb.breakers[key] = b
+	}
+	return b
+}
+
+// admit decides whether a breaker lets a request through and whether it claimed a probe slot.
+func (cb *CircuitBreaker) admit(b *breaker, now time.Time, transitions *[]circuitTransition) (bool, bool) {
+	if b.state == constants.CircuitOpen {
+		if now.Before(b.openedAt.Add(cb.config.OpenTimeout)) {
+			return false, false
+		}
+		cb.transition(b, constants.CircuitHalfOpen, "open timeout elapsed, probing", transitions)
+	}
+	if b.state == constants.CircuitHalfOpen {
+		if b.probesInFlight >= cb.config.HalfOpenMaxProbes {
+			return false, false
+		}
+		b.probesInFlight++
+		return true, true
+	}
+	return true, false
+}
+
+// observe applies one request outcome to a breaker.
+func (cb *CircuitBreaker) observe(b *breaker, now time.Time, success bool, transitions *[]circuitTransition) {
+	cb.addOutcome(b, now, success)
+	if success {
+		b.consecutiveFailures = 0
+	} else {
+		b.consecutiveFailures++
+	}
+
+	switch b.state {
+	case constants.CircuitHalfOpen:
+		if b.probesInFlight > 0 {
+			b.probesInFlight--
+		}
+		if !success {
+			cb.transition(b, constants.CircuitOpen, "probe request failed", transitions)
+			b.openedAt = now
+			return
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= cb.config.HalfOpenSuccessThreshold {
+			cb.transition(b, constants.CircuitClosed, fmt.Sprintf("%d probe requests succeeded", b.probeSuccesses), transitions)
+		}
+	case constants.CircuitClosed:
+		if success {
+			return
+		}
+		if cb.config.FailureThreshold > 0 && b.consecutiveFailures >= cb.config.FailureThreshold {
+			cb.transition(b, constants.CircuitOpen, fmt.Sprintf("%d consecutive failures", b.consecutiveFailures), transitions)
+			b.openedAt = now
+			return
+		}
+		requests, failures := cb.windowCounts(b, now)
+		if cb.config.ErrorRateThreshold > 0 && requests >= cb.config.MinRequests {
+			if rate := float64(failures) / float64(requests); rate >= cb.config.ErrorRateThreshold {
+				cb.transition(b, constants.CircuitOpen, fmt.Sprintf("error rate %.0f%% over %d requests", rate*100, requests), transitions)
+				b.openedAt = now
+			}
+		}
+	}
+}
+
+// transition changes a breaker's state and queues the change for the audit log.
+func (cb *CircuitBreaker) transition(b *breaker, to constants.CircuitState, reason string, transitions *[]circuitTransition) {
+	*transitions = append(*transitions, circuitTransition{breaker: b, from: b.state, to: to, reason: reason})
+	b.state = to
+	b.probesInFlight = 0
+	b.probeSuccesses = 0
+	if to == constants.CircuitClosed {
+		b.consecutiveFailures = 0
+		b.outcomes = nil
+	}
+}
+
+func (cb *CircuitBreaker) addOutcome(b *breaker, now time.Time, success bool) {
+	second := now.Unix()
+	if n := len(b.outcomes); n == 0 || b.outcomes[n-1].second != second {
+		b.outcomes = append(b.outcomes, outcomeBucket{second: second})
+	}
+	last := &b.outcomes[len(b.outcomes)-1]
+	if success {
+		last.successes++
+	} else {
+		last.failures++
+	}
+
+	// Drop buckets that have left the window
+	start := now.Add(-cb.config.Window).Unix()
+	i := 0
+	for i < len(b.outcomes) && b.outcomes[i].second <= start {
+		i++
+	}
+	b.outcomes = b.outcomes[i:]
+}
+
+func (cb *CircuitBreaker) windowCounts(b *breaker, now time.Time) (requests, failures int) {
+	start := now.Add(-cb.config.Window).Unix()
+	for _, bucket := range b.outcomes {
+		if bucket.second <= start {
+			continue
+		}
+		requests += bucket.successes + bucket.failures
+		failures += bucket.failures
+	}
+	return requests, failures
+}
+
+// recordTransitions logs and persists state changes. Called without cb.mu held.
+func (cb *CircuitBreaker) recordTransitions(transitions []circuitTransition) {
+	for _, t := range transitions {
+		log.Printf("[CircuitBreaker] %s breaker (provider=%d, mapping=%d): %s -> %s (%s)",
+			t.breaker.scope, t.breaker.providerID, t.breaker.mappingID, t.from, t.to, t.reason)
+		if cb.db == nil {
+			continue
+		}
+		event := database.CircuitBreakerEvent{
+			Scope:      t.breaker.scope,
+			ProviderID: t.breaker.providerID,
+			MappingID:  t.breaker.mappingID,
+			FromState:  t.from.String(),
+			ToState:    t.to.String(),
+			Reason:     t.reason,
+			Timestamp:  time.Now(),
+		}
+		if err := cb.db.Create(&event).Error; err != nil {
+			log.Printf("[CircuitBreaker] Failed to record transition: %v", err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"llm-fusion-engine/internal/constants"
+	"llm-fusion-engine/internal/database"
+	"testing"
+	"time"
+)
+
+// expireOpenCircuits moves every open circuit past its open timeout.
+func expireOpenCircuits(cb *CircuitBreaker) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	for _, b := range cb.breakers {
+		if b.state == constants.CircuitOpen {
+			b.openedAt = b.openedAt.Add(-cb.config.OpenTimeout - time.Second)
+		}
+	}
+}
+
+func mappingState(cb *CircuitBreaker, mappingID uint) string {
+	for _, status := range cb.Snapshot() {
+		if status.Scope == "mapping" && status.MappingID == mappingID {
+			return status.State
+		}
+	}
+	return ""
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	consecutive := CircuitBreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute, HalfOpenMaxProbes: 1, HalfOpenSuccessThreshold: 2}
+	errorRate := CircuitBreakerConfig{ErrorRateThreshold: 0.5, MinRequests: 4, OpenTimeout: time.Minute}
+
+	// Steps: "allow" and "reject" check Allow, "ok" and "fail" record an outcome,
+	// "release" returns a probe slot and "wait" lets the open timeout elapse
+	tests := []struct {
+		name   string
+		config CircuitBreakerConfig
+		steps  []string
+		want   constants.CircuitState
+	}{
+		{"closed below the failure threshold", consecutive, []string{"fail", "fail", "allow"}, constants.CircuitClosed},
+		{"consecutive failures open", consecutive, []string{"fail", "fail", "fail", "reject"}, constants.CircuitOpen},
+		{"success resets the failure count", consecutive, []string{"fail", "fail", "ok", "fail", "fail", "allow"}, constants.CircuitClosed},
+		{"error rate opens", errorRate, []string{"ok", "fail", "ok", "fail", "reject"}, constants.CircuitOpen},
+		{"error rate needs the minimum requests", errorRate, []string{"fail", "fail", "fail", "allow"}, constants.CircuitClosed},
+		{"error rate below the threshold", errorRate, []string{"ok", "ok", "ok", "fail", "allow"}, constants.CircuitClosed},
+		{"stays open until the timeout", consecutive, []string{"fail", "fail", "fail", "reject", "reject"}, constants.CircuitOpen},
+		{"timeout admits one probe", consecutive, []string{"fail", "fail", "fail", "wait", "allow", "reject"}, constants.CircuitHalfOpen},
+		{"released probe slot is reusable", consecutive, []string{"fail", "fail", "fail", "wait", "allow", "release", "allow"}, constants.CircuitHalfOpen},
+		{"failed probe reopens", consecutive, []string{"fail", "fail", "fail", "wait", "allow", "fail", "reject"}, constants.CircuitOpen},
+		{"one successful probe is not enough", consecutive, []string{"fail", "fail", "fail", "wait", "allow", "ok"}, constants.CircuitHalfOpen},
+		{"successful probes close", consecutive, []string{"fail", "fail", "fail", "wait", "allow", "ok", "allow", "ok", "allow", "allow"}, constants.CircuitClosed},
+		{"closing forgets old failures", consecutive, []string{"fail", "fail", "fail", "wait", "allow", "ok", "allow", "ok", "fail", "fail", "allow"}, constants.CircuitClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewCircuitBreaker(nil, tt.config)
+			for i, step := range tt.steps {
+				switch step {
+				case "allow", "reject":
+					if got := cb.Allow(1, 10); got != (step == "allow") {
+						t.Fatalf("step %d: Allow = %v, want %v", i, got, step == "allow")
+					}
+				case "ok", "fail":
+					cb.Record(1, 10, step == "ok")
+				case "release":
+					cb.Release(1, 10)
+				case "wait":
+					expireOpenCircuits(cb)
+				}
+			}
+			if got := mappingState(cb, 10); got != tt.want.String() {
+				t.Errorf("state %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerProviderScope(t *testing.T) {
+	cb := NewCircuitBreaker(nil, CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	// Failures spread over a provider's mappings open the provider's circuit for all of them
+	cb.Record(1, 10, false)
+	cb.Record(1, 11, false)
+	tests := []struct {
+		providerID, mappingID uint
+		want                  bool
+	}{
+		{1, 10, false},
+		{1, 11, false},
+		{1, 12, false},
+		{2, 20, true},
+	}
+	for _, tt := range tests {
+		if got := cb.Allow(tt.providerID, tt.mappingID); got != tt.want {
+			t.Errorf("Allow(%d, %d) = %v, want %v", tt.providerID, tt.mappingID, got, tt.want)
+		}
+	}
+
+	// A half-open provider does not spend its probe on a mapping whose own circuit is open
+	cb = NewCircuitBreaker(nil, CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+	cb.Record(1, 10, false)
+	expireOpenCircuits(cb)
+	cb.Record(1, 11, false)
+	if cb.Allow(1, 11) {
+		t.Fatal("mapping with an open circuit admitted")
+	}
+	if !cb.Allow(1, 10) {
+		t.Error("provider probe slot was leaked by a rejected mapping")
+	}
+}
+
+func TestCircuitBreakerRecordsEvents(t *testing.T) {
+	db := newTestDB(t)
+	cb := NewCircuitBreaker(db, CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenSuccessThreshold: 1})
+	cb.Record(1, 10, false)
+	expireOpenCircuits(cb)
+	cb.Allow(1, 10)
+	cb.Record(1, 10, true)
+
+	var events []database.CircuitBreakerEvent
+	db.Where("scope = ?", "mapping").Order("id").Find(&events)
+	want := [][2]string{{"closed", "open"}, {"open", "half_open"}, {"half_open", "closed"}}
+	if len(events) != len(want) {
+		t.Fatalf("%d mapping events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.FromState != want[i][0] || event.ToState != want[i][1] || event.MappingID != 10 {
+			t.Errorf("event %d: %s -> %s (mapping %d), want %s -> %s", i, event.FromState, event.ToState, event.MappingID, want[i][0], want[i][1])
+		}
+	}
+}
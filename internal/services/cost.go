@@ -0,0 +1,53 @@
+package services
+
+import (
+	"encoding/json"
+	"llm-fusion-engine/internal/database"
+	"llm-fusion-engine/internal/util"
+	"unicode/utf8"
+)
+
+// costFunc prices a completed request from the model's prices, the request body sent
+// upstream and what the capture saw of the response.
+type costFunc func(prices *database.Model, requestBody map[string]interface{}, capture *util.ResponseCapture) float64
+
+// tokenCost prices the prompt and completion tokens of a request.
+func tokenCost(prices *database.Model, requestBody map[string]interface{}, capture *util.ResponseCapture) float64 {
+	usage := capture.Usage()
+	return (float64(usage.PromptTokens)*prices.InputPrice + float64(usage.CompletionTokens)*prices.OutputPrice) / 1e6
+}
+
+// imageCost prices each requested image, plus the tokens of models that bill them.
+func imageCost(prices *database.Model, requestBody map[string]interface{}, capture *util.ResponseCapture) float64 {
+	images := 1
+	if n, ok := requestBody["n"].(float64); ok && n > 1 {
+		images = int(n)
+	}
+	return float64(images)*prices.ImagePrice + tokenCost(prices, requestBody, capture)
+}
+
+// transcriptionCost prices the audio duration the upstream reported, plus the tokens of
+// models that bill them. Only the json and verbose_json formats report a duration.
+func transcriptionCost(prices *database.Model, requestBody map[string]interface{}, capture *util.ResponseCapture) float64 {
+	var response struct {
+		Duration float64 `json:"duration"`
+		Usage    struct {
+			Type    string  `json:"type"`
+			Seconds float64 `json:"seconds"`
+		} `json:"usage"`
+	}
+	seconds := 0.0
+	if !capture.Truncated() && json.Unmarshal(capture.Body(), &response) == nil {
+		seconds = response.Duration
+		if response.Usage.Type == "duration" {
+			seconds = response.Usage.Seconds
+		}
+	}
+	return seconds/60*prices.AudioPrice + tokenCost(prices, requestBody, capture)
+}
+
+// speechCost prices the characters of the text to synthesize.
+func speechCost(prices *database.Model, requestBody map[string]interface{}, capture *util.ResponseCapture) float64 {
+	input, _ := requestBody["input"].(string)
+	return float64(utf8.RuneCountInString(input)) * prices.SpeechPrice / 1e6
+}
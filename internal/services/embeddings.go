@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/util"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits of one upstream embeddings call. Larger input arrays are split into batches that are
+// sent one after another and merged into a single response.
+const (
+	embeddingBatchSize   = 512
+	embeddingBatchTokens = 100000
+)
+
+// embeddingsResponse is the OpenAI embeddings response shape.
+type embeddingsResponse struct {
+	Object string `json:"object"`
+	Data   []struct {
+		Object    string          `json:"object"`
+		Index     int             `json:"index"`
+		Embedding json.RawMessage `json:"embedding"`
+	} `json:"data"`
+	Model string `json:"model"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// ProcessEmbeddingsHttpAsync handles an embeddings request. It is routed through the model's
+// mappings like a chat completion, and input arrays beyond one batch are split across calls.
+func (s *MultiProviderService) ProcessEmbeddingsHttpAsync(
+	c *gin.Context,
+	requestBody map[string]interface{},
+	proxyKey string,
+) (*http.Response, error) {
+	model, ok := requestBody["model"].(string)
+	if !ok {
+		return nil, errors.New("model not specified in request")
+	}
+
+	cacheKey, cacheTTL := s.responseCacheKey(c, embeddingsOperation, requestBody, proxyKey)
+	if cached := s.replayFromCache(c, cacheKey, requestBody, proxyKey, model); cached != nil {
+		return cached, nil
+	}
+
+	scope := newRequestScope(c.Request.Context())
+	batches := splitEmbeddingInput(requestBody["input"])
+	if len(batches) <= 1 {
+		var excludedProviders []uint
+		resp, _, err := s.dispatch(c, scope, embeddingsOperation, requestBody, model, proxyKey, &excludedProviders, false)
+		if err != nil {
+			scope.finish()
+			return nil, err
+		}
+		resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: func() { s.finishRequest(c, scope, model) }}
+		s.cacheOnComplete(resp, cacheKey, cacheTTL, false, nil)
+		return resp, nil
+	}
+
+	// Every batch has been read by the time the merged response is returned
+	defer s.finishRequest(c, scope, model)
+	resp, err := s.dispatchEmbeddingBatches(c, scope, requestBody, model, proxyKey, batches)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheOnComplete(resp, cacheKey, cacheTTL, false, nil)
+	return resp, nil
+}
+
+// dispatchEmbeddingBatches sends each batch as its own request and merges the results,
+// renumbering the embeddings so their indexes refer to the original input array.
+func (s *MultiProviderService) dispatchEmbeddingBatches(
+	c *gin.Context,
+	scope *requestScope,
+	requestBody map[string]interface{},
+	model string,
+	proxyKey string,
+	batches [][]interface{},
+) (*http.Response, error) {
+	var merged embeddingsResponse
+	var header http.Header
+	offset := 0
+	for _, batch := range batches {
+		batchBody := make(map[string]interface{}, len(requestBody))
+		for key, value := range requestBody {
+			batchBody[key] = value
+		}
+		batchBody["model"] = model
+		batchBody["input"] = batch
+
+		var excludedProviders []uint
+		resp, _, err := s.dispatch(c, scope, embeddingsOperation, batchBody, model, proxyKey, &excludedProviders, false)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		var result embeddingsResponse
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("invalid embeddings response for batch at input %d: %w", offset, err)
+		}
+
+		for _, item := range result.Data {
+			item.Index += offset
+			merged.Data = append(merged.Data, item)
+		}
+		merged.Usage.PromptTokens += result.Usage.PromptTokens
+		merged.Usage.TotalTokens += result.Usage.TotalTokens
+		if header == nil {
+			// Upstreams may leave out the model, so the first batch's headers are kept regardless
+			header = resp.Header.Clone()
+		}
+		if merged.Model == "" {
+			merged.Model = result.Model
+		}
+		offset += len(batch)
+	}
+
+	merged.Object = "list"
+	payload, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(len(payload)))
+	header.Del("Content-Encoding")
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(payload)),
+		ContentLength: int64(len(payload)),
+	}, nil
+}
+
+// splitEmbeddingInput splits an input array of texts or token arrays into batches bounded by
+// embeddingBatchSize items and roughly embeddingBatchTokens tokens. A single text or a single
+// token array yields no batches.
+func splitEmbeddingInput(input interface{}) [][]interface{} {
+	items, ok := input.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil
+	}
+	if _, isToken := items[0].(float64); isToken {
+		return nil
+	}
+
+	var batches [][]interface{}
+	var batch []interface{}
+	batchTokens := 0
+	for _, item := range items {
+		tokens := 0
+		switch v := item.(type) {
+		case string:
+			tokens = util.EstimateTokens(v)
+		case []interface{}:
+			tokens = len(v)
+		}
+		if len(batch) > 0 && (len(batch) >= embeddingBatchSize || batchTokens+tokens > embeddingBatchTokens) {
+			batches = append(batches, batch)
+			batch, batchTokens = nil, 0
+		}
+		batch = append(batch, item)
+		batchTokens += tokens
+	}
+	return append(batches, batch)
+}
@@ -1,14 +1,14 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"llm-fusion-engine/internal/constants"
+	"llm-fusion-engine/internal/core"
 	"llm-fusion-engine/internal/database"
 	"log"
-	"net/http"
-	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,12 +16,18 @@ import (
 
 // HealthChecker service for checking provider health
 type HealthChecker struct {
-	db *gorm.DB
+	db              *gorm.DB
+	providerFactory core.IProviderFactory
+	transportPool   core.ITransportPool
 }
 
-// NewHealthChecker creates a new HealthChecker service
-func NewHealthChecker(db *gorm.DB) *HealthChecker {
-	return &HealthChecker{db: db}
+// healthCheckTimeout bounds a single health check request.
+const healthCheckTimeout = 10 * time.Second
+
+// NewHealthChecker creates a new HealthChecker service. Probes share the transport pool of
+// live traffic, so they also exercise the provider's proxy and TLS settings.
+func NewHealthChecker(db *gorm.DB, factory core.IProviderFactory, transportPool core.ITransportPool) *HealthChecker {
+	return &HealthChecker{db: db, providerFactory: factory, transportPool: transportPool}
 }
 
 // CheckProvider checks the health of a single provider by making a test request
@@ -53,67 +59,63 @@ func (hc *HealthChecker) CheckProvider(providerID uint) error {
 		return nil
 	}
 
-	// Get API key from config
+	// Get API key, preferring one of the provider's healthy ApiKey rows over the config key
 	apiKey, _ := config["apiKey"].(string)
-	
+	var providerKey database.ApiKey
+	if err := hc.db.Where("provider_id = ? AND is_healthy = ?", provider.ID, true).Order("last_used").First(&providerKey).Error; err == nil {
+		apiKey = providerKey.Key
+	}
+
+	providerImpl, err := hc.providerFactory.GetProvider(provider.Type)
+	if err != nil {
+		now := time.Now()
+		provider.HealthStatus = string(constants.HealthStatusUnknown)
+		provider.LastChecked = &now
+		hc.db.Save(&provider)
+		return err
+	}
+
 	// Use a default model for testing based on provider type
 	// Many proxy services don't support /v1/models endpoint reliably
-	var testModel string
-	switch strings.ToLower(provider.Type) {
-	case "anthropic":
-		testModel = "claude-3-haiku-20240307"
-	case "gemini":
-		testModel = "gemini-1.5-flash"
-	default:
-		testModel = "gpt-3.5-turbo" // Default for OpenAI-compatible APIs
+	testModel := "gpt-3.5-turbo" // Default for OpenAI-compatible APIs
+	if defaults := providerImpl.DefaultModels(); len(defaults) > 0 {
+		testModel = defaults[0]
 	}
-	
+
 	log.Printf("[HealthCheck] Provider ID=%d, Name=%s, Type=%s, Using default test model: %s",
 		providerID, provider.Name, provider.Type, testModel)
-	
-	// Send chat completion request with "hi" directly
-	chatURL := strings.TrimSuffix(baseURL, "/") + "/chat/completions"
+
+	// Send chat completion request with "hi" directly; the provider translates it to its native API
 	chatPayload := map[string]interface{}{
 		"model": testModel,
-		"messages": []map[string]string{
-			{"role": "user", "content": "hi"},
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": "hi"},
 		},
 		"max_tokens": 10,
 	}
-	
-	chatBody, err := json.Marshal(chatPayload)
-	if err != nil {
-		log.Printf("[HealthCheck] Provider ID=%d: Failed to marshal chat request: %v", providerID, err)
-		now := time.Now()
-		provider.HealthStatus = string(constants.HealthStatusUnhealthy)
-		provider.Latency = nil
-		provider.LastChecked = &now
-		hc.db.Save(&provider)
-		return err
-	}
-	
-	log.Printf("[HealthCheck] Provider ID=%d: Sending chat request to %s with model %s", providerID, chatURL, testModel)
-	
-	chatReq, err := http.NewRequest("POST", chatURL, strings.NewReader(string(chatBody)))
+	client, err := hc.transportPool.Client(&provider, config)
 	if err != nil {
-		log.Printf("[HealthCheck] Provider ID=%d: Failed to create chat request: %v", providerID, err)
+		log.Printf("[HealthCheck] Provider ID=%d: %v", providerID, err)
 		now := time.Now()
 		provider.HealthStatus = string(constants.HealthStatusUnhealthy)
-		provider.Latency = nil
 		provider.LastChecked = &now
 		hc.db.Save(&provider)
 		return err
 	}
-	
-	chatReq.Header.Set("Content-Type", "application/json")
-	if apiKey != "" {
-		chatReq.Header.Set("Authorization", "Bearer "+apiKey)
+	endpoint := &core.ProviderEndpoint{
+		BaseURL: baseURL,
+		ApiKey:  apiKey,
+		Config:  config,
+		Client:  client,
 	}
-	
+
+	log.Printf("[HealthCheck] Provider ID=%d: Sending chat request to %s with model %s", providerID, baseURL, testModel)
+
 	// Measure latency for chat request
-	client := &http.Client{Timeout: 10 * time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
 	startTime := time.Now()
-	resp, err := client.Do(chatReq)
+	resp, err := providerImpl.ChatCompletion(ctx, endpoint, chatPayload)
 	latency := time.Since(startTime).Milliseconds()
 	now := time.Now()
 
@@ -1,18 +1,53 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
+	"llm-fusion-engine/internal/core"
 	"llm-fusion-engine/internal/database"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Cool-down periods applied when a provider API key fails upstream.
+const (
+	KeyAuthFailureCooldown = 30 * time.Minute
+	KeyQuotaCooldown       = time.Hour
+	KeyRateLimitCooldown   = time.Minute
+)
+
+// Key selection strategies, configured per provider with the "keySelection" config field.
+const (
+	KeySelectionLeastRecentlyUsed = "least_recently_used"
+	KeySelectionRoundRobin        = "round_robin"
+)
+
+// ErrNoAvailableProviderKey is returned when a provider has API keys but none is currently usable.
+var ErrNoAvailableProviderKey = errors.New("all API keys of the provider are unhealthy or rate limited")
+
 // KeyManager implements the IKeyManager interface.
 type KeyManager struct {
-	db *gorm.DB
+	db          *gorm.DB
+	rateLimiter core.IRateLimiter
+
+	mu         sync.Mutex
+	roundRobin map[uint]uint64 // provider ID -> next key offset
 }
 
 // NewKeyManager creates a new KeyManager.
-func NewKeyManager(db *gorm.DB) *KeyManager {
-	return &KeyManager{db: db}
+func NewKeyManager(db *gorm.DB, rateLimiter core.IRateLimiter) *KeyManager {
+	return &KeyManager{
+		db:          db,
+		rateLimiter: rateLimiter,
+		roundRobin:  make(map[uint]uint64),
+	}
 }
 
 // ValidateProxyKeyAsync checks if a proxy key is valid and enabled.
@@ -25,6 +60,90 @@ func (km *KeyManager) ValidateProxyKeyAsync(proxyKey string) (*database.ProxyKey
 	return &key, nil
 }
 
+// SelectProviderKey picks one of the provider's healthy ApiKey rows and charges it with the
+// request against its own RPM/TPM limits. It returns a nil key when the provider has no
+// ApiKey rows, in which case the caller uses the key from the provider config.
+func (km *KeyManager) SelectProviderKey(provider *database.Provider, estimatedTokens int) (*database.ApiKey, *core.RateLimitReservation, error) {
+	var keys []database.ApiKey
+	if err := km.db.Where("provider_id = ?", provider.ID).Order("id").Find(&keys).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil, nil
+	}
+
+	now := time.Now()
+	candidates := make([]database.ApiKey, 0, len(keys))
+	for _, key := range keys {
+		if !key.IsHealthy {
+			if key.UnhealthyUntil == nil || now.Before(*key.UnhealthyUntil) {
+				continue
+			}
+			// Cool-down elapsed, put the key back into rotation
+			km.db.Model(&database.ApiKey{}).Where("id = ?", key.ID).
+				Updates(map[string]interface{}{"is_healthy": true, "unhealthy_until": nil})
+			key.IsHealthy = true
+			key.UnhealthyUntil = nil
+			log.Printf("[KeyManager] API key ID=%d of provider %s re-enabled after cool-down", key.ID, provider.Name)
+		}
+		candidates = append(candidates, key)
+	}
+
+	switch providerKeySelection(provider) {
+	case KeySelectionRoundRobin:
+		km.mu.Lock()
+		offset := km.roundRobin[provider.ID]
+		km.roundRobin[provider.ID] = offset + 1
+		km.mu.Unlock()
+		if n := len(candidates); n > 0 {
+			start := int(offset % uint64(n))
+			candidates = append(candidates[start:], candidates[:start]...)
+		}
+	default:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].LastUsed.Before(candidates[j].LastUsed)
+		})
+	}
+
+	for i := range candidates {
+		key := &candidates[i]
+		var reservation *core.RateLimitReservation
+		if km.rateLimiter != nil {
+			decision := km.rateLimiter.ReserveApiKey(key, estimatedTokens)
+			if !decision.Allowed {
+				continue
+			}
+			reservation = decision.Reservation
+		}
+		key.LastUsed = now
+		km.db.Model(&database.ApiKey{}).Where("id = ?", key.ID).Update("last_used", now)
+		return key, reservation, nil
+	}
+	return nil, nil, ErrNoAvailableProviderKey
+}
+
+// ReportKeyFailure inspects a failed upstream response and, if it was caused by the API key
+// (authentication, exhausted quota or rate limiting), takes the key out of rotation for a
+// cool-down period. It reports whether the key was disabled.
+func (km *KeyManager) ReportKeyFailure(apiKeyID uint, statusCode int, header http.Header, body []byte) bool {
+	cooldown, reason := keyFailureCooldown(statusCode, header, body)
+	if cooldown == 0 {
+		return false
+	}
+	until := time.Now().Add(cooldown)
+	km.db.Model(&database.ApiKey{}).Where("id = ?", apiKeyID).
+		Updates(map[string]interface{}{"is_healthy": false, "unhealthy_until": until})
+	log.Printf("[KeyManager] API key ID=%d disabled for %v: %s (status %d)", apiKeyID, cooldown, reason, statusCode)
+	return true
+}
+
+// ReconcileKeyUsage replaces the estimated token charge on a provider API key with the actual usage.
+func (km *KeyManager) ReconcileKeyUsage(reservation *core.RateLimitReservation, actualTokens int) {
+	if km.rateLimiter != nil && reservation != nil {
+		km.rateLimiter.Reconcile(reservation, actualTokens)
+	}
+}
+
 // UpdateLogTokens updates an existing log entry with token usage data.
 func (km *KeyManager) UpdateLogTokens(requestID string, promptTokens, completionTokens, totalTokens int) {
 	km.db.Model(&database.Log{}).Where("id = ?", requestID).Updates(database.Log{
@@ -32,4 +151,36 @@ func (km *KeyManager) UpdateLogTokens(requestID string, promptTokens, completion
 		CompletionTokens: completionTokens,
 		TotalTokens:      totalTokens,
 	})
-}
\ No newline at end of file
+}
+
+// providerKeySelection reads the provider's key selection strategy from its config.
+func providerKeySelection(provider *database.Provider) string {
+	var config map[string]interface{}
+	if json.Unmarshal([]byte(provider.Config), &config) == nil {
+		if strategy, ok := config["keySelection"].(string); ok && strategy != "" {
+			return strategy
+		}
+	}
+	return KeySelectionLeastRecentlyUsed
+}
+
+// keyFailureCooldown classifies an upstream error as key-related and returns how long to disable the key.
+func keyFailureCooldown(statusCode int, header http.Header, body []byte) (time.Duration, string) {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return KeyAuthFailureCooldown, "authentication failed"
+	case http.StatusPaymentRequired:
+		return KeyQuotaCooldown, "quota exhausted"
+	case http.StatusTooManyRequests:
+		lowerBody := strings.ToLower(string(body))
+		if strings.Contains(lowerBody, "insufficient_quota") || strings.Contains(lowerBody, "exceeded your current quota") ||
+			strings.Contains(lowerBody, "billing") || strings.Contains(lowerBody, "credit balance") {
+			return KeyQuotaCooldown, "quota exhausted"
+		}
+		if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second, "rate limited"
+		}
+		return KeyRateLimitCooldown, "rate limited"
+	}
+	return 0, ""
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"llm-fusion-engine/internal/constants"
+	"llm-fusion-engine/internal/database"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencySmoothing is the weight of a new sample in the per-mapping latency moving average.
+const latencySmoothing = 0.3
+
+// BalanceStrategy orders the mappings of one priority tier. poolKey identifies the candidate
+// pool (the requested model) so stateful strategies can keep separate state per pool.
+type BalanceStrategy func(poolKey string, tier []database.ModelProviderMapping) []database.ModelProviderMapping
+
+// LoadBalancer implements core.ILoadBalancer. Mappings are grouped into tiers by provider
+// priority (higher first); lower tiers are only reached on failover. Within a tier, the
+// configured strategy decides which mapping is tried first.
+type LoadBalancer struct {
+	mu         sync.Mutex
+	strategies map[constants.LoadBalancePolicy]BalanceStrategy
+	smoothRR   map[string]map[uint]int // pool key -> mapping ID -> current weight
+	latency    map[uint]float64        // mapping ID -> moving average latency in ms
+	inFlight   map[uint]int            // mapping ID -> requests in progress
+	rand       *rand.Rand
+}
+
+// NewLoadBalancer creates a LoadBalancer with the built-in strategies registered.
+func NewLoadBalancer() *LoadBalancer {
+	lb := &LoadBalancer{
+		strategies: make(map[constants.LoadBalancePolicy]BalanceStrategy),
+		smoothRR:   make(map[string]map[uint]int),
+		latency:    make(map[uint]float64),
+		inFlight:   make(map[uint]int),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	lb.RegisterStrategy(constants.LoadBalanceFailover, failoverOrder)
+	lb.RegisterStrategy(constants.LoadBalanceWeightedRandom, lb.weightedRandomOrder)
+	lb.RegisterStrategy(constants.LoadBalanceRoundRobin, lb.smoothRoundRobinOrder)
+	lb.RegisterStrategy(constants.LoadBalanceLeastLatency, lb.leastLatencyOrder)
+	lb.RegisterStrategy(constants.LoadBalanceLeastInFlight, lb.leastInFlightOrder)
+	// Groups historically store "weighted" as their policy
+	lb.RegisterStrategy("weighted", lb.weightedRandomOrder)
+	return lb
+}
+
+// RegisterStrategy adds or replaces the strategy used for a policy.
+func (lb *LoadBalancer) RegisterStrategy(policy constants.LoadBalancePolicy, strategy BalanceStrategy) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.strategies[policy] = strategy
+}
+
+// Order returns the mappings in the order they should be tried. Unknown policies fall back to failover.
+func (lb *LoadBalancer) Order(policy string, poolKey string, mappings []database.ModelProviderMapping) []database.ModelProviderMapping {
+	lb.mu.Lock()
+	strategy, ok := lb.strategies[constants.LoadBalancePolicy(policy)]
+	lb.mu.Unlock()
+	if !ok {
+		strategy = failoverOrder
+	}
+
+	sorted := make([]database.ModelProviderMapping, len(mappings))
+	copy(sorted, mappings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		// Higher priority value means it comes first
+		return sorted[i].Provider.Priority > sorted[j].Provider.Priority
+	})
+
+	ordered := make([]database.ModelProviderMapping, 0, len(sorted))
+	for start := 0; start < len(sorted); {
+		end := start + 1
+		for end < len(sorted) && sorted[end].Provider.Priority == sorted[start].Provider.Priority {
+			end++
+		}
+		ordered = append(ordered, strategy(poolKey, sorted[start:end])...)
+		start = end
+	}
+	return ordered
+}
+
+// OrderGroups orders the groups a proxy key may route through. The failover policy tries groups
+// by priority (higher first); weighted_random (or "weighted") and round_robin spread requests
+// by the key's group weights, where groups without a weight count as 1.
+func (lb *LoadBalancer) OrderGroups(policy string, poolKey string, groups []database.Group, weights map[uint]int) []database.Group {
+	ordered := make([]database.Group, len(groups))
+	copy(ordered, groups)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+
+	ids := make([]uint, len(ordered))
+	groupWeights := make([]int, len(ordered))
+	for i, group := range ordered {
+		ids[i] = group.ID
+		groupWeights[i] = 1
+		if weight, ok := weights[group.ID]; ok {
+			groupWeights[i] = weight
+		}
+	}
+
+	var order []int
+	switch constants.LoadBalancePolicy(policy) {
+	case constants.LoadBalanceWeightedRandom, "weighted":
+		order = lb.weightedRandomPermutation(groupWeights)
+	case constants.LoadBalanceRoundRobin:
+		order = lb.smoothRoundRobinPermutation(poolKey, ids, groupWeights)
+	default:
+		return ordered
+	}
+	result := make([]database.Group, len(order))
+	for n, i := range order {
+		result[n] = ordered[i]
+	}
+	return result
+}
+
+// BeginRequest marks a request to the mapping as in flight. The returned func ends it and
+// is safe to call more than once.
+func (lb *LoadBalancer) BeginRequest(mappingID uint) func() {
+	lb.mu.Lock()
+	lb.inFlight[mappingID]++
+	lb.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			lb.mu.Lock()
+			defer lb.mu.Unlock()
+			if lb.inFlight[mappingID] <= 1 {
+				delete(lb.inFlight, mappingID)
+			} else {
+				lb.inFlight[mappingID]--
+			}
+		})
+	}
+}
+
+// RecordLatency feeds an observed upstream latency into the mapping's moving average.
+func (lb *LoadBalancer) RecordLatency(mappingID uint, latency time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	sample := float64(latency.Milliseconds())
+	if current, ok := lb.latency[mappingID]; ok {
+		lb.latency[mappingID] = current + latencySmoothing*(sample-current)
+	} else {
+		lb.latency[mappingID] = sample
+	}
+}
+
+// failoverOrder keeps the tier in its configured order.
+func failoverOrder(_ string, tier []database.ModelProviderMapping) []database.ModelProviderMapping {
+	return tier
+}
+
+// weightedRandomOrder draws mappings without replacement with probability proportional to weight.
+func (lb *LoadBalancer) weightedRandomOrder(_ string, tier []database.ModelProviderMapping) []database.ModelProviderMapping {
+	return permuteMappings(tier, lb.weightedRandomPermutation(tierWeights(tier)))
+}
+
+// smoothRoundRobinOrder implements smooth weighted round-robin over the tier.
+func (lb *LoadBalancer) smoothRoundRobinOrder(poolKey string, tier []database.ModelProviderMapping) []database.ModelProviderMapping {
+	ids := make([]uint, len(tier))
+	for i, mapping := range tier {
+		ids[i] = mapping.ID
+	}
+	return permuteMappings(tier, lb.smoothRoundRobinPermutation(poolKey, ids, tierWeights(tier)))
+}
+
+// weightedRandomPermutation draws indices without replacement with probability proportional
+// to weight. Zero-weight indices follow in their original order.
+func (lb *LoadBalancer) weightedRandomPermutation(weights []int) []int {
+	remaining := make([]int, len(weights))
+	for i := range weights {
+		remaining[i] = i
+	}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	order := make([]int, 0, len(weights))
+	for len(remaining) > 0 {
+		total := 0
+		for _, i := range remaining {
+			total += weights[i]
+		}
+		pick := 0
+		if total > 0 {
+			target := lb.rand.Intn(total)
+			for n, i := range remaining {
+				if target < weights[i] {
+					pick = n
+					break
+				}
+				target -= weights[i]
+			}
+		}
+		order = append(order, remaining[pick])
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+	}
+	return order
+}
+
+// smoothRoundRobinPermutation implements smooth weighted round-robin (as in nginx): every
+// entry's current weight grows by its weight, the largest is picked and reduced by the total.
+// The remaining indices follow by current weight for failover.
+func (lb *LoadBalancer) smoothRoundRobinPermutation(poolKey string, ids []uint, weights []int) []int {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if len(ids) == 0 {
+		delete(lb.smoothRR, poolKey)
+		return nil
+	}
+	state, ok := lb.smoothRR[poolKey]
+	if !ok {
+		state = make(map[uint]int)
+		lb.smoothRR[poolKey] = state
+	}
+	// Forget entries that left the pool (deleted, or excluded for this request) so their
+	// stale weights do not skew later picks and the state does not grow without bound
+	inPool := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		inPool[id] = true
+	}
+	for id := range state {
+		if !inPool[id] {
+			delete(state, id)
+		}
+	}
+
+	total, best := 0, 0
+	for i, id := range ids {
+		state[id] += weights[i]
+		total += weights[i]
+		if state[id] > state[ids[best]] {
+			best = i
+		}
+	}
+	state[ids[best]] -= total
+
+	order := make([]int, 0, len(ids))
+	for i := range ids {
+		if i != best {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return state[ids[order[i]]] > state[ids[order[j]]]
+	})
+	return append([]int{best}, order...)
+}
+
+func permuteMappings(tier []database.ModelProviderMapping, order []int) []database.ModelProviderMapping {
+	ordered := make([]database.ModelProviderMapping, len(order))
+	for n, i := range order {
+		ordered[n] = tier[i]
+	}
+	return ordered
+}
+
+// leastLatencyOrder prefers the mapping with the lowest observed latency, using the provider's
+// last health check latency until requests have been observed. Unknown latencies go last.
+func (lb *LoadBalancer) leastLatencyOrder(_ string, tier []database.ModelProviderMapping) []database.ModelProviderMapping {
+	lb.mu.Lock()
+	latencies := make(map[uint]float64, len(tier))
+	for _, mapping := range tier {
+		if observed, ok := lb.latency[mapping.ID]; ok {
+			latencies[mapping.ID] = observed
+		} else if mapping.Provider.Latency != nil {
+			latencies[mapping.ID] = float64(*mapping.Provider.Latency)
+		}
+	}
+	lb.mu.Unlock()
+
+	ordered := make([]database.ModelProviderMapping, len(tier))
+	copy(ordered, tier)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		li, iKnown := latencies[ordered[i].ID]
+		lj, jKnown := latencies[ordered[j].ID]
+		if iKnown != jKnown {
+			return iKnown
+		}
+		return li < lj
+	})
+	return ordered
+}
+
+// leastInFlightOrder prefers the mapping with the fewest in-flight requests relative to its
+// weight. Ties are broken by smooth weighted round-robin so idle mappings share load.
+func (lb *LoadBalancer) leastInFlightOrder(poolKey string, tier []database.ModelProviderMapping) []database.ModelProviderMapping {
+	ordered := lb.smoothRoundRobinOrder(poolKey, tier)
+	weights := make(map[uint]int, len(tier))
+	for i, weight := range tierWeights(tier) {
+		weights[tier[i].ID] = weight
+	}
+
+	lb.mu.Lock()
+	loads := make(map[uint]float64, len(ordered))
+	for _, mapping := range ordered {
+		if weights[mapping.ID] == 0 {
+			loads[mapping.ID] = math.Inf(1)
+			continue
+		}
+		loads[mapping.ID] = float64(lb.inFlight[mapping.ID]) / float64(weights[mapping.ID])
+	}
+	lb.mu.Unlock()
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return loads[ordered[i].ID] < loads[ordered[j].ID]
+	})
+	return ordered
+}
+
+// tierWeights combines each mapping's weight with its provider's weight. If every weight in
+// the tier is zero, the mappings share load equally.
+func tierWeights(tier []database.ModelProviderMapping) []int {
+	weights := make([]int, len(tier))
+	total := 0
+	for i, mapping := range tier {
+		if mapping.Weight > 0 && mapping.Provider.Weight > 0 {
+			weights[i] = mapping.Weight * int(mapping.Provider.Weight)
+			total += weights[i]
+		}
+	}
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	return weights
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"llm-fusion-engine/internal/database"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testMapping builds a mapping with its provider's priority and weight.
+func testMapping(id uint, priority int, weight int) database.ModelProviderMapping {
+	return database.ModelProviderMapping{
+		BaseModel: database.BaseModel{ID: id},
+		Weight:    weight,
+		Provider:  database.Provider{Priority: priority, Weight: 1},
+	}
+}
+
+func mappingIDs(mappings []database.ModelProviderMapping) []uint {
+	ids := make([]uint, len(mappings))
+	for i, mapping := range mappings {
+		ids[i] = mapping.ID
+	}
+	return ids
+}
+
+func TestLoadBalancerOrderTiers(t *testing.T) {
+	mappings := []database.ModelProviderMapping{
+		testMapping(1, 0, 1),
+		testMapping(2, 10, 1),
+		testMapping(3, 0, 1),
+		testMapping(4, 10, 1),
+		testMapping(5, 5, 1),
+	}
+	tests := []struct {
+		policy string
+		want   []uint
+	}{
+		// Higher priority tiers come first; failover keeps the configured order within a tier
+		{"failover", []uint{2, 4, 5, 1, 3}},
+		{"unknown-policy", []uint{2, 4, 5, 1, 3}},
+		{"", []uint{2, 4, 5, 1, 3}},
+	}
+	for _, tt := range tests {
+		if got := mappingIDs(NewLoadBalancer().Order(tt.policy, "m", mappings)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Order(%q) = %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+
+	// Whatever the strategy, a tier never mixes with another
+	for _, policy := range []string{"weighted_random", "round_robin", "least_latency", "least_in_flight"} {
+		got := mappingIDs(NewLoadBalancer().Order(policy, "m", mappings))
+		if len(got) != 5 || got[2] != 5 || !(got[0] == 2 || got[0] == 4) || !(got[3] == 1 || got[3] == 3) {
+			t.Errorf("Order(%q) = %v mixes priority tiers", policy, got)
+		}
+	}
+}
+
+func TestLoadBalancerSmoothRoundRobin(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		want    []uint // First pick of consecutive calls
+	}{
+		{"nginx sequence", []int{5, 1, 1}, []uint{1, 1, 2, 1, 3, 1, 1}},
+		{"equal weights", []int{1, 1, 1}, []uint{1, 2, 3, 1, 2, 3}},
+		{"all weights zero share equally", []int{0, 0}, []uint{1, 2, 1, 2}},
+		{"zero weight is never picked", []int{1, 0}, []uint{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLoadBalancer()
+			var tier []database.ModelProviderMapping
+			for i, weight := range tt.weights {
+				tier = append(tier, testMapping(uint(i+1), 0, weight))
+			}
+			var picks []uint
+			for range tt.want {
+				ordered := lb.Order("round_robin", "m", tier)
+				if len(ordered) != len(tier) {
+					t.Fatalf("order has %d mappings, want %d", len(ordered), len(tier))
+				}
+				picks = append(picks, ordered[0].ID)
+			}
+			if !reflect.DeepEqual(picks, tt.want) {
+				t.Errorf("picks %v, want %v", picks, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBalancerSmoothRoundRobinPrunesState(t *testing.T) {
+	lb := NewLoadBalancer()
+	full := []database.ModelProviderMapping{testMapping(1, 0, 1), testMapping(2, 0, 1), testMapping(3, 0, 1)}
+	lb.Order("round_robin", "m", full)
+	lb.Order("round_robin", "m", full[:2])
+
+	lb.mu.Lock()
+	_, stale := lb.smoothRR["m"][3]
+	lb.mu.Unlock()
+	if stale {
+		t.Error("state kept a mapping that left the pool")
+	}
+
+	lb.smoothRoundRobinPermutation("m", nil, nil)
+	lb.mu.Lock()
+	_, kept := lb.smoothRR["m"]
+	lb.mu.Unlock()
+	if kept {
+		t.Error("state kept an empty pool")
+	}
+}
+
+func TestLoadBalancerWeightedRandom(t *testing.T) {
+	lb := NewLoadBalancer()
+	tier := []database.ModelProviderMapping{testMapping(1, 0, 3), testMapping(2, 0, 1), testMapping(3, 0, 0)}
+	first := map[uint]int{}
+	for i := 0; i < 4000; i++ {
+		ordered := lb.Order("weighted_random", "m", tier)
+		if ordered[2].ID != 3 {
+			t.Fatalf("zero-weight mapping not last: %v", mappingIDs(ordered))
+		}
+		first[ordered[0].ID]++
+	}
+	// Expect a 3:1 split between mappings 1 and 2
+	if ratio := float64(first[1]) / float64(first[2]); ratio < 2.5 || ratio > 3.6 {
+		t.Errorf("first picks %v, want about 3:1", first)
+	}
+}
+
+func TestLoadBalancerLeastLatency(t *testing.T) {
+	healthCheck := int64(50)
+	tests := []struct {
+		name     string
+		samples  map[uint][]time.Duration
+		provider map[uint]*int64 // Health check latency
+		want     []uint
+	}{
+		{"no data keeps the order", nil, nil, []uint{1, 2, 3}},
+		{"observed latency", map[uint][]time.Duration{1: {300 * time.Millisecond}, 2: {100 * time.Millisecond}, 3: {200 * time.Millisecond}}, nil, []uint{2, 3, 1}},
+		{"unknown latency goes last", map[uint][]time.Duration{3: {500 * time.Millisecond}}, nil, []uint{3, 1, 2}},
+		{"health check latency until requests are observed", map[uint][]time.Duration{1: {100 * time.Millisecond}}, map[uint]*int64{2: &healthCheck}, []uint{2, 1, 3}},
+		// 100 then 1000 averages to 370 with a smoothing of 0.3
+		{"moving average", map[uint][]time.Duration{1: {100 * time.Millisecond, 1000 * time.Millisecond}, 2: {400 * time.Millisecond}}, nil, []uint{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLoadBalancer()
+			tier := []database.ModelProviderMapping{testMapping(1, 0, 1), testMapping(2, 0, 1), testMapping(3, 0, 1)}
+			for i := range tier {
+				tier[i].Provider.Latency = tt.provider[tier[i].ID]
+			}
+			for id, samples := range tt.samples {
+				for _, sample := range samples {
+					lb.RecordLatency(id, sample)
+				}
+			}
+			if got := mappingIDs(lb.Order("least_latency", "m", tier)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBalancerLeastInFlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		weights  []int
+		inFlight map[uint]int
+		want     uint
+	}{
+		{"fewest requests", []int{1, 1, 1}, map[uint]int{1: 2, 2: 0, 3: 1}, 2},
+		{"relative to weight", []int{4, 1}, map[uint]int{1: 3, 2: 1}, 1},
+		{"busy mapping loses", []int{1, 1}, map[uint]int{1: 1}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLoadBalancer()
+			var tier []database.ModelProviderMapping
+			for i, weight := range tt.weights {
+				tier = append(tier, testMapping(uint(i+1), 0, weight))
+			}
+			var ends []func()
+			for id, n := range tt.inFlight {
+				for i := 0; i < n; i++ {
+					ends = append(ends, lb.BeginRequest(id))
+				}
+			}
+			if got := lb.Order("least_in_flight", "m", tier)[0].ID; got != tt.want {
+				t.Errorf("first pick %d, want %d", got, tt.want)
+			}
+
+			// Ending requests is idempotent and clears their bookkeeping
+			for _, end := range ends {
+				end()
+				end()
+			}
+			lb.mu.Lock()
+			left := len(lb.inFlight)
+			lb.mu.Unlock()
+			if left != 0 {
+				t.Errorf("%d mappings still in flight", left)
+			}
+		})
+	}
+}
+
+func TestLoadBalancerOrderGroups(t *testing.T) {
+	groups := []database.Group{
+		{BaseModel: database.BaseModel{ID: 1}, Priority: 0},
+		{BaseModel: database.BaseModel{ID: 2}, Priority: 5},
+		{BaseModel: database.BaseModel{ID: 3}, Priority: 1},
+	}
+	groupIDs := func(groups []database.Group) []uint {
+		ids := make([]uint, len(groups))
+		for i, group := range groups {
+			ids[i] = group.ID
+		}
+		return ids
+	}
+
+	if got := groupIDs(NewLoadBalancer().OrderGroups("failover", "k", groups, nil)); !reflect.DeepEqual(got, []uint{2, 3, 1}) {
+		t.Errorf("failover order %v, want [2 3 1]", got)
+	}
+
+	lb := NewLoadBalancer()
+	weights := map[uint]int{1: 2, 2: 0, 3: 0}
+	var picks []uint
+	for i := 0; i < 4; i++ {
+		ordered := lb.OrderGroups("round_robin", "k", groups, weights)
+		if len(ordered) != 3 {
+			t.Fatalf("%d groups ordered, want 3", len(ordered))
+		}
+		picks = append(picks, ordered[0].ID)
+	}
+	if !reflect.DeepEqual(picks, []uint{1, 1, 1, 1}) {
+		t.Errorf("round robin picks %v, want only the weighted group", picks)
+	}
+}
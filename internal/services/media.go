@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"llm-fusion-engine/internal/constants"
+	"llm-fusion-engine/internal/core"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// imageGenerationOperation sends an image generation request.
+var imageGenerationOperation = upstreamOperation{
+	send: func(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+		return provider.ImageGeneration(ctx, endpoint, requestBody)
+	},
+	capabilities: []constants.Capability{constants.CapabilityImageGeneration},
+	cost:         imageCost,
+}
+
+// transcriptionOperation uploads audio for transcription.
+var transcriptionOperation = upstreamOperation{
+	send: func(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+		return provider.AudioTranscription(ctx, endpoint, requestBody)
+	},
+	capabilities: []constants.Capability{constants.CapabilityTranscription},
+	cost:         transcriptionCost,
+}
+
+// speechOperation synthesizes speech.
+var speechOperation = upstreamOperation{
+	send: func(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+		return provider.Speech(ctx, endpoint, requestBody)
+	},
+	capabilities: []constants.Capability{constants.CapabilitySpeech},
+	cost:         speechCost,
+}
+
+// ProcessImageGenerationHttpAsync handles an image generation request, routed to the
+// model's mappings that can generate images.
+func (s *MultiProviderService) ProcessImageGenerationHttpAsync(
+	c *gin.Context,
+	requestBody map[string]interface{},
+	proxyKey string,
+) (*http.Response, error) {
+	return s.process(c, imageGenerationOperation, requestBody, proxyKey, false)
+}
+
+// ProcessTranscriptionHttpAsync handles an audio transcription request. The uploaded audio
+// is held in the request body so it can be sent again to another mapping.
+func (s *MultiProviderService) ProcessTranscriptionHttpAsync(
+	c *gin.Context,
+	requestBody map[string]interface{},
+	proxyKey string,
+) (*http.Response, error) {
+	return s.process(c, transcriptionOperation, requestBody, proxyKey, false)
+}
+
+// ProcessSpeechHttpAsync handles a text-to-speech request. The audio is passed to the
+// client as the upstream produces it.
+func (s *MultiProviderService) ProcessSpeechHttpAsync(
+	c *gin.Context,
+	requestBody map[string]interface{},
+	proxyKey string,
+) (*http.Response, error) {
+	return s.process(c, speechOperation, requestBody, proxyKey, false)
+}
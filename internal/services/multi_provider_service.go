@@ -1,16 +1,23 @@
 package services
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/core"
 	"github.com/gin-gonic/gin"
 	"llm-fusion-engine/internal/database"
+	"llm-fusion-engine/internal/providers"
+	"llm-fusion-engine/internal/util"
+	"log"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,184 +28,601 @@ import (
 type MultiProviderService struct {
 	router          core.IProviderRouter
 	providerFactory core.IProviderFactory
+	keyManager      core.IKeyManager
+	loadBalancer    core.ILoadBalancer
+	circuitBreaker  core.ICircuitBreaker
+	transportPool   core.ITransportPool
 	db              *gorm.DB
+	logBodyLimit    int
+	responseCache   core.IResponseCache
+	semanticCache   core.ISemanticCache
 }
 
+// DefaultLogBodyLimit is how many bytes of an upstream response are kept in its log entry.
+const DefaultLogBodyLimit = 64 << 10
+
+// maxErrorBodySize bounds how much of an upstream error response is read to classify it.
+const maxErrorBodySize = 64 << 10
+
 // NewMultiProviderService creates a new MultiProviderService.
-func NewMultiProviderService(router core.IProviderRouter, factory core.IProviderFactory, db *gorm.DB) *MultiProviderService {
+// A nil factory falls back to the built-in provider registry and a nil pool to a private one.
+func NewMultiProviderService(router core.IProviderRouter, factory core.IProviderFactory, keyManager core.IKeyManager, loadBalancer core.ILoadBalancer, circuitBreaker core.ICircuitBreaker, transportPool core.ITransportPool, db *gorm.DB) *MultiProviderService {
+	if factory == nil {
+		factory = providers.DefaultRegistry()
+	}
+	if transportPool == nil {
+		transportPool = NewTransportPool()
+	}
 	return &MultiProviderService{
-		router:         router,
+		router:          router,
 		providerFactory: factory,
-		db:             db,
+		keyManager:      keyManager,
+		loadBalancer:    loadBalancer,
+		circuitBreaker:  circuitBreaker,
+		transportPool:   transportPool,
+		db:              db,
+		logBodyLimit:    DefaultLogBodyLimit,
 	}
 }
 
+// SetLogBodyLimit sets how many bytes of each upstream response are kept for logging.
+// Responses are passed through in full regardless; zero keeps no response bodies.
+func (s *MultiProviderService) SetLogBodyLimit(limit int) {
+	s.logBodyLimit = limit
+}
+
+// SetResponseCache enables answering repeated deterministic requests from the cache, for the
+// proxy keys that have caching enabled. A nil cache disables it.
+func (s *MultiProviderService) SetResponseCache(cache core.IResponseCache) {
+	s.responseCache = cache
+}
+
+// SetSemanticCache enables answering chat prompts that are similar to a cached one, for the
+// models that name a SemanticCacheModel. It only applies to requests the response cache
+// would store; a nil cache disables it.
+func (s *MultiProviderService) SetSemanticCache(cache core.ISemanticCache) {
+	s.semanticCache = cache
+}
+
 // ProcessChatCompletionHttpAsync handles the chat completion request.
 func (s *MultiProviderService) ProcessChatCompletionHttpAsync(
 	c *gin.Context,
 	requestBody map[string]interface{},
 	proxyKey string,
+) (*http.Response, error) {
+	return s.process(c, chatOperation, requestBody, proxyKey, true)
+}
+
+// ProcessCompletionsHttpAsync handles a legacy completion request. Providers without a
+// completions API serve it through chat.
+func (s *MultiProviderService) ProcessCompletionsHttpAsync(
+	c *gin.Context,
+	requestBody map[string]interface{},
+	proxyKey string,
+) (*http.Response, error) {
+	return s.process(c, completionsOperation, requestBody, proxyKey, false)
+}
+
+// ProcessResponsesHttpAsync handles a Responses API request. Providers without a Responses
+// API serve it through chat.
+func (s *MultiProviderService) ProcessResponsesHttpAsync(
+	c *gin.Context,
+	requestBody map[string]interface{},
+	proxyKey string,
+) (*http.Response, error) {
+	return s.process(c, responsesOperation, requestBody, proxyKey, false)
+}
+
+// process routes a request through op and returns the first successful response.
+func (s *MultiProviderService) process(
+	c *gin.Context,
+	op upstreamOperation,
+	requestBody map[string]interface{},
+	proxyKey string,
+	allowStreamFailover bool,
 ) (*http.Response, error) {
 	model, ok := requestBody["model"].(string)
 	if !ok {
 		return nil, errors.New("model not specified in request")
 	}
 
+	cacheKey, cacheTTL := s.responseCacheKey(c, op, requestBody, proxyKey)
+	if cached := s.replayFromCache(c, cacheKey, requestBody, proxyKey, model); cached != nil {
+		return cached, nil
+	}
+	started := time.Now()
+	semantic, similar := s.semanticLookup(c, op, requestBody, proxyKey, model, cacheKey)
+	if similar != nil {
+		return s.replayCached(c, similar, requestBody, proxyKey, model, semantic.similarity, started), nil
+	}
+	stream := isStreamRequest(requestBody)
+
+	// Upstream calls end when the client disconnects or the model's total timeout elapses
+	scope := newRequestScope(c.Request.Context())
 	var excludedProviders []uint
-	var lastErr error
+	resp, _, err := s.dispatch(c, scope, op, requestBody, model, proxyKey, &excludedProviders, allowStreamFailover)
+	if err != nil {
+		scope.finish()
+		return nil, err
+	}
+	// The scope ends once the client has consumed the response
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: func() { s.finishRequest(c, scope, model) }}
+	if semantic != nil {
+		// Recorded on misses too, so that the model's threshold can be tuned from the logs
+		if requestID, exists := c.Get("requestID"); exists {
+			s.db.Model(&database.Log{}).Where("id = ?", requestID).Update("similarity", semantic.similarity)
+		}
+	}
+	s.cacheOnComplete(resp, cacheKey, cacheTTL, stream, semantic)
+	return resp, nil
+}
 
-	for i := 0; i < 5; i++ { // Allow up to 5 retries (initial + 4 retries)
-		// 1. Route the request
-		routeResult, err := s.router.RouteRequestAsync(model, proxyKey, excludedProviders)
-		if err != nil {
-			return nil, err
+// finishRequest ends a request scope, recording a client that went away mid-response.
+func (s *MultiProviderService) finishRequest(c *gin.Context, scope *requestScope, model string) {
+	if scope.err() == core.ErrClientAborted {
+		log.Printf("[MultiProviderService] Client aborted request for model %s after %v", model, time.Since(scope.started).Round(time.Millisecond))
+		if requestID, exists := c.Get("requestID"); exists {
+			s.db.Model(&database.Log{}).Where("id = ?", requestID).Update("client_aborted", true)
+		}
+	}
+	scope.finish()
+}
+
+// upstreamOperation sends one kind of OpenAI-format request through a provider adapter.
+type upstreamOperation struct {
+	send func(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error)
+	// streamOptions marks request formats that accept stream_options, so usage can be
+	// requested from OpenAI-compatible upstreams
+	streamOptions bool
+	// capabilities are required of every mapping the request is routed to
+	capabilities []constants.Capability
+	// cacheScope names the operation in response cache keys; empty keeps its responses out of the cache
+	cacheScope string
+	// deterministic reports whether a request is cached without the client asking for it
+	deterministic func(requestBody map[string]interface{}) bool
+	// cost prices a completed request; token prices apply when it is nil
+	cost costFunc
+}
+
+// requestCost prices a completed request with the model's prices.
+func (op upstreamOperation) requestCost(prices *database.Model, requestBody map[string]interface{}, capture *util.ResponseCapture) float64 {
+	if prices == nil {
+		return 0
+	}
+	if op.cost != nil {
+		return op.cost(prices, requestBody, capture)
+	}
+	return tokenCost(prices, requestBody, capture)
+}
+
+// chatOperation sends a chat completion, streaming when the request asks for it.
+var chatOperation = upstreamOperation{
+	send: func(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+		if isStreamRequest(requestBody) {
+			return provider.StreamChatCompletion(ctx, endpoint, requestBody)
+		}
+		return provider.ChatCompletion(ctx, endpoint, requestBody)
+	},
+	streamOptions: true,
+	cacheScope:    "chat",
+	deterministic: zeroTemperature,
+}
+
+// embeddingsOperation sends an embeddings request.
+var embeddingsOperation = upstreamOperation{
+	send: func(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+		return provider.Embeddings(ctx, endpoint, requestBody)
+	},
+	cacheScope:    "embeddings",
+	deterministic: func(map[string]interface{}) bool { return true },
+}
+
+// completionsOperation sends a legacy completion request.
+var completionsOperation = upstreamOperation{
+	send: func(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+		return provider.Completions(ctx, endpoint, requestBody)
+	},
+	streamOptions: true,
+}
+
+// responsesOperation sends a Responses API request.
+var responsesOperation = upstreamOperation{
+	send: func(ctx context.Context, provider core.IProvider, endpoint *core.ProviderEndpoint, requestBody map[string]interface{}) (*http.Response, error) {
+		return provider.Responses(ctx, endpoint, requestBody)
+	},
+}
+
+// dispatch routes the request and tries mappings until one of them answers through op.
+// Every provider that is tried is added to excludedProviders. Failed attempts are retried as
+// the model's and provider's retry policy allows, on the same mapping or the next one. With
+// allowStreamFailover set, a successful stream is wrapped so that it can move to the next
+// mapping if it breaks.
+func (s *MultiProviderService) dispatch(
+	c *gin.Context,
+	scope *requestScope,
+	op upstreamOperation,
+	requestBody map[string]interface{},
+	model string,
+	proxyKey string,
+	excludedProviders *[]uint,
+	allowStreamFailover bool,
+) (*http.Response, *core.ProviderRouteResult, error) {
+	var lastErr error
+	estimatedTokens := util.EstimateRequestTokens(requestBody)
+	maxAttempts := defaultMaxAttempts
+	var retry *core.ProviderRouteResult // Mapping to try again instead of routing anew
+
+	for attempts := 0; attempts < maxAttempts; {
+		// 1. Route the request, unless the last mapping is retried and its circuit still allows it
+		routeResult := retry
+		retry = nil
+		if routeResult != nil && !s.circuitBreaker.Allow(routeResult.Provider.ID, routeResult.MappingID) {
+			routeResult = nil
+		}
+		if routeResult == nil {
+			routed, err := s.router.RouteRequestAsync(scope.ctx, model, proxyKey, *excludedProviders, estimatedTokens, requestBody, op.capabilities)
+			if err != nil {
+				if abortErr := scope.err(); abortErr != nil {
+					return nil, nil, abortErr
+				}
+				if lastErr != nil {
+					// Every remaining provider has been tried; report the last upstream failure
+					break
+				}
+				return nil, nil, err
+			}
+			if routed.Provider == nil {
+				return nil, nil, errors.New("no provider found in route result")
+			}
+			// Exclude this provider from future routing in this request
+			*excludedProviders = append(*excludedProviders, routed.Provider.ID)
+			routeResult = routed
 		}
 
 		// 2. Get the provider and prepare the request
 		provider := routeResult.Provider
-		if provider == nil {
-			return nil, errors.New("no provider found in route result")
-		}
 
-		// Exclude this provider from future retries in this request
-		excludedProviders = append(excludedProviders, provider.ID)
+		// The model's timeout bounds the wait for a response, retries included, but not the body
+		modelRecord := s.findModel(routeResult.Model)
+		if modelRecord != nil {
+			scope.armDeadline(time.Duration(modelRecord.Timeout) * time.Second)
+		}
 
 		var config map[string]interface{}
 		if err := json.Unmarshal([]byte(provider.Config), &config); err != nil {
 			lastErr = fmt.Errorf("failed to parse config for provider %s: %w", provider.Name, err)
+			s.keyManager.ReconcileKeyUsage(routeResult.KeyReservation, 0)
+			s.circuitBreaker.Release(provider.ID, routeResult.MappingID)
 			continue
 		}
 
+		policy := resolveRetryPolicy(modelRecord, config)
+		maxAttempts = policy.maxAttempts
+
 		baseUrl, _ := config["baseUrl"].(string)
 		apiKey := routeResult.ApiKey
-		if configKey, ok := config["apiKey"].(string); ok && configKey != "" {
-			apiKey = configKey
-		}
 
 		requestBody["model"] = routeResult.ResolvedModel
 		requestBody = cleanupUndefined(requestBody).(map[string]interface{})
 
-		apiEndpoint, err := getRequestURL(provider.Type, baseUrl)
+		providerImpl, err := s.providerFactory.GetProvider(provider.Type)
 		if err != nil {
 			lastErr = err
+			s.keyManager.ReconcileKeyUsage(routeResult.KeyReservation, 0)
+			s.circuitBreaker.Release(provider.ID, routeResult.MappingID)
 			continue
 		}
-
-		jsonBody, err := json.Marshal(requestBody)
+		// Deadlines are enforced through the request context rather than a client timeout
+		client, err := s.transportPool.Client(provider, config)
 		if err != nil {
-			return nil, err
+			lastErr = err
+			s.keyManager.ReconcileKeyUsage(routeResult.KeyReservation, 0)
+			s.circuitBreaker.Release(provider.ID, routeResult.MappingID)
+			continue
+		}
+		endpoint := &core.ProviderEndpoint{
+			BaseURL: baseUrl,
+			ApiKey:  apiKey,
+			Config:  config,
+			Client:  client,
 		}
 
-		// 3. Execute the request and handle retries
+		// 3. Execute the request and handle retries. The attempt is cancelled if the upstream
+		// does not start responding within the provider's first byte timeout.
+		attemptCtx, cancelAttempt := context.WithCancelCause(scope.ctx)
+		var firstByteTimer *time.Timer
+		if timeout := firstByteTimeout(provider, config); timeout > 0 {
+			firstByteTimer = time.AfterFunc(timeout, func() { cancelAttempt(errFirstByteTimeout) })
+		}
+		endRequest := s.loadBalancer.BeginRequest(routeResult.MappingID)
 		startTime := time.Now()
-		req, err := http.NewRequest("POST", apiEndpoint, bytes.NewBuffer(jsonBody))
-		if err != nil {
-			return nil, err
+		upstreamBody, injectedStreamUsage := requestBody, false
+		if op.streamOptions {
+			upstreamBody, injectedStreamUsage = withStreamUsage(requestBody, providerImpl, config)
+		}
+		resp, err := op.send(attemptCtx, providerImpl, endpoint, upstreamBody)
+		if firstByteTimer != nil {
+			firstByteTimer.Stop()
 		}
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+apiKey)
-
-		client := &http.Client{Timeout: time.Duration(provider.Timeout) * time.Second}
-		resp, err := client.Do(req)
 		latency := time.Since(startTime)
-
+		apiEndpoint := upstreamURL(resp, err, baseUrl)
+
+		if errors.Is(err, core.ErrOperationNotSupported) {
+			// The provider type cannot serve this kind of request; try another mapping
+			endRequest()
+			cancelAttempt(nil)
+			lastErr = fmt.Errorf("provider %s: %w", provider.Name, err)
+			s.keyManager.ReconcileKeyUsage(routeResult.KeyReservation, 0)
+			s.circuitBreaker.Release(provider.ID, routeResult.MappingID)
+			continue
+		}
+		var invalid *core.InvalidRequestError
+		if errors.As(err, &invalid) {
+			// The request itself is malformed; no provider would accept it
+			endRequest()
+			cancelAttempt(nil)
+			s.keyManager.ReconcileKeyUsage(routeResult.KeyReservation, 0)
+			s.circuitBreaker.Release(provider.ID, routeResult.MappingID)
+			return nil, nil, err
+		}
+		attempts++
 		if err != nil {
+			endRequest()
+			firstByteExpired := context.Cause(attemptCtx) == errFirstByteTimeout
+			cancelAttempt(nil)
 			lastErr = err
+			s.keyManager.ReconcileKeyUsage(routeResult.KeyReservation, 0)
+			abortErr := scope.err()
+			if abortErr == core.ErrClientAborted {
+				// The client went away; that says nothing about the provider
+				s.circuitBreaker.Release(provider.ID, routeResult.MappingID)
+			} else {
+				s.circuitBreaker.Record(provider.ID, routeResult.MappingID, false)
+			}
 			// Create a unique request ID for logging
 			requestID := uuid.New().String()
 			c.Set("requestID", requestID) // Store it in context for later use
-			s.LogRequest(requestID, requestBody, proxyKey, provider.Name, apiEndpoint, nil, false, latency, 0, 0, 0)
-			continue // Retry with the next provider
+			s.logAttempt(scope, requestID, requestBody, proxyKey, model, routeResult.Model, provider.Name, apiEndpoint, nil, false, latency)
+			if abortErr != nil {
+				log.Printf("[MultiProviderService] Request for model %s ended on provider %s after %v: %v",
+					model, provider.Name, time.Since(scope.started).Round(time.Millisecond), abortErr)
+				if abortErr == core.ErrClientAborted {
+					s.db.Model(&database.Log{}).Where("id = ?", requestID).Update("client_aborted", true)
+				}
+				return nil, nil, abortErr
+			}
+			if firstByteExpired {
+				lastErr = fmt.Errorf("provider %s: %w", provider.Name, errFirstByteTimeout)
+			}
+			if !policy.retriesError(err, firstByteExpired) {
+				return nil, nil, fmt.Errorf("provider %s failed with a non-retriable error: %w", provider.Name, lastErr)
+			}
+			if retry, err = s.sameProviderRetry(scope, policy, routeResult, attempts, model); err != nil {
+				return nil, nil, err
+			}
+			continue // Retry the same mapping or fail over to the next one
 		}
 
-		// For logging, we need to read the body and then replace it.
-		// This logic is now centralized within the LogRequest function.
-		// We pass a placeholder for token usage for now, which will be updated.
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			// We will parse the body for tokens later
+			// The body streams through to the client; its log entry is completed from the
+			// capture once the body has been consumed
 			requestID := uuid.New().String()
 			c.Set("requestID", requestID)
-			s.LogRequest(requestID, requestBody, proxyKey, provider.Name, apiEndpoint, resp, true, latency, 0, 0, 0)
-			return resp, nil // Success
+			keyReservation := routeResult.KeyReservation
+			logBodyLimit := s.logBodyLimit
+			if !isTextContent(resp.Header.Get("Content-Type")) {
+				// Binary bodies such as synthesized audio are not kept in the log
+				logBodyLimit = 0
+			}
+			capture := util.NewResponseCapture(resp.Body, isStreamRequest(requestBody), logBodyLimit, func(capture *util.ResponseCapture) {
+				s.completeLog(requestID, capture, op.requestCost(modelRecord, requestBody, capture))
+				if usage := capture.Usage(); usage.TotalTokens > 0 {
+					s.keyManager.ReconcileKeyUsage(keyReservation, usage.TotalTokens)
+				}
+			})
+			// Counted locally in case the upstream reports no usage
+			capture.EstimateUsage(util.CountPromptTokens(requestBody, routeResult.ResolvedModel), util.TokenizerForModel(routeResult.ResolvedModel))
+			addResponseCapture(c, capture)
+			resp.Body = capture
+			if injectedStreamUsage {
+				resp.Body = newUsageChunkFilter(resp.Body)
+			}
+			if allowStreamFailover && isStreamRequest(requestBody) {
+				if mode := streamFailoverMode(modelRecord); mode != constants.StreamFailoverOff {
+					resp.Body = newFailoverStream(resp.Body, mode, s.streamReopener(c, scope, requestBody, model, proxyKey, excludedProviders, routeResult, mode))
+				}
+			}
+			s.logAttempt(scope, requestID, requestBody, proxyKey, model, routeResult.Model, provider.Name, apiEndpoint, resp, true, latency)
+			s.loadBalancer.RecordLatency(routeResult.MappingID, latency)
+			s.circuitBreaker.Record(provider.ID, routeResult.MappingID, true)
+			// The request stays in flight until the client has consumed the response
+			resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: func() {
+				endRequest()
+				cancelAttempt(nil)
+			}}
+			// A long generation may stream for as long as the client reads it
+			scope.stopDeadline()
+			return resp, routeResult, nil // Success
 		}
 
 		// Handle non-2xx responses
 		requestID := uuid.New().String()
 		c.Set("requestID", requestID)
-		s.LogRequest(requestID, requestBody, proxyKey, provider.Name, apiEndpoint, resp, false, latency, 0, 0, 0)
-		// The original response body is closed within LogRequest, so we don't do it here.
-
-		// Decide if we should retry
-		shouldRetry := false
-		if policy, ok := config["retryPolicy"].(map[string]interface{}); ok {
-			if codes, ok := policy["statusCodes"].([]interface{}); ok {
-				for _, code := range codes {
-					if int(code.(float64)) == resp.StatusCode {
-						shouldRetry = true
-						break
-					}
-				}
-			}
-		} else {
-			// Default retry policy for common transient errors
-			defaultRetryCodes := []int{429, 500, 502, 503, 504}
-			for _, code := range defaultRetryCodes {
-				if code == resp.StatusCode {
-					shouldRetry = true
-					break
-				}
+		s.logAttempt(scope, requestID, requestBody, proxyKey, model, routeResult.Model, provider.Name, apiEndpoint, resp, false, latency)
+		errorBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		resp.Body.Close()
+		endRequest()
+		cancelAttempt(nil)
+		s.keyManager.ReconcileKeyUsage(routeResult.KeyReservation, 0)
+
+		// A failure caused by the API key takes that key out of rotation; the provider
+		// stays eligible so the next attempt can use one of its other keys.
+		if routeResult.ApiKeyID != 0 {
+			if s.keyManager.ReportKeyFailure(routeResult.ApiKeyID, resp.StatusCode, resp.Header, errorBody) {
+				// The key, not the provider, was at fault
+				s.circuitBreaker.Release(provider.ID, routeResult.MappingID)
+				*excludedProviders = (*excludedProviders)[:len(*excludedProviders)-1]
+				lastErr = fmt.Errorf("provider %s rejected API key with status %d", provider.Name, resp.StatusCode)
+				continue
 			}
 		}
 
-		if !shouldRetry {
-			return nil, fmt.Errorf("provider %s returned non-retriable status code %d", provider.Name, resp.StatusCode)
+		s.circuitBreaker.Record(provider.ID, routeResult.MappingID, !isProviderFailure(resp.StatusCode))
+
+		if !policy.retriesStatus(resp.StatusCode) {
+			return nil, nil, fmt.Errorf("provider %s returned non-retriable status code %d", provider.Name, resp.StatusCode)
 		}
 
 		lastErr = fmt.Errorf("provider %s failed with status %d", provider.Name, resp.StatusCode)
-		// Loop will continue to the next provider
+		routeResult.RetryAfter = parseRetryAfter(resp.Header)
+		if retry, err = s.sameProviderRetry(scope, policy, routeResult, attempts, model); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return nil, nil, fmt.Errorf("all retries failed. last error: %w", lastErr)
+}
+
+// sameProviderRetry waits for the policy's backoff and returns the route to try again, or nil
+// when the request should fail over to the next mapping instead. It returns the scope's error
+// if the request ends during the wait.
+func (s *MultiProviderService) sameProviderRetry(scope *requestScope, policy retryPolicy, routeResult *core.ProviderRouteResult, attempts int, model string) (*core.ProviderRouteResult, error) {
+	if attempts >= policy.maxAttempts {
+		return nil, nil
+	}
+	delay, ok := policy.sameProviderDelay(routeResult.RetryCount, routeResult.RetryAfter)
+	if !ok {
+		return nil, nil
 	}
+	log.Printf("[MultiProviderService] Retrying provider %s for model %s in %v (retry %d of %d on this provider)",
+		routeResult.Provider.Name, model, delay.Round(time.Millisecond), routeResult.RetryCount+1, policy.sameProviderRetries)
+	if !scope.sleep(delay) {
+		return nil, scope.err()
+	}
+	routeResult.RetryCount++
+	routeResult.RetryAfter = 0
+	// The key's usage was reconciled after the failed attempt; the retry is not charged again
+	routeResult.KeyReservation = nil
+	return routeResult, nil
+}
 
-	return nil, fmt.Errorf("all retries failed. last error: %w", lastErr)
+// findModel loads the model a request was routed as, or nil if it cannot be read.
+func (s *MultiProviderService) findModel(name string) *database.Model {
+	var model database.Model
+	if err := s.db.Where("name = ?", name).First(&model).Error; err != nil {
+		return nil
+	}
+	return &model
 }
 
-// getRequestURL acts as an adapter to get the correct API endpoint for different provider types.
-func getRequestURL(providerType, baseUrl string) (string, error) {
-	if baseUrl == "" {
-		return "", errors.New("baseUrl is not configured for the provider")
+// firstByteTimeout returns how long an attempt may wait for the upstream to start responding:
+// the provider config's "firstByteTimeout" in seconds, or else the provider's Timeout.
+func firstByteTimeout(provider *database.Provider, config map[string]interface{}) time.Duration {
+	if seconds, ok := config["firstByteTimeout"].(float64); ok && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
 	}
+	return time.Duration(provider.Timeout) * time.Second
+}
 
-	// Normalize base URL
-	baseUrl = strings.TrimSuffix(baseUrl, "/")
-
-	// Determine the standard suffix for the given provider type
-	var fullPath string
-	var pathAfterV1 string
-
-	switch strings.ToLower(providerType) {
-	case "anthropic":
-		fullPath = "/v1/messages"
-		pathAfterV1 = "/messages"
-	case "gemini":
-		fullPath = "/v1beta/models/gemini-pro:generateContent"
-		// Gemini is special, doesn't follow /v1 pattern, so just append
-		return baseUrl + fullPath, nil
-	default:
-		fullPath = "/v1/chat/completions"
-		pathAfterV1 = "/chat/completions"
+// streamFailoverMode returns the model's StreamFailover setting, defaulting to off.
+func streamFailoverMode(model *database.Model) constants.StreamFailoverMode {
+	if model == nil {
+		return constants.StreamFailoverOff
+	}
+	if mode := constants.StreamFailoverMode(model.StreamFailover); mode.IsValid() {
+		return mode
 	}
+	return constants.StreamFailoverOff
+}
 
-	// Case 1: URL is already complete
-	if strings.HasSuffix(baseUrl, fullPath) {
-		return baseUrl, nil
+// streamReopener returns the callback a failoverStream uses to continue on the next mapping.
+// The broken mapping counts as a failure for its circuit breaker and stays excluded.
+func (s *MultiProviderService) streamReopener(
+	c *gin.Context,
+	scope *requestScope,
+	requestBody map[string]interface{},
+	model string,
+	proxyKey string,
+	excludedProviders *[]uint,
+	routeResult *core.ProviderRouteResult,
+	mode constants.StreamFailoverMode,
+) func(prefix string, cause error) (io.ReadCloser, error) {
+	current := routeResult
+	return func(prefix string, cause error) (io.ReadCloser, error) {
+		if abortErr := scope.err(); abortErr != nil {
+			// The stream was cut by the client or the deadline, not by the provider
+			return nil, abortErr
+		}
+		s.circuitBreaker.Record(current.Provider.ID, current.MappingID, false)
+		log.Printf("[MultiProviderService] Stream from provider %s for model %s broke before completion (%v), failing over (mode=%s, resumed chars=%d)",
+			current.Provider.Name, model, cause, mode, len(prefix))
+
+		// The model's timeout bounds the wait for the next mapping as it did for the first
+		scope.rearmDeadline()
+		retryBody := requestBody
+		if prefix != "" {
+			retryBody = withAssistantPrefix(requestBody, prefix)
+		}
+		resp, next, err := s.dispatch(c, scope, chatOperation, retryBody, model, proxyKey, excludedProviders, false)
+		if err != nil {
+			log.Printf("[MultiProviderService] Stream failover for model %s failed: %v", model, err)
+			return nil, err
+		}
+		log.Printf("[MultiProviderService] Stream for model %s continues on provider %s", model, next.Provider.Name)
+		current = next
+		return resp.Body, nil
 	}
+}
+
+// isProviderFailure reports whether an upstream status means the provider could not serve the
+// request, as opposed to a client error such as a malformed request.
+func isProviderFailure(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests ||
+		statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+}
 
-	// Case 2: URL ends with /v1
-	if strings.HasSuffix(baseUrl, "/v1") {
-		return baseUrl + pathAfterV1, nil
+// releaseOnClose runs release once when the wrapped body is first closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (r *releaseOnClose) Close() error {
+	defer r.once.Do(r.release)
+	return r.ReadCloser.Close()
+}
+
+// upstreamURL returns the URL an upstream call was sent to, for logging.
+func upstreamURL(resp *http.Response, err error, baseUrl string) string {
+	if resp != nil && resp.Request != nil {
+		return resp.Request.URL.String()
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.URL
 	}
+	return baseUrl
+}
 
-	// Case 3: Bare domain or other path
-	return baseUrl + fullPath, nil
+// isTextContent reports whether a response content type is text that is worth logging.
+// Responses without a content type are assumed to be JSON.
+func isTextContent(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "" || strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") || strings.Contains(contentType, "xml")
+}
+
+// zeroTemperature reports whether a request samples with temperature 0.
+func zeroTemperature(requestBody map[string]interface{}) bool {
+	temperature, ok := requestBody["temperature"].(float64)
+	return ok && temperature == 0
+}
+
+// isStreamRequest reports whether the client asked for a streaming response.
+func isStreamRequest(requestBody map[string]interface{}) bool {
+	stream, ok := requestBody["stream"].(bool)
+	return ok && stream
 }
 
 // LogRequest logs the details of an API request and its response.
@@ -206,6 +630,8 @@ func (s *MultiProviderService) LogRequest(
 	requestID string,
 	requestBody map[string]interface{},
 	proxyKey string,
+	requestedModel string,
+	resolvedModel string,
 	providerName string,
 	requestUrl string,
 	response *http.Response,
@@ -215,22 +641,64 @@ func (s *MultiProviderService) LogRequest(
 	completionTokens int,
 	totalTokens int,
 ) {
+	logEntry := s.newLogEntry(requestID, requestBody, proxyKey, requestedModel, resolvedModel, providerName, requestUrl, response, isSuccess, latency, promptTokens, completionTokens, totalTokens)
+	s.db.Create(&logEntry)
+}
+
+// logAttempt logs an upstream attempt, recording it under the first attempt of its request.
+func (s *MultiProviderService) logAttempt(
+	scope *requestScope,
+	requestID string,
+	requestBody map[string]interface{},
+	proxyKey string,
+	requestedModel string,
+	resolvedModel string,
+	providerName string,
+	requestUrl string,
+	response *http.Response,
+	isSuccess bool,
+	latency time.Duration,
+) {
+	logEntry := s.newLogEntry(requestID, requestBody, proxyKey, requestedModel, resolvedModel, providerName, requestUrl, response, isSuccess, latency, 0, 0, 0)
+	logEntry.ParentID, logEntry.Attempt = scope.nextAttempt(requestID)
+	s.db.Create(&logEntry)
+}
+
+// newLogEntry builds the log entry of an API request and its response.
+func (s *MultiProviderService) newLogEntry(
+	requestID string,
+	requestBody map[string]interface{},
+	proxyKey string,
+	requestedModel string,
+	resolvedModel string,
+	providerName string,
+	requestUrl string,
+	response *http.Response,
+	isSuccess bool,
+	latency time.Duration,
+	promptTokens int,
+	completionTokens int,
+	totalTokens int,
+) database.Log {
 	reqBodyBytes, _ := json.Marshal(requestBody)
 	var respBodyBytes []byte
 	var status int
 
 	if response != nil {
 		status = response.StatusCode
-		// Read and then replace the body to allow it to be read again
-		respBodyBytes, _ = ioutil.ReadAll(response.Body)
-		response.Body.Close() // Close the original body
-		response.Body = ioutil.NopCloser(bytes.NewBuffer(respBodyBytes))
+		// A successful body is recorded by its capture as it streams to the client; an error
+		// body is peeked up to the log limit and left readable for the caller
+		if !isSuccess {
+			respBodyBytes, response.Body = util.PeekBody(response.Body, s.logBodyLimit)
+		}
 	}
 
 	logEntry := database.Log{
 		ID:               requestID,
 		ProxyKey:         proxyKey,
 		Model:            requestBody["model"].(string),
+		RequestedModel:   requestedModel,
+		ResolvedModel:    resolvedModel,
 		Provider:         providerName,
 		RequestURL:       requestUrl,
 		RequestBody:      string(reqBodyBytes),
@@ -243,8 +711,33 @@ func (s *MultiProviderService) LogRequest(
 		CompletionTokens: completionTokens,
 		TotalTokens:      totalTokens,
 	}
+	return logEntry
+}
 
-	s.db.Create(&logEntry)
+// addResponseCapture records a capture on the request context.
+func addResponseCapture(c *gin.Context, capture *util.ResponseCapture) {
+	var captures []*util.ResponseCapture
+	if existing, exists := c.Get(core.ResponseCapturesKey); exists {
+		captures, _ = existing.([]*util.ResponseCapture)
+	}
+	c.Set(core.ResponseCapturesKey, append(captures, capture))
+}
+
+// completeLog records what a capture saw of a response body, and the resulting cost, in the
+// response's log entry.
+func (s *MultiProviderService) completeLog(requestID string, capture *util.ResponseCapture, cost float64) {
+	usage := capture.Usage()
+	s.db.Model(&database.Log{}).Where("id = ?", requestID).Updates(map[string]interface{}{
+		"response_body":      string(capture.Body()),
+		"response_truncated": capture.Truncated(),
+		"response_size":      capture.Size(),
+		"finish_reason":      strings.Join(capture.FinishReasons(), ","),
+		"prompt_tokens":      usage.PromptTokens,
+		"completion_tokens":  usage.CompletionTokens,
+		"total_tokens":       usage.TotalTokens,
+		"estimated":          capture.UsageEstimated(),
+		"cost":               cost,
+	})
 }
 
 // cleanupUndefined recursively removes keys with "[undefined]" string values from a map.
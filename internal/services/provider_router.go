@@ -1,86 +1,584 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"llm-fusion-engine/internal/constants"
 	"llm-fusion-engine/internal/core"
 	"llm-fusion-engine/internal/database"
-	"sort"
+	"llm-fusion-engine/internal/util"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
 // ProviderRouter implements the IProviderRouter interface.
 type ProviderRouter struct {
-	db         *gorm.DB
-	keyManager core.IKeyManager
+	db                *gorm.DB
+	keyManager        core.IKeyManager
+	loadBalancer      core.ILoadBalancer
+	circuitBreaker    core.ICircuitBreaker
+	unhealthyFallback bool
 }
 
 // NewProviderRouter creates a new ProviderRouter.
-func NewProviderRouter(db *gorm.DB, keyManager core.IKeyManager) *ProviderRouter {
+func NewProviderRouter(db *gorm.DB, keyManager core.IKeyManager, loadBalancer core.ILoadBalancer, circuitBreaker core.ICircuitBreaker) *ProviderRouter {
 	return &ProviderRouter{
-		db:         db,
-		keyManager: keyManager,
+		db:                db,
+		keyManager:        keyManager,
+		loadBalancer:      loadBalancer,
+		circuitBreaker:    circuitBreaker,
+		unhealthyFallback: true,
 	}
 }
 
 // RouteRequestAsync selects a provider based on model mappings and performs failover.
-func (r *ProviderRouter) RouteRequestAsync(model, proxyKey string, excludedProviders []uint) (*core.ProviderRouteResult, error) {
+// Groups are tried first in the order of the proxy key's group balance policy, then the
+// providers inside the chosen group. The requested name is rewritten through the group's
+// model aliases, or the global ModelAlias table, before the model is looked up.
+// Disabled models, mappings and providers are never used. Degraded providers are only tried
+// after healthy ones, and unhealthy providers only when nothing else is left and the
+// unhealthy fallback is enabled. Mappings without one of the required capabilities, or of those
+// the request body needs, are skipped; when no mapping has them the request is rejected with 400.
+func (r *ProviderRouter) RouteRequestAsync(ctx context.Context, model, proxyKey string, excludedProviders []uint, estimatedTokens int, requestBody map[string]interface{}, required []constants.Capability) (*core.ProviderRouteResult, error) {
+	required = append(requestCapabilities(requestBody), required...)
+
 	// 1. Validate proxy key
-	_, err := r.keyManager.ValidateProxyKeyAsync(proxyKey)
+	key, err := r.keyManager.ValidateProxyKeyAsync(proxyKey)
 	if err != nil {
 		return nil, errors.New("invalid proxy key")
 	}
+	// Lookups stop when the client goes away
+	db := r.db.WithContext(ctx)
 
-	// 2. Find all candidate providers via ModelProviderMapping
-	var mappings []database.ModelProviderMapping
-	query := r.db.Joins("JOIN models ON models.id = model_provider_mappings.model_id").
-		Where("models.name = ?", model).
-		Preload("Provider")
+	// 2. Determine the groups the proxy key may reach the model through, ordered by the key's
+	// group balance policy. Each group resolves the requested name through its model aliases.
+	groups, err := r.routeGroups(db, key, model)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, &core.RouteError{
+			Status:  http.StatusNotFound,
+			Code:    "model_not_found",
+			Message: fmt.Sprintf("The model `%s` is not available through the groups allowed for this key", model),
+		}
+	}
+
+	// 3. Try the groups in order, selecting a provider inside each one. A provider that belongs
+	// to several groups is only considered once.
+	excluded := make(map[uint]bool, len(excludedProviders))
+	for _, id := range excludedProviders {
+		excluded[id] = true
+	}
+	models := make(map[string]*routeModel)
+	considered := make(map[uint]bool)
+	var skips routeSkips
+	var modelErr error
+	for _, rg := range groups {
+		target, err := r.loadRouteModel(db, models, model, rg.model)
+		if err != nil {
+			var routeErr *core.RouteError
+			if !errors.As(err, &routeErr) {
+				return nil, err
+			}
+			if modelErr == nil {
+				modelErr = err
+			}
+			continue
+		}
+
+		var groupMappings []database.ModelProviderMapping
+		for _, mapping := range target.mappings {
+			if rg.providers[mapping.ProviderID] && !considered[mapping.ID] {
+				considered[mapping.ID] = true
+				groupMappings = append(groupMappings, mapping)
+			}
+		}
+		if len(groupMappings) == 0 {
+			continue
+		}
+
+		// The proxy key's policy overrides the model's, which overrides the group's
+		policy, poolKey := target.record.LoadBalancePolicy, target.record.Name
+		if rg.group != nil {
+			if policy == "" {
+				policy = rg.group.LoadBalancePolicy
+			}
+			poolKey = fmt.Sprintf("%s@group:%d", target.record.Name, rg.group.ID)
+		}
+		if key.LoadBalancePolicy != "" {
+			policy = key.LoadBalancePolicy
+		}
+
+		if result := r.selectProvider(target.record.Name, policy, poolKey, groupMappings, excluded, estimatedTokens, required, &skips); result != nil {
+			result.Group = rg.group
+			result.Model = target.record.Name
+			return result, nil
+		}
+	}
+
+	// 4. If no group resolved the name to a usable model, report why
+	loaded := 0
+	for _, target := range models {
+		if target != nil {
+			loaded++
+			for _, mapping := range target.mappings {
+				if !considered[mapping.ID] {
+					skips.outsideGroups++
+				}
+			}
+		}
+	}
+	if loaded == 0 && modelErr != nil {
+		return nil, modelErr
+	}
+	if len(required) > 0 && len(considered) > 0 && skips.capable == 0 {
+		// Waiting for providers to recover would not help: none of them can serve the request
+		return nil, &core.RouteError{
+			Status:  http.StatusBadRequest,
+			Code:    "unsupported_capability",
+			Message: fmt.Sprintf("No provider of model `%s` supports this request; it requires %s", model, joinCapabilities(required)),
+		}
+	}
+
+	// 5. If the loop completes, no provider mapped to the model can take the request
+	return nil, &core.RouteError{
+		Status:  http.StatusServiceUnavailable,
+		Code:    "no_available_provider",
+		Message: fmt.Sprintf("No provider is available for model `%s`: %s", model, skips),
+	}
+}
+
+// routeModel is a model record together with its provider mappings.
+type routeModel struct {
+	record   database.Model
+	mappings []database.ModelProviderMapping
+}
 
-	if len(excludedProviders) > 0 {
-		query = query.Where("provider_id NOT IN ?", excludedProviders)
+// loadRouteModel loads the model a requested name resolved to, caching the result (nil for
+// unusable models) in cache. Unknown, disabled and unmapped models yield a 404 RouteError.
+func (r *ProviderRouter) loadRouteModel(db *gorm.DB, cache map[string]*routeModel, requested, name string) (*routeModel, error) {
+	label := fmt.Sprintf("`%s`", name)
+	if name != requested {
+		label = fmt.Sprintf("`%s` (alias `%s`)", name, requested)
 	}
+	if target, ok := cache[name]; ok {
+		if target == nil {
+			return nil, &core.RouteError{Status: http.StatusNotFound, Code: "model_not_found", Message: fmt.Sprintf("The model %s is not available", label)}
+		}
+		return target, nil
+	}
+	cache[name] = nil
 
-	if err := query.Find(&mappings).Error; err != nil || len(mappings) == 0 {
-		return nil, errors.New("no provider mapping found for the given model")
+	var modelRecord database.Model
+	if err := db.Where("name = ?", name).First(&modelRecord).Error; err != nil {
+		return nil, &core.RouteError{
+			Status:  http.StatusNotFound,
+			Code:    "model_not_found",
+			Message: fmt.Sprintf("The model %s does not exist", label),
+		}
+	}
+	if !modelRecord.Enabled {
+		return nil, &core.RouteError{
+			Status:  http.StatusNotFound,
+			Code:    "model_not_found",
+			Message: fmt.Sprintf("The model %s is disabled", label),
+		}
 	}
 
-	// 3. Sort providers by priority (for failover)
-	sort.Slice(mappings, func(i, j int) bool {
-		// Higher priority value means it comes first
-		return mappings[i].Provider.Priority > mappings[j].Provider.Priority
-	})
+	var mappings []database.ModelProviderMapping
+	if err := db.Where("model_id = ?", modelRecord.ID).Order("id").Preload("Provider").Find(&mappings).Error; err != nil {
+		delete(cache, name)
+		return nil, err
+	}
+	if len(mappings) == 0 {
+		return nil, &core.RouteError{
+			Status:  http.StatusNotFound,
+			Code:    "model_not_found",
+			Message: fmt.Sprintf("The model %s has no provider configured", label),
+		}
+	}
 
-	// 4. Iterate through sorted providers and try to get a key from the provider's config.
+	target := &routeModel{record: modelRecord, mappings: mappings}
+	cache[name] = target
+	return target, nil
+}
+
+// selectProvider picks a mapping and an API key among the given mappings, or returns nil and
+// records in skips why every mapping was passed over.
+func (r *ProviderRouter) selectProvider(model, policy, poolKey string, mappings []database.ModelProviderMapping, excluded map[uint]bool, estimatedTokens int, required []constants.Capability, skips *routeSkips) *core.ProviderRouteResult {
+	// Drop unusable mappings and split the rest by provider health
+	var available, degraded, unhealthy []database.ModelProviderMapping
 	for _, mapping := range mappings {
+		supported := mappingSupports(&mapping, required)
+		if supported {
+			skips.capable++
+		}
+		switch {
+		case excluded[mapping.ProviderID]:
+			skips.failed++
+		case !mapping.Enabled:
+			skips.mappingDisabled++
+		case !mapping.Provider.Enabled:
+			skips.providerDisabled++
+		case !supported:
+			skips.incapable++
+		case mapping.Provider.HealthStatus == string(constants.HealthStatusUnhealthy):
+			unhealthy = append(unhealthy, mapping)
+		case mapping.Provider.HealthStatus == string(constants.HealthStatusDegraded):
+			degraded = append(degraded, mapping)
+		default:
+			available = append(available, mapping)
+		}
+	}
+
+	// Order mappings by provider priority tier, balancing load within each tier. Degraded
+	// providers come after healthy ones, and unhealthy ones are only reached once every
+	// other provider is ruled out.
+	candidates := append(r.loadBalancer.Order(policy, poolKey, available), r.loadBalancer.Order(policy, poolKey, degraded)...)
+	fallbackFrom := len(candidates)
+	if r.unhealthyFallback {
+		candidates = append(candidates, r.loadBalancer.Order(policy, poolKey, unhealthy)...)
+	} else {
+		skips.unhealthy += len(unhealthy)
+	}
+
+	// Iterate through sorted providers and pick an API key for the first one that has a usable key.
+	// Keys come from the provider's ApiKey rows; the config key is only used when it has none.
+	for i, mapping := range candidates {
 		provider := &mapping.Provider
-		var apiKey string
-		keyFound := false
+		if i == fallbackFrom {
+			log.Printf("[Router] No healthy provider left for model %s, falling back to unhealthy providers", model)
+		}
+		// Live traffic overrides the periodic health status: skip circuits that are open
+		if !r.circuitBreaker.Allow(provider.ID, mapping.ID) {
+			skips.circuitOpen++
+			continue
+		}
 
-		// API keys are stored in the provider's JSON config.
+		apiKey, reservation, err := r.keyManager.SelectProviderKey(provider, estimatedTokens)
+		if err != nil {
+			log.Printf("[Router] Skipping provider %s: %v", provider.Name, err)
+			r.circuitBreaker.Release(provider.ID, mapping.ID)
+			skips.noKey++
+			continue
+		}
+		if apiKey != nil {
+			return &core.ProviderRouteResult{
+				Provider:       provider,
+				MappingID:      mapping.ID,
+				ApiKey:         apiKey.Key,
+				ApiKeyID:       apiKey.ID,
+				KeyReservation: reservation,
+				ResolvedModel:  mapping.ProviderModel,
+			}
+		}
+
+		// No ApiKey rows: fall back to the key stored in the provider's JSON config.
 		var config map[string]interface{}
 		if json.Unmarshal([]byte(provider.Config), &config) == nil {
 			if configKey, ok := config["apiKey"].(string); ok && configKey != "" {
-				apiKey = configKey
-				keyFound = true
+				return &core.ProviderRouteResult{
+					Provider:      provider,
+					MappingID:     mapping.ID,
+					ApiKey:        configKey,
+					ResolvedModel: mapping.ProviderModel,
+				}
 			}
 		}
+		// If no key is found, the loop will continue to the next provider (failover).
+		r.circuitBreaker.Release(provider.ID, mapping.ID)
+		skips.noKey++
+	}
+	return nil
+}
 
-		if keyFound {
-			// Success! We found a key in the provider's config.
-			return &core.ProviderRouteResult{
-				Group:         nil,
-				Provider:      provider,
-				ApiKey:        apiKey,
-				ResolvedModel: mapping.ProviderModel,
-			}, nil
+// mappingSupports reports whether a mapping can serve every required capability. Only a
+// flag explicitly set to false rules a mapping out.
+func mappingSupports(mapping *database.ModelProviderMapping, required []constants.Capability) bool {
+	for _, capability := range required {
+		var flag *bool
+		switch capability {
+		case constants.CapabilityToolCall:
+			flag = mapping.ToolCall
+		case constants.CapabilityStructuredOutput:
+			flag = mapping.StructuredOutput
+		case constants.CapabilityImageInput:
+			flag = mapping.Image
+		case constants.CapabilityImageGeneration:
+			flag = mapping.ImageGeneration
+		case constants.CapabilityTranscription:
+			flag = mapping.AudioTranscription
+		case constants.CapabilitySpeech:
+			flag = mapping.Speech
+		}
+		if flag != nil && !*flag {
+			return false
 		}
-		// If no key is found in the config, the loop will continue to the next provider (failover).
 	}
+	return true
+}
+
+// requestCapabilities returns the capabilities a chat completion or Responses API request
+// needs: tools (or a conversation that already uses them), a json_schema response format,
+// and image inputs.
+func requestCapabilities(requestBody map[string]interface{}) []constants.Capability {
+	var required []constants.Capability
+	if requestUsesTools(requestBody) {
+		required = append(required, constants.CapabilityToolCall)
+	}
+	if requestUsesJSONSchema(requestBody) {
+		required = append(required, constants.CapabilityStructuredOutput)
+	}
+	if requestHasImages(requestBody) {
+		required = append(required, constants.CapabilityImageInput)
+	}
+	return required
+}
 
-	// 5. If the loop completes, it means no provider in the mapping had a working key
-	return nil, errors.New("no available API key for any of the mapped providers")
+func requestUsesTools(requestBody map[string]interface{}) bool {
+	for _, field := range []string{"tools", "functions"} {
+		if list, _ := requestBody[field].([]interface{}); len(list) > 0 {
+			return true
+		}
+	}
+	messages, _ := requestBody["messages"].([]interface{})
+	for _, item := range messages {
+		message, _ := item.(map[string]interface{})
+		if role, _ := message["role"].(string); role == "tool" || role == "function" {
+			return true
+		}
+		if calls, _ := message["tool_calls"].([]interface{}); len(calls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// requestUsesJSONSchema checks response_format for chat completions and text.format for the
+// Responses API.
+func requestUsesJSONSchema(requestBody map[string]interface{}) bool {
+	format, _ := requestBody["response_format"].(map[string]interface{})
+	if text, ok := requestBody["text"].(map[string]interface{}); ok && format == nil {
+		format, _ = text["format"].(map[string]interface{})
+	}
+	formatType, _ := format["type"].(string)
+	return formatType == "json_schema"
+}
+
+// requestHasImages looks for image_url parts in chat messages and input_image parts in
+// Responses API input.
+func requestHasImages(requestBody map[string]interface{}) bool {
+	for _, field := range []string{"messages", "input"} {
+		items, _ := requestBody[field].([]interface{})
+		for _, item := range items {
+			message, _ := item.(map[string]interface{})
+			parts, _ := message["content"].([]interface{})
+			for _, part := range parts {
+				content, _ := part.(map[string]interface{})
+				if partType, _ := content["type"].(string); partType == "image_url" || partType == "input_image" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// joinCapabilities lists capabilities for error messages.
+func joinCapabilities(capabilities []constants.Capability) string {
+	names := make([]string, len(capabilities))
+	for i, capability := range capabilities {
+		names[i] = capability.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// routeGroup is one group a request may be routed through. A nil group stands for the
+// providers that belong to no group, which keys without AllowedGroups can also use.
+type routeGroup struct {
+	group     *database.Group
+	providers map[uint]bool
+	model     string // Requested model name after applying the group's or the global aliases
+}
+
+// routeGroups returns the enabled groups that serve the model and that the proxy key may use,
+// in the order of the key's GroupBalancePolicy. Keys without AllowedGroups may use every group
+// and, after them, the providers that are not assigned to any group.
+func (r *ProviderRouter) routeGroups(db *gorm.DB, key *database.ProxyKey, model string) ([]routeGroup, error) {
+	var groups []database.Group
+	if err := db.Where("enabled = ?", true).Order("id").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	var members []database.GroupProvider
+	if err := db.Find(&members).Error; err != nil {
+		return nil, err
+	}
+	var aliasRows []database.ModelAlias
+	if err := db.Where("enabled = ?", true).Find(&aliasRows).Error; err != nil {
+		return nil, err
+	}
+
+	groupProviders := make(map[uint]map[uint]bool)
+	grouped := make(map[uint]bool)
+	for _, member := range members {
+		if groupProviders[member.GroupID] == nil {
+			groupProviders[member.GroupID] = make(map[uint]bool)
+		}
+		groupProviders[member.GroupID][member.ProviderID] = true
+		grouped[member.ProviderID] = true
+	}
+
+	// Group aliases take precedence over global ones; the result is not resolved again
+	globalAliases := make(map[string]string, len(aliasRows))
+	for _, row := range aliasRows {
+		globalAliases[row.Alias] = row.Target
+	}
+	resolve := func(group *database.Group) string {
+		if group != nil {
+			var aliases map[string]string
+			if group.ModelAliases != "" && json.Unmarshal([]byte(group.ModelAliases), &aliases) == nil {
+				if target, ok := util.ResolveModelAlias(aliases, model); ok {
+					return target
+				}
+			}
+		}
+		target, _ := util.ResolveModelAlias(globalAliases, model)
+		return target
+	}
+
+	allowed := parseGroupRefs(key.AllowedGroups)
+	var eligible []database.Group
+	resolved := make(map[uint]string)
+	for _, group := range groups {
+		if len(allowed) > 0 && !allowed[strconv.FormatUint(uint64(group.ID), 10)] && !allowed[group.Name] {
+			continue
+		}
+		target := resolve(&group)
+		if groupServesModel(&group, model) || groupServesModel(&group, target) {
+			eligible = append(eligible, group)
+			resolved[group.ID] = target
+		}
+	}
+
+	ordered := r.loadBalancer.OrderGroups(key.GroupBalancePolicy, fmt.Sprintf("proxykey:%d", key.ID), eligible, parseGroupWeights(key.GroupWeights, groups))
+	routeGroups := make([]routeGroup, 0, len(ordered)+1)
+	for i := range ordered {
+		routeGroups = append(routeGroups, routeGroup{group: &ordered[i], providers: groupProviders[ordered[i].ID], model: resolved[ordered[i].ID]})
+	}
+
+	if len(allowed) == 0 {
+		var providerIDs []uint
+		if err := db.Model(&database.Provider{}).Pluck("id", &providerIDs).Error; err != nil {
+			return nil, err
+		}
+		ungrouped := make(map[uint]bool)
+		for _, id := range providerIDs {
+			if !grouped[id] {
+				ungrouped[id] = true
+			}
+		}
+		routeGroups = append(routeGroups, routeGroup{providers: ungrouped, model: resolve(nil)})
+	}
+	return routeGroups, nil
+}
+
+// groupServesModel reports whether the model is listed in the group's Models. A group
+// without a model list serves every model.
+func groupServesModel(group *database.Group, model string) bool {
+	var models []string
+	if group.Models == "" || json.Unmarshal([]byte(group.Models), &models) != nil || len(models) == 0 {
+		return true
+	}
+	for _, m := range models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGroupRefs reads a JSON array of group IDs or names, e.g. [1, "2", "premium"].
+func parseGroupRefs(raw string) map[string]bool {
+	var refs []interface{}
+	if raw == "" || json.Unmarshal([]byte(raw), &refs) != nil {
+		return nil
+	}
+	result := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		switch v := ref.(type) {
+		case float64:
+			result[strconv.FormatFloat(v, 'f', -1, 64)] = true
+		case string:
+			if v != "" {
+				result[v] = true
+			}
+		}
+	}
+	return result
+}
+
+// parseGroupWeights reads a JSON object of weights keyed by group ID or name, e.g. {"1": 3, "backup": 1}.
+func parseGroupWeights(raw string, groups []database.Group) map[uint]int {
+	var byRef map[string]float64
+	if raw == "" || json.Unmarshal([]byte(raw), &byRef) != nil {
+		return nil
+	}
+	weights := make(map[uint]int, len(byRef))
+	for _, group := range groups {
+		if weight, ok := byRef[strconv.FormatUint(uint64(group.ID), 10)]; ok {
+			weights[group.ID] = int(weight)
+		} else if weight, ok := byRef[group.Name]; ok {
+			weights[group.ID] = int(weight)
+		}
+	}
+	return weights
+}
+
+// SetUnhealthyFallback controls whether unhealthy providers are used once every healthy and
+// degraded provider of a model is ruled out (enabled by default, since health checks can be stale).
+func (r *ProviderRouter) SetUnhealthyFallback(enabled bool) {
+	r.unhealthyFallback = enabled
+}
+
+// routeSkips counts why candidate mappings were passed over, for the error returned to clients.
+type routeSkips struct {
+	mappingDisabled  int
+	providerDisabled int
+	unhealthy        int
+	circuitOpen      int
+	noKey            int
+	failed           int
+	outsideGroups    int
+	incapable        int
+	capable          int // Mappings with every required capability, whatever their state; not a skip reason
+}
+
+func (s routeSkips) String() string {
+	var reasons []string
+	add := func(count int, reason string) {
+		if count > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d %s", count, reason))
+		}
+	}
+	add(s.mappingDisabled, "mapping(s) disabled")
+	add(s.providerDisabled, "provider(s) disabled")
+	add(s.unhealthy, "provider(s) unhealthy")
+	add(s.circuitOpen, "circuit breaker(s) open")
+	add(s.noKey, "provider(s) without a usable API key")
+	add(s.failed, "provider(s) already failed for this request")
+	add(s.outsideGroups, "provider(s) outside the groups available to this key")
+	add(s.incapable, "mapping(s) without a required capability")
+	if len(reasons) == 0 {
+		return "no candidates"
+	}
+	return strings.Join(reasons, ", ")
 }
 
 func init() {
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"fmt"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateLimitWindow is the sliding window over which RPM and TPM limits are measured.
+const RateLimitWindow = time.Minute
+
+// RateLimiter enforces the RpmLimit and TpmLimit of proxy keys and provider API keys with a
+// sliding window. Limits are read from the key on every call, so admin changes apply immediately.
+type RateLimiter struct {
+	store core.IRateLimitStore
+	// mu serializes check-and-charge so concurrent requests cannot overshoot a limit.
+	mu sync.Mutex
+}
+
+// NewRateLimiter creates a RateLimiter. A nil store falls back to an in-memory store.
+func NewRateLimiter(store core.IRateLimitStore) *RateLimiter {
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	return &RateLimiter{store: store}
+}
+
+// Reserve checks the proxy key's limits and charges one request and estimatedTokens if the request fits.
+func (rl *RateLimiter) Reserve(key *database.ProxyKey, estimatedTokens int) *core.RateLimitDecision {
+	return rl.reserve(fmt.Sprintf("proxykey:%d", key.ID), key.RpmLimit, key.TpmLimit, estimatedTokens)
+}
+
+// ReserveApiKey checks the provider API key's limits and charges one request and estimatedTokens if the request fits.
+func (rl *RateLimiter) ReserveApiKey(key *database.ApiKey, estimatedTokens int) *core.RateLimitDecision {
+	return rl.reserve(fmt.Sprintf("apikey:%d", key.ID), key.RpmLimit, key.TpmLimit, estimatedTokens)
+}
+
+func (rl *RateLimiter) reserve(subject string, rpmLimit, tpmLimit, estimatedTokens int) *core.RateLimitDecision {
+	now := time.Now()
+	requestsKey, tokensKey := rateLimitKeys(subject)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	decision := &core.RateLimitDecision{
+		Allowed:         true,
+		RequestLimit:    rpmLimit,
+		TokenLimit:      tpmLimit,
+		RequestedTokens: estimatedTokens,
+	}
+
+	if rpmLimit > 0 {
+		used, oldest := rl.store.Usage(requestsKey, now, RateLimitWindow)
+		decision.RequestsRemaining = remaining(rpmLimit, used)
+		decision.RequestsReset = windowReset(oldest, now)
+		if used+1 > int64(rpmLimit) {
+			decision.Allowed = false
+			decision.LimitedBy = "requests"
+			decision.RetryAfter = decision.RequestsReset
+		}
+	}
+
+	if tpmLimit > 0 {
+		used, oldest := rl.store.Usage(tokensKey, now, RateLimitWindow)
+		decision.TokensRemaining = remaining(tpmLimit, used)
+		decision.TokensReset = windowReset(oldest, now)
+		if decision.Allowed && used+int64(estimatedTokens) > int64(tpmLimit) {
+			decision.Allowed = false
+			decision.LimitedBy = "tokens"
+			decision.RetryAfter = decision.TokensReset
+		}
+	}
+
+	if !decision.Allowed {
+		if decision.RetryAfter <= 0 {
+			decision.RetryAfter = time.Second
+		}
+		return decision
+	}
+
+	rl.store.Add(requestsKey, now, 1)
+	rl.store.Add(tokensKey, now, int64(estimatedTokens))
+	if rpmLimit > 0 {
+		decision.RequestsRemaining = remaining(decision.RequestsRemaining, 1)
+		if decision.RequestsReset == 0 {
+			decision.RequestsReset = RateLimitWindow
+		}
+	}
+	if tpmLimit > 0 {
+		decision.TokensRemaining = remaining(decision.TokensRemaining, int64(estimatedTokens))
+		if decision.TokensReset == 0 {
+			decision.TokensReset = RateLimitWindow
+		}
+	}
+	decision.Reservation = &core.RateLimitReservation{
+		Subject:         subject,
+		ReservedAt:      now,
+		EstimatedTokens: estimatedTokens,
+	}
+	return decision
+}
+
+// Reconcile replaces the estimated token charge of a reservation with the actual usage.
+// The correction is booked at the reservation time so it leaves the window with the original charge.
+func (rl *RateLimiter) Reconcile(reservation *core.RateLimitReservation, actualTokens int) {
+	if reservation == nil {
+		return
+	}
+	delta := actualTokens - reservation.EstimatedTokens
+	if delta == 0 {
+		return
+	}
+	_, tokensKey := rateLimitKeys(reservation.Subject)
+	rl.store.Add(tokensKey, reservation.ReservedAt, int64(delta))
+	reservation.EstimatedTokens = actualTokens
+}
+
+// SchedulePeriodicCleanup prunes expired usage from the store at the given interval.
+func (rl *RateLimiter) SchedulePeriodicCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			rl.store.Prune(time.Now().Add(-RateLimitWindow))
+		}
+	}()
+	log.Printf("[RateLimiter] Scheduled usage cleanup every %v", interval)
+}
+
+func rateLimitKeys(subject string) (requestsKey, tokensKey string) {
+	return subject + ":requests", subject + ":tokens"
+}
+
+func remaining(limit int, used int64) int {
+	if left := int64(limit) - used; left > 0 {
+		return int(left)
+	}
+	return 0
+}
+
+// windowReset returns how long until the oldest usage in the window expires.
+func windowReset(oldest time.Time, now time.Time) time.Duration {
+	if oldest.IsZero() {
+		return 0
+	}
+	if reset := oldest.Add(RateLimitWindow).Sub(now); reset > 0 {
+		return reset
+	}
+	return 0
+}
+
+// MemoryRateLimitStore is an in-process IRateLimitStore that keeps per-second usage buckets.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string][]usageBucket
+}
+
+type usageBucket struct {
+	second int64
+	amount int64
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string][]usageBucket)}
+}
+
+// Add records amount against key in the bucket for the given second.
+func (s *MemoryRateLimitStore) Add(key string, at time.Time, amount int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	second := at.Unix()
+	buckets := s.buckets[key]
+	i := sort.Search(len(buckets), func(i int) bool { return buckets[i].second >= second })
+	if i < len(buckets) && buckets[i].second == second {
+		buckets[i].amount += amount
+		return
+	}
+	buckets = append(buckets, usageBucket{})
+	copy(buckets[i+1:], buckets[i:])
+	buckets[i] = usageBucket{second: second, amount: amount}
+	s.buckets[key] = buckets
+}
+
+// Usage sums the buckets of key that fall inside the window ending at now.
+func (s *MemoryRateLimitStore) Usage(key string, now time.Time, window time.Duration) (int64, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := now.Add(-window).Unix()
+	var total int64
+	var oldest time.Time
+	for _, bucket := range s.buckets[key] {
+		if bucket.second <= start {
+			continue
+		}
+		total += bucket.amount
+		if oldest.IsZero() && bucket.amount > 0 {
+			oldest = time.Unix(bucket.second, 0)
+		}
+	}
+	return total, oldest
+}
+
+// Prune drops buckets recorded before the given time and forgets keys with no remaining usage.
+func (s *MemoryRateLimitStore) Prune(before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := before.Unix()
+	for key, buckets := range s.buckets {
+		i := sort.Search(len(buckets), func(i int) bool { return buckets[i].second >= cutoff })
+		if i == len(buckets) {
+			delete(s.buckets, key)
+			continue
+		}
+		s.buckets[key] = append(buckets[:0], buckets[i:]...)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"llm-fusion-engine/internal/database"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	type call struct {
+		tokens    int
+		allowed   bool
+		limitedBy string
+	}
+	tests := []struct {
+		name     string
+		rpmLimit int
+		tpmLimit int
+		calls    []call
+	}{
+		{
+			name:  "unlimited",
+			calls: []call{{1000, true, ""}, {1000, true, ""}, {1000, true, ""}},
+		},
+		{
+			name:     "request limit",
+			rpmLimit: 2,
+			calls:    []call{{10, true, ""}, {10, true, ""}, {10, false, "requests"}},
+		},
+		{
+			name:     "token limit",
+			tpmLimit: 100,
+			calls:    []call{{60, true, ""}, {50, false, "tokens"}, {40, true, ""}, {1, false, "tokens"}},
+		},
+		{
+			name:     "requests are checked first",
+			rpmLimit: 1,
+			tpmLimit: 10,
+			calls:    []call{{5, true, ""}, {50, false, "requests"}},
+		},
+		{
+			name:     "a request larger than the limit is rejected",
+			tpmLimit: 100,
+			calls:    []call{{101, false, "tokens"}, {100, true, ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(NewMemoryRateLimitStore())
+			key := &database.ProxyKey{BaseModel: database.BaseModel{ID: 1}, RpmLimit: tt.rpmLimit, TpmLimit: tt.tpmLimit}
+			for i, c := range tt.calls {
+				decision := limiter.Reserve(key, c.tokens)
+				if decision.Allowed != c.allowed || decision.LimitedBy != c.limitedBy {
+					t.Fatalf("call %d: allowed %v limited by %q, want %v %q", i, decision.Allowed, decision.LimitedBy, c.allowed, c.limitedBy)
+				}
+				if decision.Allowed && decision.Reservation == nil {
+					t.Fatalf("call %d: allowed without a reservation", i)
+				}
+				if !decision.Allowed && decision.RetryAfter <= 0 {
+					t.Fatalf("call %d: rejected without a retry delay", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterRemaining(t *testing.T) {
+	limiter := NewRateLimiter(nil)
+	key := &database.ProxyKey{BaseModel: database.BaseModel{ID: 1}, RpmLimit: 3, TpmLimit: 1000}
+
+	tests := []struct {
+		tokens            int
+		requestsRemaining int
+		tokensRemaining   int
+	}{
+		{100, 2, 900},
+		{250, 1, 650},
+		{650, 0, 0},
+	}
+	for i, tt := range tests {
+		decision := limiter.Reserve(key, tt.tokens)
+		if !decision.Allowed {
+			t.Fatalf("call %d: rejected by %s", i, decision.LimitedBy)
+		}
+		if decision.RequestsRemaining != tt.requestsRemaining || decision.TokensRemaining != tt.tokensRemaining {
+			t.Errorf("call %d: remaining %d requests %d tokens, want %d %d", i,
+				decision.RequestsRemaining, decision.TokensRemaining, tt.requestsRemaining, tt.tokensRemaining)
+		}
+		if decision.RequestsReset <= 0 || decision.RequestsReset > RateLimitWindow {
+			t.Errorf("call %d: request reset %v outside the window", i, decision.RequestsReset)
+		}
+	}
+}
+
+func TestRateLimiterKeysAreIndependent(t *testing.T) {
+	limiter := NewRateLimiter(nil)
+	proxyKey := &database.ProxyKey{BaseModel: database.BaseModel{ID: 1}, RpmLimit: 1}
+	otherProxyKey := &database.ProxyKey{BaseModel: database.BaseModel{ID: 2}, RpmLimit: 1}
+	apiKey := &database.ApiKey{BaseModel: database.BaseModel{ID: 1}, RpmLimit: 1}
+
+	if !limiter.Reserve(proxyKey, 0).Allowed {
+		t.Fatal("first request of proxy key 1 rejected")
+	}
+	if !limiter.Reserve(otherProxyKey, 0).Allowed {
+		t.Fatal("proxy key 2 shares the limit of proxy key 1")
+	}
+	if !limiter.ReserveApiKey(apiKey, 0).Allowed {
+		t.Fatal("API key 1 shares the limit of proxy key 1")
+	}
+	if limiter.Reserve(proxyKey, 0).Allowed {
+		t.Fatal("second request of proxy key 1 allowed")
+	}
+}
+
+func TestRateLimiterReconcile(t *testing.T) {
+	tests := []struct {
+		name      string
+		estimated int
+		actual    int
+		next      int
+		allowed   bool
+	}{
+		{"overestimate frees tokens", 80, 20, 80, true},
+		{"underestimate charges the difference", 20, 80, 30, false},
+		{"exact estimate", 50, 50, 50, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(nil)
+			key := &database.ProxyKey{BaseModel: database.BaseModel{ID: 1}, TpmLimit: 100}
+			decision := limiter.Reserve(key, tt.estimated)
+			if !decision.Allowed {
+				t.Fatal("first request rejected")
+			}
+			limiter.Reconcile(decision.Reservation, tt.actual)
+			if got := limiter.Reserve(key, tt.next).Allowed; got != tt.allowed {
+				t.Errorf("next request of %d tokens allowed %v, want %v", tt.next, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreWindow(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore()
+	store.Add("k", now.Add(-2*RateLimitWindow), 100)
+	store.Add("k", now.Add(-30*time.Second), 5)
+	store.Add("k", now, 7)
+
+	used, oldest := store.Usage("k", now, RateLimitWindow)
+	if used != 12 {
+		t.Errorf("usage %d, want 12", used)
+	}
+	if want := now.Add(-30 * time.Second).Unix(); oldest.Unix() != want {
+		t.Errorf("oldest %v, want unix %d", oldest, want)
+	}
+
+	store.Prune(now.Add(-RateLimitWindow))
+	if used, _ := store.Usage("k", now, 10*RateLimitWindow); used != 12 {
+		t.Errorf("usage after prune %d, want 12", used)
+	}
+	store.Prune(now.Add(time.Second))
+	if used, _ := store.Usage("k", now, RateLimitWindow); used != 0 {
+		t.Errorf("usage after pruning everything %d, want 0", used)
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"llm-fusion-engine/internal/core"
+	"sync"
+	"time"
+)
+
+// errFirstByteTimeout cancels an upstream attempt whose response did not start in time.
+var errFirstByteTimeout = errors.New("upstream did not respond within the first byte timeout")
+
+// requestScope carries the context shared by every upstream attempt of one client request.
+// It is cancelled when the client disconnects, when the model's total timeout elapses before
+// a response starts, or when the response has been consumed.
+type requestScope struct {
+	parent  context.Context
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	started time.Time
+
+	mu            sync.Mutex
+	deadline      *time.Timer
+	deadlineStart time.Time // When the total timeout starts counting
+	armed         bool
+	rootLog       string // Log entry of the first attempt, which later attempts are recorded under
+	attempts      int
+}
+
+func newRequestScope(parent context.Context) *requestScope {
+	ctx, cancel := context.WithCancelCause(parent)
+	now := time.Now()
+	return &requestScope{parent: parent, ctx: ctx, cancel: cancel, started: now, deadlineStart: now}
+}
+
+// armDeadline starts the total timeout the first time it is called. The timeout is counted
+// from the start of the request, or from the last rearmDeadline; zero means no limit.
+func (rs *requestScope) armDeadline(timeout time.Duration) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.armed {
+		return
+	}
+	rs.armed = true
+	if timeout > 0 {
+		rs.deadline = time.AfterFunc(timeout-time.Since(rs.deadlineStart), func() {
+			rs.cancel(core.ErrRequestTimeout)
+		})
+	}
+}
+
+// stopDeadline lifts the total timeout once a response has started. The timeout bounds the
+// wait for a response and its retries, not how long the client takes to consume the body.
+func (rs *requestScope) stopDeadline() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.deadline != nil {
+		rs.deadline.Stop()
+	}
+}
+
+// rearmDeadline lets the next armDeadline start a new total timeout counted from now. A stream
+// failover uses it so that the wait for the next mapping is bounded again after the first
+// response lifted the deadline.
+func (rs *requestScope) rearmDeadline() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.deadline != nil {
+		rs.deadline.Stop()
+		rs.deadline = nil
+	}
+	rs.armed = false
+	rs.deadlineStart = time.Now()
+}
+
+// finish releases the scope once the response is no longer needed.
+func (rs *requestScope) finish() {
+	rs.mu.Lock()
+	if rs.deadline != nil {
+		rs.deadline.Stop()
+	}
+	rs.mu.Unlock()
+	rs.cancel(context.Canceled)
+}
+
+// err reports why the scope ended before the request completed: core.ErrClientAborted,
+// core.ErrRequestTimeout, or nil while the request may continue.
+func (rs *requestScope) err() error {
+	if rs.parent.Err() != nil {
+		return core.ErrClientAborted
+	}
+	if context.Cause(rs.ctx) == core.ErrRequestTimeout {
+		return core.ErrRequestTimeout
+	}
+	return nil
+}
+
+// nextAttempt registers the log entry of an upstream attempt and returns the entry it belongs
+// to (empty for the first attempt, which stands for the whole request) and its number.
+func (rs *requestScope) nextAttempt(logID string) (string, int) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.attempts++
+	if rs.rootLog == "" {
+		rs.rootLog = logID
+		return "", rs.attempts
+	}
+	return rs.rootLog, rs.attempts
+}
+
+// sleep waits for d, returning false if the scope ended first.
+func (rs *requestScope) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-rs.ctx.Done():
+		return false
+	}
+}
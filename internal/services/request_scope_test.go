@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"llm-fusion-engine/internal/core"
+	"testing"
+	"time"
+)
+
+func TestRequestScopeDeadline(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(rs *requestScope)
+		wantErr error
+	}{
+		{"expires", func(rs *requestScope) {
+			rs.armDeadline(20 * time.Millisecond)
+		}, core.ErrRequestTimeout},
+		{"counted from the start of the request", func(rs *requestScope) {
+			time.Sleep(30 * time.Millisecond)
+			rs.armDeadline(20 * time.Millisecond)
+		}, core.ErrRequestTimeout},
+		{"only the first arm counts", func(rs *requestScope) {
+			rs.armDeadline(20 * time.Millisecond)
+			rs.armDeadline(time.Hour)
+		}, core.ErrRequestTimeout},
+		{"lifted once a response starts", func(rs *requestScope) {
+			rs.armDeadline(20 * time.Millisecond)
+			rs.stopDeadline()
+		}, nil},
+		{"rearmed for a stream failover", func(rs *requestScope) {
+			rs.armDeadline(20 * time.Millisecond)
+			rs.stopDeadline()
+			rs.rearmDeadline()
+			rs.armDeadline(20 * time.Millisecond)
+		}, core.ErrRequestTimeout},
+		{"zero timeout never expires", func(rs *requestScope) {
+			rs.armDeadline(0)
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := newRequestScope(context.Background())
+			defer rs.finish()
+			tt.run(rs)
+			time.Sleep(60 * time.Millisecond)
+			if err := rs.err(); err != tt.wantErr {
+				t.Errorf("err() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A rearmed deadline counts from the failover, not from the start of the request
+	rs := newRequestScope(context.Background())
+	defer rs.finish()
+	rs.armDeadline(30 * time.Millisecond)
+	rs.stopDeadline()
+	time.Sleep(50 * time.Millisecond)
+	rs.rearmDeadline()
+	rs.armDeadline(time.Second)
+	if err := rs.err(); err != nil {
+		t.Errorf("rearmed deadline expired at once: %v", err)
+	}
+
+	// Cancelling the client ends the scope as aborted rather than timed out
+	parent, cancel := context.WithCancel(context.Background())
+	rs = newRequestScope(parent)
+	defer rs.finish()
+	rs.armDeadline(time.Hour)
+	cancel()
+	if err := rs.err(); err != core.ErrClientAborted {
+		t.Errorf("err() after client cancel = %v, want ErrClientAborted", err)
+	}
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultResponseCacheSize is how many responses the response cache keeps in memory.
+const DefaultResponseCacheSize = 1000
+
+// DefaultResponseCacheTTL applies to proxy keys without their own CacheTTL.
+const DefaultResponseCacheTTL = time.Hour
+
+// maxCachedBodySize bounds the responses that are cached; larger ones are passed through uncached.
+const maxCachedBodySize = 4 << 20
+
+// CacheHeader asks for a request to be cached even when it is not deterministic; "false"
+// keeps a request out of the cache. Responses of cacheable requests carry it as HIT or MISS.
+const CacheHeader = "X-Cache"
+
+// cacheProviderName stands in for the provider in the log entries of cache hits.
+const cacheProviderName = "cache"
+
+// cacheExcludedFields do not change the response and are left out of cache keys. stream_options
+// stays in: include_usage decides whether a replayed stream carries a usage chunk.
+var cacheExcludedFields = map[string]bool{"user": true, "metadata": true, "store": true}
+
+// ResponseCache implements core.IResponseCache with an in-memory LRU in front of the
+// ResponseCacheEntry table. Responses evicted from memory are still found in the database.
+type ResponseCache struct {
+	db       *gorm.DB
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type responseCacheItem struct {
+	key      string
+	response *core.CachedResponse
+}
+
+// NewResponseCache creates a response cache keeping up to capacity responses in memory.
+// A nil db keeps responses in memory only.
+func NewResponseCache(db *gorm.DB, capacity int) *ResponseCache {
+	if capacity <= 0 {
+		capacity = DefaultResponseCacheSize
+	}
+	return &ResponseCache{
+		db:       db,
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the unexpired response stored under key, looking in memory first.
+func (rc *ResponseCache) Get(key string) (*core.CachedResponse, bool) {
+	if response := rc.lookup(key); response != nil {
+		rc.hits.Add(1)
+		return response, true
+	}
+	if rc.db != nil {
+		// Find rather than First: a miss is expected and not worth an error log
+		var entry database.ResponseCacheEntry
+		result := rc.db.Where("key = ? AND expires_at > ?", key, time.Now()).Limit(1).Find(&entry)
+		if result.Error == nil && result.RowsAffected > 0 {
+			response := &core.CachedResponse{ContentType: entry.ContentType, Body: entry.Body, ExpiresAt: entry.ExpiresAt}
+			rc.remember(key, response)
+			rc.hits.Add(1)
+			return response, true
+		}
+	}
+	rc.misses.Add(1)
+	return nil, false
+}
+
+// Set stores a response in memory and in the database.
+func (rc *ResponseCache) Set(key string, response *core.CachedResponse) {
+	rc.remember(key, response)
+	if rc.db != nil {
+		entry := database.ResponseCacheEntry{
+			Key:         key,
+			ContentType: response.ContentType,
+			Body:        response.Body,
+			ExpiresAt:   response.ExpiresAt,
+			CreatedAt:   time.Now(),
+		}
+		if err := rc.db.Save(&entry).Error; err != nil {
+			log.Printf("[ResponseCache] Failed to store response: %v", err)
+		}
+	}
+}
+
+// Stats returns the hit and miss counters since startup.
+func (rc *ResponseCache) Stats() core.ResponseCacheStats {
+	rc.mu.Lock()
+	entries := rc.order.Len()
+	rc.mu.Unlock()
+	return core.ResponseCacheStats{Hits: rc.hits.Load(), Misses: rc.misses.Load(), Entries: entries}
+}
+
+// lookup returns an unexpired response from memory, marking it as recently used.
+func (rc *ResponseCache) lookup(key string) *core.CachedResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	element, ok := rc.entries[key]
+	if !ok {
+		return nil
+	}
+	item := element.Value.(*responseCacheItem)
+	if !time.Now().Before(item.response.ExpiresAt) {
+		rc.order.Remove(element)
+		delete(rc.entries, key)
+		return nil
+	}
+	rc.order.MoveToFront(element)
+	return item.response
+}
+
+// remember keeps a response in memory, evicting the least recently used one when full.
+func (rc *ResponseCache) remember(key string, response *core.CachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if element, ok := rc.entries[key]; ok {
+		element.Value.(*responseCacheItem).response = response
+		rc.order.MoveToFront(element)
+		return
+	}
+	rc.entries[key] = rc.order.PushFront(&responseCacheItem{key: key, response: response})
+	for rc.order.Len() > rc.capacity {
+		oldest := rc.order.Back()
+		rc.order.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*responseCacheItem).key)
+	}
+}
+
+// PurgeExpired drops expired responses from memory and from the database.
+func (rc *ResponseCache) PurgeExpired() {
+	now := time.Now()
+	rc.mu.Lock()
+	for element := rc.order.Front(); element != nil; {
+		next := element.Next()
+		item := element.Value.(*responseCacheItem)
+		if !now.Before(item.response.ExpiresAt) {
+			rc.order.Remove(element)
+			delete(rc.entries, item.key)
+		}
+		element = next
+	}
+	rc.mu.Unlock()
+	if rc.db != nil {
+		rc.db.Where("expires_at <= ?", now).Delete(&database.ResponseCacheEntry{})
+	}
+}
+
+// SchedulePeriodicCleanup purges expired responses at the given interval.
+func (rc *ResponseCache) SchedulePeriodicCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			rc.PurgeExpired()
+		}
+	}()
+	log.Printf("[ResponseCache] Scheduled cleanup of expired responses every %v", interval)
+}
+
+// responseCacheKey returns the key a request is cached under and how long its response stays
+// valid. The key is empty when the request is not cached: the cache is not configured, the
+// operation or proxy key does not use it, or the request is neither deterministic nor
+// marked with CacheHeader. Keys are scoped to the proxy key, so clients never see each
+// other's responses.
+func (s *MultiProviderService) responseCacheKey(c *gin.Context, op upstreamOperation, requestBody map[string]interface{}, proxyKey string) (string, time.Duration) {
+	if s.responseCache == nil || op.cacheScope == "" {
+		return "", 0
+	}
+	switch strings.ToLower(strings.TrimSpace(c.GetHeader(CacheHeader))) {
+	case "false", "0", "off", "no":
+		return "", 0
+	case "":
+		if op.deterministic == nil || !op.deterministic(requestBody) {
+			return "", 0
+		}
+	}
+	key, err := s.keyManager.ValidateProxyKeyAsync(proxyKey)
+	if err != nil || !key.CacheEnabled {
+		return "", 0
+	}
+
+	canonical := make(map[string]interface{}, len(requestBody))
+	for field, value := range requestBody {
+		if !cacheExcludedFields[field] {
+			canonical[field] = value
+		}
+	}
+	// Maps are encoded with sorted keys, so equal requests encode to equal bytes
+	encoded, err := json.Marshal(canonical)
+	if err != nil {
+		return "", 0
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\n%s\n", key.ID, op.cacheScope)
+	hash.Write(encoded)
+
+	ttl := DefaultResponseCacheTTL
+	if key.CacheTTL > 0 {
+		ttl = time.Duration(key.CacheTTL) * time.Second
+	}
+	return hex.EncodeToString(hash.Sum(nil)), ttl
+}
+
+// replayFromCache answers a request from the response cache, logging the hit. It returns nil
+// when the key is empty or nothing is cached under it.
+func (s *MultiProviderService) replayFromCache(c *gin.Context, cacheKey string, requestBody map[string]interface{}, proxyKey, model string) *http.Response {
+	if cacheKey == "" {
+		return nil
+	}
+	started := time.Now()
+	cached, ok := s.responseCache.Get(cacheKey)
+	if !ok {
+		return nil
+	}
+	return s.replayCached(c, cached, requestBody, proxyKey, model, 0, started)
+}
+
+// replayCached answers a request with a cached response and logs the hit. The similarity is
+// that of the semantic cache match, or zero for an exact match.
+func (s *MultiProviderService) replayCached(c *gin.Context, cached *core.CachedResponse, requestBody map[string]interface{}, proxyKey, model string, similarity float64, started time.Time) *http.Response {
+	requestID := uuid.New().String()
+	c.Set("requestID", requestID)
+	logged := cached.Body
+	if len(logged) > s.logBodyLimit {
+		logged = logged[:s.logBodyLimit]
+	}
+	reqBodyBytes, _ := json.Marshal(requestBody)
+	s.db.Create(&database.Log{
+		ID:                requestID,
+		ProxyKey:          proxyKey,
+		Model:             model,
+		RequestedModel:    model,
+		Provider:          cacheProviderName,
+		RequestBody:       string(reqBodyBytes),
+		ResponseBody:      string(logged),
+		ResponseStatus:    http.StatusOK,
+		IsSuccess:         true,
+		Latency:           time.Since(started).Milliseconds(),
+		Timestamp:         time.Now(),
+		ResponseSize:      int64(len(cached.Body)),
+		ResponseTruncated: len(logged) < len(cached.Body),
+		CacheHit:          true,
+		Similarity:        similarity,
+	})
+
+	header := make(http.Header)
+	header.Set("Content-Type", cached.ContentType)
+	header.Set(CacheHeader, "HIT")
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+	}
+}
+
+// cacheOnComplete stores a response under cacheKey once the client has read all of it, and
+// indexes it in the semantic cache when the request was looked up there. Streams are only
+// stored when they ran to [DONE].
+func (s *MultiProviderService) cacheOnComplete(resp *http.Response, cacheKey string, ttl time.Duration, stream bool, semantic *semanticQuery) {
+	if cacheKey == "" {
+		return
+	}
+	resp.Header.Set(CacheHeader, "MISS")
+	contentType := resp.Header.Get("Content-Type")
+	resp.Body = &cacheRecorder{ReadCloser: resp.Body, complete: func(body []byte) {
+		if stream && !bytes.Contains(body, []byte("data: [DONE]")) {
+			return
+		}
+		response := &core.CachedResponse{ContentType: contentType, Body: body, ExpiresAt: time.Now().Add(ttl)}
+		s.responseCache.Set(cacheKey, response)
+		if semantic != nil {
+			s.semanticCache.Add(semantic.model, semantic.context, semantic.vector, response)
+		}
+	}}
+}
+
+// cacheRecorder keeps a copy of a response body as it is read and hands it to complete when
+// the body has been read to the end. Bodies beyond maxCachedBodySize are not kept.
+type cacheRecorder struct {
+	io.ReadCloser
+	complete func(body []byte)
+	buffer   bytes.Buffer
+	overflow bool
+	done     bool
+}
+
+func (r *cacheRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if !r.overflow && n > 0 {
+		if r.buffer.Len()+n > maxCachedBodySize {
+			r.overflow = true
+			r.buffer = bytes.Buffer{}
+		} else {
+			r.buffer.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !r.done {
+		r.done = true
+		if !r.overflow {
+			r.complete(r.buffer.Bytes())
+		}
+	}
+	return n, err
+}
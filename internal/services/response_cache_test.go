@@ -0,0 +1,152 @@
+package services
+
+import (
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	for _, key := range []database.ProxyKey{
+		{UserID: 1, Key: "sk-a", CacheEnabled: true},
+		{UserID: 1, Key: "sk-b", CacheEnabled: true, CacheTTL: 60},
+		{UserID: 1, Key: "sk-off"},
+	} {
+		if err := db.Create(&key).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewMultiProviderService(nil, nil, NewKeyManager(db, nil), nil, nil, nil, db)
+	s.SetResponseCache(NewResponseCache(nil, 10))
+
+	request := func(extra map[string]interface{}) map[string]interface{} {
+		body := map[string]interface{}{
+			"model":       "m",
+			"temperature": 0.0,
+			"messages":    []interface{}{map[string]interface{}{"role": "user", "content": "hi"}},
+		}
+		for field, value := range extra {
+			body[field] = value
+		}
+		return body
+	}
+	cacheKey := func(op upstreamOperation, body map[string]interface{}, proxyKey, header string) (string, time.Duration) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/", nil)
+		if header != "" {
+			c.Request.Header.Set(CacheHeader, header)
+		}
+		return s.responseCacheKey(c, op, body, proxyKey)
+	}
+	base, baseTTL := cacheKey(chatOperation, request(nil), "sk-a", "")
+	if base == "" || baseTTL != DefaultResponseCacheTTL {
+		t.Fatalf("deterministic request not cached: key %q, ttl %v", base, baseTTL)
+	}
+
+	const (
+		same      = "same"
+		different = "different"
+		uncached  = "uncached"
+	)
+	tests := []struct {
+		name     string
+		op       upstreamOperation
+		body     map[string]interface{}
+		proxyKey string
+		header   string
+		want     string
+	}{
+		{"identical request", chatOperation, request(nil), "sk-a", "", same},
+		{"excluded fields", chatOperation, request(map[string]interface{}{"user": "u1", "metadata": map[string]interface{}{"a": "b"}, "store": true}), "sk-a", "", same},
+		{"stream_options", chatOperation, request(map[string]interface{}{"stream_options": map[string]interface{}{"include_usage": true}}), "sk-a", "", different},
+		{"other message", chatOperation, request(map[string]interface{}{"messages": []interface{}{map[string]interface{}{"role": "user", "content": "bye"}}}), "sk-a", "", different},
+		{"stream", chatOperation, request(map[string]interface{}{"stream": true}), "sk-a", "", different},
+		{"other proxy key", chatOperation, request(nil), "sk-b", "", different},
+		{"other operation", embeddingsOperation, request(nil), "sk-a", "", different},
+		{"sampling", chatOperation, request(map[string]interface{}{"temperature": 0.7}), "sk-a", "", uncached},
+		{"sampling marked for caching", chatOperation, request(map[string]interface{}{"temperature": 0.7}), "sk-a", "true", different},
+		{"opted out", chatOperation, request(nil), "sk-a", "false", uncached},
+		{"key without caching", chatOperation, request(nil), "sk-off", "", uncached},
+		{"unknown key", chatOperation, request(nil), "sk-unknown", "", uncached},
+		{"operation without a cache", responsesOperation, request(nil), "sk-a", "true", uncached},
+	}
+	for _, tt := range tests {
+		key, _ := cacheKey(tt.op, tt.body, tt.proxyKey, tt.header)
+		got := different
+		switch key {
+		case "":
+			got = uncached
+		case base:
+			got = same
+		}
+		if got != tt.want {
+			t.Errorf("%s: key is %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, ttl := cacheKey(chatOperation, request(nil), "sk-b", ""); ttl != time.Minute {
+		t.Errorf("ttl %v, want the key's 1m0s", ttl)
+	}
+	s.SetResponseCache(nil)
+	if key, _ := cacheKey(chatOperation, request(nil), "sk-a", ""); key != "" {
+		t.Error("request cached without a configured cache")
+	}
+}
+
+func TestResponseCacheGetSet(t *testing.T) {
+	db := newTestDB(t)
+	rc := NewResponseCache(db, 2)
+	fresh := func(body string) *core.CachedResponse {
+		return &core.CachedResponse{ContentType: "application/json", Body: []byte(body), ExpiresAt: time.Now().Add(time.Hour)}
+	}
+	rc.Set("a", fresh("A"))
+	rc.Set("b", fresh("B"))
+	rc.Get("a") // Now more recently used than b
+	rc.Set("c", fresh("C"))
+	rc.Set("expired", &core.CachedResponse{Body: []byte("X"), ExpiresAt: time.Now().Add(-time.Second)})
+
+	tests := []struct {
+		key  string
+		want string // Empty for a miss
+	}{
+		{"a", "A"},
+		{"b", "B"}, // Evicted from memory, found in the database
+		{"c", "C"},
+		{"expired", ""},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		response, ok := rc.Get(tt.key)
+		got := ""
+		if ok {
+			got = string(response.Body)
+		}
+		if got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+	if stats := rc.Stats(); stats.Hits != 4 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("stats %+v, want 4 hits, 2 misses, 2 entries", stats)
+	}
+
+	// Without a database, an evicted response is gone
+	memory := NewResponseCache(nil, 1)
+	memory.Set("a", fresh("A"))
+	memory.Set("b", fresh("B"))
+	if _, ok := memory.Get("a"); ok {
+		t.Error("evicted response still served")
+	}
+
+	rc.PurgeExpired()
+	var left int64
+	db.Model(&database.ResponseCacheEntry{}).Where("key = ?", "expired").Count(&left)
+	if left != 0 {
+		t.Error("expired response kept in the database")
+	}
+}
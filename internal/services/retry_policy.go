@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"llm-fusion-engine/internal/database"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultMaxAttempts bounds the upstream attempts of a request whose model cannot be read.
+const defaultMaxAttempts = 5
+
+// Default backoff between retries on the same mapping.
+const (
+	defaultRetryBackoff    = 500 * time.Millisecond
+	defaultRetryMaxBackoff = 10 * time.Second
+)
+
+// defaultRetryStatusCodes are the upstream statuses retried unless a policy lists its own.
+var defaultRetryStatusCodes = []int{429, 500, 502, 503, 504}
+
+// retryPolicy decides whether and how a failed upstream attempt is retried. Attempts that are
+// retried move to the next mapping, except for the first sameProviderRetries retries of a
+// mapping, which wait for a backoff and go to the same mapping again.
+type retryPolicy struct {
+	maxAttempts         int // Upstream attempts per request, the first one included
+	sameProviderRetries int
+	backoff             time.Duration // Wait before the first retry on a mapping, doubled for each further one
+	maxBackoff          time.Duration // Upper bound of a wait; a longer Retry-After fails over instead
+	statusCodes         map[int]bool
+	retryTimeouts       bool // Connection, first byte and upstream timeouts
+	retryNetworkErrors  bool // Any other error that prevented a response
+}
+
+// retryPolicySettings is the JSON form of a policy, used by the model's RetryPolicy and by the
+// "retryPolicy" object of a provider config. Unset fields keep the value they override.
+type retryPolicySettings struct {
+	StatusCodes         []int `json:"statusCodes"`
+	SameProviderRetries *int  `json:"sameProviderRetries"`
+	BackoffMs           *int  `json:"backoffMs"`
+	MaxBackoffMs        *int  `json:"maxBackoffMs"`
+	RetryTimeouts       *bool `json:"retryTimeouts"`
+	RetryNetworkErrors  *bool `json:"retryNetworkErrors"`
+}
+
+// resolveRetryPolicy builds the policy for an attempt on a provider: the defaults, overridden
+// by the model's RetryPolicy and then by the provider config's retryPolicy. The number of
+// attempts comes from the model's MaxRetry.
+func resolveRetryPolicy(model *database.Model, providerConfig map[string]interface{}) retryPolicy {
+	policy := retryPolicy{
+		maxAttempts:        defaultMaxAttempts,
+		backoff:            defaultRetryBackoff,
+		maxBackoff:         defaultRetryMaxBackoff,
+		statusCodes:        make(map[int]bool, len(defaultRetryStatusCodes)),
+		retryTimeouts:      true,
+		retryNetworkErrors: true,
+	}
+	for _, code := range defaultRetryStatusCodes {
+		policy.statusCodes[code] = true
+	}
+
+	if model != nil {
+		policy.maxAttempts = model.MaxRetry + 1
+		if policy.maxAttempts < 1 {
+			policy.maxAttempts = 1
+		}
+		var settings retryPolicySettings
+		if model.RetryPolicy != "" && json.Unmarshal([]byte(model.RetryPolicy), &settings) == nil {
+			policy.apply(settings)
+		}
+	}
+	if raw, ok := providerConfig["retryPolicy"]; ok {
+		// The provider config is already decoded; re-encode the object to read it as settings
+		var settings retryPolicySettings
+		if encoded, err := json.Marshal(raw); err == nil && json.Unmarshal(encoded, &settings) == nil {
+			policy.apply(settings)
+		}
+	}
+	return policy
+}
+
+func (p *retryPolicy) apply(settings retryPolicySettings) {
+	if settings.StatusCodes != nil {
+		p.statusCodes = make(map[int]bool, len(settings.StatusCodes))
+		for _, code := range settings.StatusCodes {
+			p.statusCodes[code] = true
+		}
+	}
+	if settings.SameProviderRetries != nil && *settings.SameProviderRetries >= 0 {
+		p.sameProviderRetries = *settings.SameProviderRetries
+	}
+	if settings.BackoffMs != nil && *settings.BackoffMs >= 0 {
+		p.backoff = time.Duration(*settings.BackoffMs) * time.Millisecond
+	}
+	if settings.MaxBackoffMs != nil && *settings.MaxBackoffMs >= 0 {
+		p.maxBackoff = time.Duration(*settings.MaxBackoffMs) * time.Millisecond
+	}
+	if settings.RetryTimeouts != nil {
+		p.retryTimeouts = *settings.RetryTimeouts
+	}
+	if settings.RetryNetworkErrors != nil {
+		p.retryNetworkErrors = *settings.RetryNetworkErrors
+	}
+}
+
+// retriesStatus reports whether an upstream error status is worth another attempt.
+func (p retryPolicy) retriesStatus(statusCode int) bool {
+	return p.statusCodes[statusCode]
+}
+
+// retriesError reports whether an attempt that got no response is worth another attempt.
+func (p retryPolicy) retriesError(err error, firstByteExpired bool) bool {
+	if firstByteExpired || isTimeoutError(err) {
+		return p.retryTimeouts
+	}
+	return p.retryNetworkErrors
+}
+
+// sameProviderDelay reports whether a mapping that has been retried the given number of times
+// is tried again, and how long to wait first. The exponential backoff is jittered and raised
+// to the upstream's Retry-After; when that exceeds maxBackoff the request fails over instead.
+func (p retryPolicy) sameProviderDelay(retries int, retryAfter time.Duration) (time.Duration, bool) {
+	if retries >= p.sameProviderRetries || retryAfter > p.maxBackoff {
+		return 0, false
+	}
+	delay := p.backoff << uint(retries)
+	if delay > p.maxBackoff || delay < p.backoff {
+		delay = p.maxBackoff
+	}
+	if delay > 0 {
+		// Equal jitter: somewhere between half and all of the backoff
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay, true
+}
+
+// isTimeoutError reports whether an upstream call failed because something took too long.
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"llm-fusion-engine/internal/database"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSameProviderDelay(t *testing.T) {
+	policy := retryPolicy{sameProviderRetries: 3, backoff: 100 * time.Millisecond, maxBackoff: time.Second}
+	tests := []struct {
+		name       string
+		policy     retryPolicy
+		retries    int
+		retryAfter time.Duration
+		wantOK     bool
+		min, max   time.Duration
+	}{
+		{"first retry", policy, 0, 0, true, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"backoff doubles", policy, 2, 0, true, 200 * time.Millisecond, 400 * time.Millisecond},
+		{"retries used up", policy, 3, 0, false, 0, 0},
+		{"no same-provider retries", retryPolicy{backoff: time.Second, maxBackoff: time.Second}, 0, 0, false, 0, 0},
+		{"Retry-After raises the wait", policy, 0, 700 * time.Millisecond, true, 700 * time.Millisecond, 700 * time.Millisecond},
+		{"short Retry-After keeps the backoff", policy, 2, time.Millisecond, true, 200 * time.Millisecond, 400 * time.Millisecond},
+		{"Retry-After beyond the cap fails over", policy, 0, 2 * time.Second, false, 0, 0},
+		{"capped at the max backoff", retryPolicy{sameProviderRetries: 100, backoff: 100 * time.Millisecond, maxBackoff: time.Second}, 5, 0, true, 500 * time.Millisecond, time.Second},
+		{"shift overflow is capped", retryPolicy{sameProviderRetries: 100, backoff: 100 * time.Millisecond, maxBackoff: time.Second}, 70, 0, true, 500 * time.Millisecond, time.Second},
+		{"zero backoff", retryPolicy{sameProviderRetries: 1}, 0, 0, true, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The jitter is random; sample it a few times
+			for i := 0; i < 20; i++ {
+				delay, ok := tt.policy.sameProviderDelay(tt.retries, tt.retryAfter)
+				if ok != tt.wantOK {
+					t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+				}
+				if delay < tt.min || delay > tt.max {
+					t.Fatalf("delay %v, want between %v and %v", delay, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		min, max time.Duration
+	}{
+		{"missing", "", 0, 0},
+		{"seconds", "3", 3 * time.Second, 3 * time.Second},
+		{"negative", "-1", 0, 0},
+		{"garbage", "soon", 0, 0},
+		{"http date", time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second},
+		{"date in the past", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.value != "" {
+			header.Set("Retry-After", tt.value)
+		}
+		if got := parseRetryAfter(header); got < tt.min || got > tt.max {
+			t.Errorf("%s: parseRetryAfter(%q) = %v, want between %v and %v", tt.name, tt.value, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestResolveRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		model          *database.Model
+		config         map[string]interface{}
+		wantAttempts   int
+		wantSame       int
+		wantBackoff    time.Duration
+		wantRetried    []int
+		wantNotRetried []int
+	}{
+		{"defaults without a model", nil, nil, defaultMaxAttempts, 0, defaultRetryBackoff, []int{429, 500, 502, 503, 504}, []int{400, 401, 404}},
+		{"attempts from MaxRetry", &database.Model{MaxRetry: 2}, nil, 3, 0, defaultRetryBackoff, []int{503}, nil},
+		{"negative MaxRetry still sends once", &database.Model{MaxRetry: -4}, nil, 1, 0, defaultRetryBackoff, nil, nil},
+		{"model policy", &database.Model{MaxRetry: 1, RetryPolicy: `{"statusCodes":[503],"sameProviderRetries":2,"backoffMs":50}`}, nil,
+			2, 2, 50 * time.Millisecond, []int{503}, []int{429, 500}},
+		{"provider overrides the model", &database.Model{RetryPolicy: `{"statusCodes":[503],"sameProviderRetries":2}`},
+			map[string]interface{}{"retryPolicy": map[string]interface{}{"sameProviderRetries": 0.0, "statusCodes": []interface{}{429.0}}},
+			1, 0, defaultRetryBackoff, []int{429}, []int{503}},
+		{"unparseable model policy is ignored", &database.Model{RetryPolicy: `{`}, nil, 1, 0, defaultRetryBackoff, []int{500}, nil},
+		{"negative values are ignored", &database.Model{RetryPolicy: `{"sameProviderRetries":-1,"backoffMs":-5}`}, nil, 1, 0, defaultRetryBackoff, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := resolveRetryPolicy(tt.model, tt.config)
+			if policy.maxAttempts != tt.wantAttempts || policy.sameProviderRetries != tt.wantSame || policy.backoff != tt.wantBackoff {
+				t.Errorf("attempts %d, same-provider retries %d, backoff %v; want %d, %d, %v",
+					policy.maxAttempts, policy.sameProviderRetries, policy.backoff, tt.wantAttempts, tt.wantSame, tt.wantBackoff)
+			}
+			for _, code := range tt.wantRetried {
+				if !policy.retriesStatus(code) {
+					t.Errorf("status %d not retried", code)
+				}
+			}
+			for _, code := range tt.wantNotRetried {
+				if policy.retriesStatus(code) {
+					t.Errorf("status %d retried", code)
+				}
+			}
+		})
+	}
+}
+
+func TestRetriesError(t *testing.T) {
+	noTimeouts := resolveRetryPolicy(nil, map[string]interface{}{"retryPolicy": map[string]interface{}{"retryTimeouts": false}})
+	noNetwork := resolveRetryPolicy(nil, map[string]interface{}{"retryPolicy": map[string]interface{}{"retryNetworkErrors": false}})
+	refused := errors.New("connection refused")
+
+	tests := []struct {
+		name             string
+		policy           retryPolicy
+		err              error
+		firstByteExpired bool
+		want             bool
+	}{
+		{"timeout", noNetwork, context.DeadlineExceeded, false, true},
+		{"timeout not retried", noTimeouts, context.DeadlineExceeded, false, false},
+		{"first byte timeout not retried", noTimeouts, context.Canceled, true, false},
+		{"network error", noTimeouts, refused, false, true},
+		{"network error not retried", noNetwork, refused, false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.retriesError(tt.err, tt.firstByteExpired); got != tt.want {
+			t.Errorf("%s: retriesError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
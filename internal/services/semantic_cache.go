@@ -0,0 +1,235 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/providers"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultSemanticCacheSize is how many prompts the semantic cache indexes per model.
+const DefaultSemanticCacheSize = 1000
+
+// DefaultSemanticCacheThreshold is the cosine similarity a prompt needs to reuse a cached
+// response when its model sets no threshold.
+const DefaultSemanticCacheThreshold = 0.95
+
+// SemanticCache implements core.ISemanticCache with an in-memory index per model, searched
+// linearly. Each model keeps its most recent prompts up to the capacity.
+type SemanticCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	indexes map[string][]*semanticEntry // By model, oldest first
+}
+
+type semanticEntry struct {
+	context  string
+	vector   []float64 // Normalized to unit length
+	response *core.CachedResponse
+}
+
+// NewSemanticCache creates a semantic cache indexing up to capacity prompts per model.
+func NewSemanticCache(capacity int) *SemanticCache {
+	if capacity <= 0 {
+		capacity = DefaultSemanticCacheSize
+	}
+	return &SemanticCache{capacity: capacity, indexes: make(map[string][]*semanticEntry)}
+}
+
+// Search returns the most similar unexpired response for the model and context. The
+// similarity is reported even when the caller's threshold rejects it, so that thresholds can
+// be tuned from the logs. Expired entries are dropped on the way.
+func (sc *SemanticCache) Search(model, context string, vector []float64) (*core.CachedResponse, float64) {
+	query := normalize(vector)
+	if query == nil {
+		return nil, 0
+	}
+	now := time.Now()
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var best *core.CachedResponse
+	bestSimilarity := 0.0
+	index := sc.indexes[model]
+	kept := index[:0]
+	for _, entry := range index {
+		if !now.Before(entry.response.ExpiresAt) {
+			continue
+		}
+		kept = append(kept, entry)
+		// Vectors of another embedding model (after a configuration change) are not comparable
+		if entry.context != context || len(entry.vector) != len(query) {
+			continue
+		}
+		if similarity := dot(entry.vector, query); best == nil || similarity > bestSimilarity {
+			best, bestSimilarity = entry.response, similarity
+		}
+	}
+	sc.indexes[model] = kept
+	return best, bestSimilarity
+}
+
+// Add indexes a response, dropping the model's oldest prompt when the index is full.
+func (sc *SemanticCache) Add(model, context string, vector []float64, response *core.CachedResponse) {
+	normalized := normalize(vector)
+	if normalized == nil {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	index := append(sc.indexes[model], &semanticEntry{context: context, vector: normalized, response: response})
+	if len(index) > sc.capacity {
+		index = index[len(index)-sc.capacity:]
+	}
+	sc.indexes[model] = index
+}
+
+// normalize returns a unit-length copy of vector, or nil for an empty or zero vector.
+func normalize(vector []float64) []float64 {
+	norm := math.Sqrt(dot(vector, vector))
+	if norm == 0 {
+		return nil
+	}
+	normalized := make([]float64, len(vector))
+	for i, value := range vector {
+		normalized[i] = value / norm
+	}
+	return normalized
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// semanticQuery is a chat request looked up in the semantic cache: the model it is indexed
+// under, the hash of everything but its last user message, the embedding of that message,
+// and the best similarity the search found.
+type semanticQuery struct {
+	model      string
+	context    string
+	vector     []float64
+	similarity float64
+}
+
+// semanticLookup searches the semantic cache for a chat request that is cached under cacheKey
+// but missed the exact cache. It returns nil when the model has no semantic cache or the
+// prompt could not be embedded, and the cached response when one is similar enough.
+func (s *MultiProviderService) semanticLookup(c *gin.Context, op upstreamOperation, requestBody map[string]interface{}, proxyKey, model, cacheKey string) (*semanticQuery, *core.CachedResponse) {
+	if s.semanticCache == nil || cacheKey == "" || op.cacheScope != chatOperation.cacheScope {
+		return nil, nil
+	}
+	modelRecord := s.findModel(model)
+	if modelRecord == nil || modelRecord.SemanticCacheModel == "" {
+		return nil, nil
+	}
+	prompt, context, ok := semanticPrompt(requestBody, proxyKey)
+	if !ok {
+		return nil, nil
+	}
+
+	vector, err := s.embedPrompt(c, modelRecord.SemanticCacheModel, prompt, proxyKey)
+	if err != nil {
+		log.Printf("[MultiProviderService] Semantic cache lookup for model %s skipped: %v", model, err)
+		return nil, nil
+	}
+	query := &semanticQuery{model: model, context: context, vector: vector}
+	cached, similarity := s.semanticCache.Search(query.model, query.context, vector)
+	query.similarity = similarity
+
+	threshold := modelRecord.SemanticCacheThreshold
+	if threshold <= 0 {
+		threshold = DefaultSemanticCacheThreshold
+	}
+	if cached != nil && similarity >= threshold {
+		return query, cached
+	}
+	return query, nil
+}
+
+// semanticPrompt splits a chat request into the text of its last user message and a hash of
+// everything else, which must match exactly for a cached response to be reused: earlier
+// messages, sampling parameters, stream and the proxy key. ok is false when the request has no
+// user message with text.
+func semanticPrompt(requestBody map[string]interface{}, proxyKey string) (prompt, context string, ok bool) {
+	messages, _ := requestBody["messages"].([]interface{})
+	last := lastUserMessage(messages)
+	if last < 0 {
+		return "", "", false
+	}
+	prompt = providers.MessageText(messages[last].(map[string]interface{})["content"])
+	if prompt == "" {
+		return "", "", false
+	}
+
+	canonical := make(map[string]interface{}, len(requestBody))
+	for field, value := range requestBody {
+		if !cacheExcludedFields[field] {
+			canonical[field] = value
+		}
+	}
+	canonical["messages"] = append(append([]interface{}{}, messages[:last]...), messages[last+1:]...)
+	encoded, err := json.Marshal(canonical)
+	if err != nil {
+		return "", "", false
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n", proxyKey)
+	hash.Write(encoded)
+	return prompt, hex.EncodeToString(hash.Sum(nil)), true
+}
+
+// embedPrompt embeds a prompt through the mappings of the embedding model. The call has a
+// scope of its own so that the embedding model's timeout does not bound the chat request.
+func (s *MultiProviderService) embedPrompt(c *gin.Context, embeddingModel, prompt, proxyKey string) ([]float64, error) {
+	scope := newRequestScope(c.Request.Context())
+	defer scope.finish()
+	var excludedProviders []uint
+	requestBody := map[string]interface{}{"model": embeddingModel, "input": prompt, "encoding_format": "float"}
+	resp, _, err := s.dispatch(c, scope, embeddingsOperation, requestBody, embeddingModel, proxyKey, &excludedProviders, false)
+	if err != nil {
+		return nil, err
+	}
+	// Read to the end so that the attempt's log entry is completed
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Data) == 0 || len(result.Data[0].Embedding) == 0 {
+		return nil, errors.New("embedding response has no vector")
+	}
+	return result.Data[0].Embedding, nil
+}
+
+// lastUserMessage returns the index of the last user message, or -1 if there is none.
+func lastUserMessage(messages []interface{}) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if message, ok := messages[i].(map[string]interface{}); ok && message["role"] == "user" {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"llm-fusion-engine/internal/core"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSemanticPrompt(t *testing.T) {
+	user := func(text string) map[string]interface{} {
+		return map[string]interface{}{"role": "user", "content": text}
+	}
+	system := map[string]interface{}{"role": "system", "content": "be brief"}
+	request := func(messages []interface{}, extra map[string]interface{}) map[string]interface{} {
+		body := map[string]interface{}{"model": "m", "temperature": 0.0, "messages": messages}
+		for field, value := range extra {
+			body[field] = value
+		}
+		return body
+	}
+	_, base, ok := semanticPrompt(request([]interface{}{system, user("What is Go?")}, nil), "sk-a")
+	if !ok {
+		t.Fatal("request with a user message not looked up")
+	}
+
+	tests := []struct {
+		name        string
+		body        map[string]interface{}
+		proxyKey    string
+		wantOK      bool
+		wantPrompt  string
+		sameContext bool
+	}{
+		{"other wording of the prompt", request([]interface{}{system, user("Tell me about Go")}, nil), "sk-a", true, "Tell me about Go", true},
+		{"excluded fields", request([]interface{}{system, user("What is Go?")}, map[string]interface{}{"user": "u1"}), "sk-a", true, "What is Go?", true},
+		{"content parts", request([]interface{}{system, map[string]interface{}{"role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "What"}, map[string]interface{}{"type": "text", "text": "is Go?"}}}}, nil),
+			"sk-a", true, "What\nis Go?", true},
+		{"other system prompt", request([]interface{}{map[string]interface{}{"role": "system", "content": "be verbose"}, user("What is Go?")}, nil), "sk-a", true, "What is Go?", false},
+		{"earlier turns", request([]interface{}{system, user("Hi"), map[string]interface{}{"role": "assistant", "content": "Hello"}, user("What is Go?")}, nil), "sk-a", true, "What is Go?", false},
+		{"assistant prefill after the prompt", request([]interface{}{system, user("What is Go?"), map[string]interface{}{"role": "assistant", "content": "Go is"}}, nil), "sk-a", true, "What is Go?", false},
+		{"sampling parameters", request([]interface{}{system, user("What is Go?")}, map[string]interface{}{"temperature": 0.5}), "sk-a", true, "What is Go?", false},
+		{"stream", request([]interface{}{system, user("What is Go?")}, map[string]interface{}{"stream": true}), "sk-a", true, "What is Go?", false},
+		{"other proxy key", request([]interface{}{system, user("What is Go?")}, nil), "sk-b", true, "What is Go?", false},
+		{"no user message", request([]interface{}{system}, nil), "sk-a", false, "", false},
+		{"empty prompt", request([]interface{}{system, user("")}, nil), "sk-a", false, "", false},
+		{"no messages", map[string]interface{}{"model": "m"}, "sk-a", false, "", false},
+	}
+	for _, tt := range tests {
+		prompt, context, ok := semanticPrompt(tt.body, tt.proxyKey)
+		if ok != tt.wantOK || prompt != tt.wantPrompt {
+			t.Errorf("%s: prompt %q ok %v, want %q %v", tt.name, prompt, ok, tt.wantPrompt, tt.wantOK)
+			continue
+		}
+		if ok && (context == base) != tt.sameContext {
+			t.Errorf("%s: same context %v, want %v", tt.name, context == base, tt.sameContext)
+		}
+	}
+}
+
+func TestSemanticCacheSearch(t *testing.T) {
+	response := func(body string, ttl time.Duration) *core.CachedResponse {
+		return &core.CachedResponse{Body: []byte(body), ExpiresAt: time.Now().Add(ttl)}
+	}
+	sc := NewSemanticCache(10)
+	sc.Add("m", "ctx", []float64{1, 0, 0}, response("x", time.Hour))
+	sc.Add("m", "ctx", []float64{0.8, 0.6, 0}, response("xy", time.Hour))
+	sc.Add("m", "ctx", []float64{0, 0, 1}, response("expired", -time.Second))
+	sc.Add("m", "other-ctx", []float64{0, 1, 0}, response("other context", time.Hour))
+	sc.Add("m", "ctx", []float64{0, 0}, response("zero vector", time.Hour))
+
+	tests := []struct {
+		name           string
+		model, context string
+		vector         []float64
+		want           string // Empty when nothing is found
+		similarity     float64
+	}{
+		{"exact match", "m", "ctx", []float64{1, 0, 0}, "x", 1},
+		{"length does not matter", "m", "ctx", []float64{8, 6, 0}, "xy", 1},
+		{"most similar wins", "m", "ctx", []float64{0.6, 0.8, 0}, "xy", 0.96},
+		{"dissimilar is still reported", "m", "ctx", []float64{0, 1, 0}, "xy", 0.6},
+		{"context must match", "m", "other-ctx", []float64{1, 0, 0}, "other context", 0},
+		{"other model", "n", "ctx", []float64{1, 0, 0}, "", 0},
+		{"other dimensions", "m", "ctx", []float64{1, 0}, "", 0},
+		{"zero query", "m", "ctx", []float64{0, 0, 0}, "", 0},
+		{"expired entries are skipped", "m", "ctx", []float64{0, 0, 1}, "x", 0},
+	}
+	for _, tt := range tests {
+		cached, similarity := sc.Search(tt.model, tt.context, tt.vector)
+		got := ""
+		if cached != nil {
+			got = string(cached.Body)
+		}
+		if got != tt.want || math.Abs(similarity-tt.similarity) > 1e-9 {
+			t.Errorf("%s: found %q with similarity %v, want %q with %v", tt.name, got, similarity, tt.want, tt.similarity)
+		}
+	}
+
+	sc.mu.Lock()
+	entries := len(sc.indexes["m"])
+	sc.mu.Unlock()
+	if entries != 3 {
+		t.Errorf("%d entries left for m, want 3 once the expired one is dropped", entries)
+	}
+}
+
+func TestSemanticCacheCapacity(t *testing.T) {
+	sc := NewSemanticCache(2)
+	for i, body := range []string{"first", "second", "third"} {
+		vector := []float64{0, 0, 0}
+		vector[i] = 1
+		sc.Add("m", "ctx", vector, &core.CachedResponse{Body: []byte(body), ExpiresAt: time.Now().Add(time.Hour)})
+	}
+	if cached, similarity := sc.Search("m", "ctx", []float64{1, 0, 0}); similarity != 0 || cached == nil || string(cached.Body) == "first" {
+		t.Errorf("oldest prompt still indexed")
+	}
+	if cached, similarity := sc.Search("m", "ctx", []float64{0, 0, 1}); similarity != 1 || string(cached.Body) != "third" {
+		t.Errorf("newest prompt not found")
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"llm-fusion-engine/internal/core"
+	"llm-fusion-engine/internal/database"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultSessionTTL is how long an access token stays valid after it is issued.
+	DefaultSessionTTL = 12 * time.Hour
+	// DefaultRefreshTTL is how long a refresh token can be exchanged for a new session.
+	DefaultRefreshTTL = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidSession is returned when a token does not match any active session.
+	ErrInvalidSession = errors.New("invalid or revoked session")
+	// ErrSessionExpired is returned when a token matches a session that has expired.
+	ErrSessionExpired = errors.New("session expired")
+)
+
+// SessionManager implements the ISessionManager interface using the sessions table.
+type SessionManager struct {
+	db         *gorm.DB
+	sessionTTL time.Duration
+	refreshTTL time.Duration
+}
+
+// NewSessionManager creates a new SessionManager with the default token lifetimes.
+func NewSessionManager(db *gorm.DB) *SessionManager {
+	return &SessionManager{
+		db:         db,
+		sessionTTL: DefaultSessionTTL,
+		refreshTTL: DefaultRefreshTTL,
+	}
+}
+
+// CreateSession issues a new access/refresh token pair for the given user.
+func (sm *SessionManager) CreateSession(userID uint, clientIP, userAgent string) (*core.SessionTokens, error) {
+	accessToken, err := generateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := generateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := database.Session{
+		UserID:           userID,
+		TokenHash:        hashToken(accessToken),
+		RefreshTokenHash: hashToken(refreshToken),
+		ExpiresAt:        now.Add(sm.sessionTTL),
+		RefreshExpiresAt: now.Add(sm.refreshTTL),
+		LastUsedAt:       now,
+		ClientIP:         clientIP,
+		UserAgent:        userAgent,
+	}
+	if err := sm.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	return &core.SessionTokens{
+		SessionID:        session.ID,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresAt:        session.ExpiresAt,
+		RefreshExpiresAt: session.RefreshExpiresAt,
+	}, nil
+}
+
+// ValidateSession resolves an access token to its session and user.
+func (sm *SessionManager) ValidateSession(accessToken string) (*database.Session, error) {
+	if accessToken == "" {
+		return nil, ErrInvalidSession
+	}
+
+	var session database.Session
+	err := sm.db.Preload("User").
+		Where("token_hash = ? AND revoked_at IS NULL", hashToken(accessToken)).
+		First(&session).Error
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	if session.User.ID == 0 {
+		// The owning user has been deleted.
+		return nil, ErrInvalidSession
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+
+	// Throttle last-used bookkeeping so every admin call doesn't write to the database.
+	if time.Since(session.LastUsedAt) > time.Minute {
+		sm.db.Model(&database.Session{}).Where("id = ?", session.ID).Update("last_used_at", time.Now())
+	}
+
+	return &session, nil
+}
+
+// RefreshSession rotates a session: the old tokens are revoked and a new pair is issued.
+func (sm *SessionManager) RefreshSession(refreshToken, clientIP, userAgent string) (*core.SessionTokens, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidSession
+	}
+
+	var session database.Session
+	err := sm.db.Where("refresh_token_hash = ? AND revoked_at IS NULL", hashToken(refreshToken)).
+		First(&session).Error
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	if time.Now().After(session.RefreshExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+
+	// Only the refresh that revokes the session may replace it; a concurrent one with the same
+	// token finds it already revoked
+	result := sm.db.Model(&database.Session{}).
+		Where("id = ? AND revoked_at IS NULL", session.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidSession
+	}
+	return sm.CreateSession(session.UserID, clientIP, userAgent)
+}
+
+// RevokeSession revokes the session identified by the given ID.
+func (sm *SessionManager) RevokeSession(sessionID uint) error {
+	return sm.revoke(sm.db.Where("id = ?", sessionID))
+}
+
+// RevokeUserSessions revokes every active session of a user except the one given in keepSessionID (0 revokes all).
+func (sm *SessionManager) RevokeUserSessions(userID uint, keepSessionID uint) error {
+	query := sm.db.Where("user_id = ?", userID)
+	if keepSessionID != 0 {
+		query = query.Where("id <> ?", keepSessionID)
+	}
+	return sm.revoke(query)
+}
+
+// revoke marks all active sessions matched by the query as revoked.
+func (sm *SessionManager) revoke(query *gorm.DB) error {
+	return query.Model(&database.Session{}).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+// PurgeExpiredSessions permanently deletes sessions whose refresh window has passed.
+func (sm *SessionManager) PurgeExpiredSessions() {
+	result := sm.db.Unscoped().Where("refresh_expires_at < ?", time.Now()).Delete(&database.Session{})
+	if result.Error != nil {
+		log.Printf("[Session] Failed to purge expired sessions: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("[Session] Purged %d expired sessions", result.RowsAffected)
+	}
+}
+
+// SchedulePeriodicCleanup purges expired sessions at the given interval.
+func (sm *SessionManager) SchedulePeriodicCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			sm.PurgeExpiredSessions()
+		}
+	}()
+}
+
+// generateSecureToken creates a random, URL-safe opaque token.
+func generateSecureToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 digest used to look tokens up.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"llm-fusion-engine/internal/database"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newTestDB opens a migrated in-memory database private to the test.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.InitDatabase(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestSessionManagerRefresh(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(db *gorm.DB, sessionID uint)
+		token   func(access, refresh string) string
+		wantErr error
+	}{
+		{
+			name:  "rotates the session",
+			token: func(_, refresh string) string { return refresh },
+		},
+		{
+			name:    "empty token",
+			token:   func(_, _ string) string { return "" },
+			wantErr: ErrInvalidSession,
+		},
+		{
+			name:    "access token is not a refresh token",
+			token:   func(access, _ string) string { return access },
+			wantErr: ErrInvalidSession,
+		},
+		{
+			name: "revoked session",
+			prepare: func(db *gorm.DB, sessionID uint) {
+				db.Model(&database.Session{}).Where("id = ?", sessionID).Update("revoked_at", time.Now())
+			},
+			token:   func(_, refresh string) string { return refresh },
+			wantErr: ErrInvalidSession,
+		},
+		{
+			name: "expired refresh window",
+			prepare: func(db *gorm.DB, sessionID uint) {
+				db.Model(&database.Session{}).Where("id = ?", sessionID).Update("refresh_expires_at", time.Now().Add(-time.Minute))
+			},
+			token:   func(_, refresh string) string { return refresh },
+			wantErr: ErrSessionExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			sm := NewSessionManager(db)
+			tokens, err := sm.CreateSession(1, "127.0.0.1", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prepare != nil {
+				tt.prepare(db, tokens.SessionID)
+			}
+
+			refreshed, err := sm.RefreshSession(tt.token(tokens.AccessToken, tokens.RefreshToken), "127.0.0.1", "test")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RefreshSession error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if _, err := sm.ValidateSession(refreshed.AccessToken); err != nil {
+				t.Errorf("new access token rejected: %v", err)
+			}
+			if _, err := sm.ValidateSession(tokens.AccessToken); !errors.Is(err, ErrInvalidSession) {
+				t.Errorf("old access token: error %v, want ErrInvalidSession", err)
+			}
+			if _, err := sm.RefreshSession(tokens.RefreshToken, "127.0.0.1", "test"); !errors.Is(err, ErrInvalidSession) {
+				t.Errorf("reused refresh token: error %v, want ErrInvalidSession", err)
+			}
+		})
+	}
+}
+
+func TestSessionManagerConcurrentRefresh(t *testing.T) {
+	sm := NewSessionManager(newTestDB(t))
+	tokens, err := sm.CreateSession(1, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only one of several refreshes racing with the same token may get a new session
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := sm.RefreshSession(tokens.RefreshToken, "127.0.0.1", "test"); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Errorf("%d refreshes succeeded, want 1", succeeded)
+	}
+}
+
+func TestSessionManagerValidate(t *testing.T) {
+	db := newTestDB(t)
+	sm := NewSessionManager(db)
+	active, _ := sm.CreateSession(1, "", "")
+	expired, _ := sm.CreateSession(1, "", "")
+	db.Model(&database.Session{}).Where("id = ?", expired.SessionID).Update("expires_at", time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"active", active.AccessToken, nil},
+		{"expired", expired.AccessToken, ErrSessionExpired},
+		{"unknown", "not-a-token", ErrInvalidSession},
+		{"empty", "", ErrInvalidSession},
+	}
+	for _, tt := range tests {
+		if _, err := sm.ValidateSession(tt.token); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSessionManagerRevokeUserSessions(t *testing.T) {
+	sm := NewSessionManager(newTestDB(t))
+	current, _ := sm.CreateSession(1, "", "")
+	other, _ := sm.CreateSession(1, "", "")
+
+	if err := sm.RevokeUserSessions(1, current.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sm.ValidateSession(current.AccessToken); err != nil {
+		t.Errorf("kept session rejected: %v", err)
+	}
+	if _, err := sm.ValidateSession(other.AccessToken); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("other session: error %v, want ErrInvalidSession", err)
+	}
+
+	if err := sm.RevokeUserSessions(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sm.ValidateSession(current.AccessToken); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("session survived revoking all: error %v", err)
+	}
+}
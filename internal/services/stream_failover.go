@@ -0,0 +1,232 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"llm-fusion-engine/internal/constants"
+	"strings"
+)
+
+// maxStreamFailovers bounds how often one client stream may switch to another mapping.
+const maxStreamFailovers = 3
+
+// failoverStream is the body of a streaming chat completion that can continue on another
+// mapping when the upstream breaks before the stream completes. It forwards whole SSE events,
+// so a partially received event is never sent to the client. A stream counts as complete once
+// [DONE] or a finish_reason has been seen.
+//
+// In retry mode it fails over only while no content has been forwarded. In resume mode it
+// replays the forwarded content as an assistant prefix, which requires a single choice
+// without tool calls.
+type failoverStream struct {
+	mode   constants.StreamFailoverMode
+	body   io.ReadCloser
+	reader *bufio.Reader
+	// reopen requests a new stream from the next mapping; prefix is the content to resume from
+	reopen func(prefix string, cause error) (io.ReadCloser, error)
+
+	event     bytes.Buffer    // Lines of the event being received
+	pending   []byte          // Complete events not yet handed to the reader
+	content   strings.Builder // Assistant content forwarded so far
+	forwarded bool            // Whether any chunk has been forwarded
+	sent      bool            // Whether any content or tool call has been forwarded
+	resumable bool            // Whether the forwarded output can be replayed as a prefix
+	finished  bool
+	resuming  bool // Drop role-only chunks of a resumed stream until its first content
+	failovers int
+	err       error
+}
+
+func newFailoverStream(body io.ReadCloser, mode constants.StreamFailoverMode, reopen func(prefix string, cause error) (io.ReadCloser, error)) *failoverStream {
+	return &failoverStream{
+		mode:      mode,
+		body:      body,
+		reader:    bufio.NewReader(body),
+		reopen:    reopen,
+		resumable: true,
+	}
+}
+
+// Read hands out complete events, pulling more from the upstream as needed.
+func (f *failoverStream) Read(p []byte) (int, error) {
+	for len(f.pending) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		f.fill()
+	}
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
+
+// Close closes the current upstream body.
+func (f *failoverStream) Close() error {
+	return f.body.Close()
+}
+
+// fill reads one line from the upstream and forwards the event it completes.
+func (f *failoverStream) fill() {
+	line, err := f.reader.ReadBytes('\n')
+	f.event.Write(line)
+	if err == nil {
+		if len(bytes.TrimSpace(line)) == 0 {
+			if held := f.forwardEvent(); held != nil && !f.failover(errors.New("upstream sent an error event")) {
+				f.pending = append(f.pending, held...)
+			}
+		}
+		return
+	}
+
+	// The upstream ended: forward a trailing event that completes the stream
+	if f.event.Len() > 0 && (f.finished || isTerminalEvent(f.event.Bytes())) {
+		f.forwardEvent()
+	}
+	if f.finished || !f.canFailover() {
+		if f.event.Len() > 0 {
+			f.pending = append(f.pending, f.event.Bytes()...)
+			f.event.Reset()
+		}
+		f.err = err
+		return
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if !f.failover(err) {
+		f.err = err
+	}
+}
+
+// failover replaces the upstream with a stream from the next mapping and reports whether
+// that succeeded. A partially received event of the broken upstream is discarded.
+func (f *failoverStream) failover(cause error) bool {
+	prefix := ""
+	if f.mode == constants.StreamFailoverResume {
+		prefix = f.content.String()
+	}
+	f.failovers++
+	body, err := f.reopen(prefix, cause)
+	if err != nil {
+		return false
+	}
+	f.body.Close()
+	f.body = body
+	f.reader = bufio.NewReader(body)
+	f.event.Reset()
+	f.resuming = f.forwarded
+	return true
+}
+
+// canFailover reports whether the configured mode allows switching mappings now.
+func (f *failoverStream) canFailover() bool {
+	if f.failovers >= maxStreamFailovers {
+		return false
+	}
+	switch f.mode {
+	case constants.StreamFailoverRetry:
+		return !f.sent
+	case constants.StreamFailoverResume:
+		return !f.sent || f.resumable
+	}
+	return false
+}
+
+// forwardEvent inspects the buffered event and queues it for the client. An upstream error
+// event is returned instead of queued while a failover is still possible.
+func (f *failoverStream) forwardEvent() []byte {
+	raw := append([]byte(nil), f.event.Bytes()...)
+	f.event.Reset()
+
+	data := sseData(raw)
+	if data == "" {
+		f.pending = append(f.pending, raw...)
+		return nil
+	}
+	if data == "[DONE]" {
+		f.finished = true
+		f.pending = append(f.pending, raw...)
+		return nil
+	}
+
+	var chunk struct {
+		Error   json.RawMessage `json:"error"`
+		Choices []struct {
+			Index int `json:"index"`
+			Delta struct {
+				Content   string          `json:"content"`
+				ToolCalls json.RawMessage `json:"tool_calls"`
+			} `json:"delta"`
+			FinishReason *string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if json.Unmarshal([]byte(data), &chunk) != nil {
+		f.pending = append(f.pending, raw...)
+		return nil
+	}
+	if len(chunk.Error) > 0 && len(chunk.Choices) == 0 && !f.finished && f.canFailover() {
+		return raw
+	}
+
+	hasOutput := false
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" || len(choice.Delta.ToolCalls) > 0 {
+			hasOutput = true
+		}
+		if choice.Index != 0 || len(choice.Delta.ToolCalls) > 0 {
+			f.resumable = false
+		}
+		if choice.Index == 0 {
+			f.content.WriteString(choice.Delta.Content)
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			f.finished = true
+		}
+	}
+	if f.resuming && !hasOutput && !f.finished {
+		// The new stream repeats the role chunk the client already received
+		return nil
+	}
+	if hasOutput {
+		f.sent = true
+		f.resuming = false
+	}
+	f.forwarded = true
+	f.pending = append(f.pending, raw...)
+	return nil
+}
+
+// sseData joins the data lines of an SSE event.
+func sseData(event []byte) string {
+	var data []string
+	for _, line := range strings.Split(string(event), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return strings.Join(data, "\n")
+}
+
+// isTerminalEvent reports whether an event without its trailing blank line is the [DONE] marker.
+func isTerminalEvent(event []byte) bool {
+	return sseData(event) == "[DONE]"
+}
+
+// withAssistantPrefix returns a copy of the request whose messages end with the content that
+// was already streamed, so the next provider continues the answer instead of restarting it.
+func withAssistantPrefix(requestBody map[string]interface{}, prefix string) map[string]interface{} {
+	resumed := make(map[string]interface{}, len(requestBody))
+	for key, value := range requestBody {
+		resumed[key] = value
+	}
+	messages, _ := requestBody["messages"].([]interface{})
+	resumed["messages"] = append(append([]interface{}{}, messages...), map[string]interface{}{
+		"role":    "assistant",
+		"content": prefix,
+	})
+	return resumed
+}
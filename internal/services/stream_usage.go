@@ -0,0 +1,99 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"llm-fusion-engine/internal/core"
+)
+
+// withStreamUsage asks an OpenAI-compatible upstream to end a stream with a usage chunk by
+// setting stream_options.include_usage, and reports whether it changed the request. The
+// translating providers report usage on their own, and a provider whose backend rejects
+// stream_options can opt out with "streamUsage": false in its config. The check uses the
+// implementation's type rather than the configured one, so provider types that fall back to
+// the OpenAI-compatible implementation are included.
+func withStreamUsage(requestBody map[string]interface{}, provider core.IProvider, config map[string]interface{}) (map[string]interface{}, bool) {
+	if provider.Type() != "openai" || !isStreamRequest(requestBody) {
+		return requestBody, false
+	}
+	if enabled, ok := config["streamUsage"].(bool); ok && !enabled {
+		return requestBody, false
+	}
+	options, _ := requestBody["stream_options"].(map[string]interface{})
+	if _, set := options["include_usage"]; set {
+		return requestBody, false
+	}
+
+	withUsage := make(map[string]interface{}, len(requestBody)+1)
+	for key, value := range requestBody {
+		withUsage[key] = value
+	}
+	streamOptions := make(map[string]interface{}, len(options)+1)
+	for key, value := range options {
+		streamOptions[key] = value
+	}
+	streamOptions["include_usage"] = true
+	withUsage["stream_options"] = streamOptions
+	return withUsage, true
+}
+
+// usageChunkFilter drops the usage-only chunk that withStreamUsage asked for, so clients that
+// did not request it see the stream they expect. Every other event passes through unchanged.
+type usageChunkFilter struct {
+	body    io.ReadCloser
+	reader  *bufio.Reader
+	event   bytes.Buffer
+	pending []byte
+	err     error
+}
+
+func newUsageChunkFilter(body io.ReadCloser) *usageChunkFilter {
+	return &usageChunkFilter{body: body, reader: bufio.NewReader(body)}
+}
+
+func (u *usageChunkFilter) Read(p []byte) (int, error) {
+	for len(u.pending) == 0 {
+		if u.err != nil {
+			return 0, u.err
+		}
+		line, err := u.reader.ReadBytes('\n')
+		u.event.Write(line)
+		if err != nil {
+			u.pending = append(u.pending, u.event.Bytes()...)
+			u.event.Reset()
+			u.err = err
+			continue
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			if !isUsageOnlyEvent(u.event.Bytes()) {
+				u.pending = append(u.pending, u.event.Bytes()...)
+			}
+			u.event.Reset()
+		}
+	}
+	n := copy(p, u.pending)
+	u.pending = u.pending[n:]
+	return n, nil
+}
+
+func (u *usageChunkFilter) Close() error {
+	return u.body.Close()
+}
+
+// isUsageOnlyEvent reports whether an SSE event is a chunk with usage and no choices.
+func isUsageOnlyEvent(event []byte) bool {
+	data := sseData(event)
+	if data == "" || data == "[DONE]" {
+		return false
+	}
+	var chunk struct {
+		Usage   json.RawMessage   `json:"usage"`
+		Choices []json.RawMessage `json:"choices"`
+	}
+	if json.Unmarshal([]byte(data), &chunk) != nil {
+		return false
+	}
+	return len(chunk.Usage) > 0 && string(chunk.Usage) != "null" && len(chunk.Choices) == 0
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"io"
+	"llm-fusion-engine/internal/providers"
+	"strings"
+	"testing"
+)
+
+func TestWithStreamUsage(t *testing.T) {
+	stream := map[string]interface{}{"model": "m", "stream": true}
+	tests := []struct {
+		name         string
+		providerType string
+		body         map[string]interface{}
+		config       map[string]interface{}
+		want         bool
+	}{
+		{"openai stream", "openai", stream, nil, true},
+		{"openai-compatible fallback", "vllm", stream, nil, true},
+		{"translating provider", "anthropic", stream, nil, false},
+		{"not streaming", "openai", map[string]interface{}{"model": "m"}, nil, false},
+		{"opted out", "openai", stream, map[string]interface{}{"streamUsage": false}, false},
+		{"client already chose", "openai", map[string]interface{}{"stream": true, "stream_options": map[string]interface{}{"include_usage": false}}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := providers.DefaultRegistry().GetProvider(tt.providerType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, changed := withStreamUsage(tt.body, provider, tt.config)
+			if changed != tt.want {
+				t.Fatalf("changed = %v, want %v", changed, tt.want)
+			}
+			if !changed {
+				return
+			}
+			if options, _ := body["stream_options"].(map[string]interface{}); options["include_usage"] != true {
+				t.Errorf("stream_options = %v", body["stream_options"])
+			}
+			if _, set := tt.body["stream_options"]; set {
+				t.Error("the caller's request body was modified")
+			}
+		})
+	}
+}
+
+func TestUsageChunkFilter(t *testing.T) {
+	input := "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"total_tokens\":3}}\n\n" +
+		"data: [DONE]\n\n"
+	out, err := io.ReadAll(newUsageChunkFilter(io.NopCloser(strings.NewReader(input))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n"
+	if string(out) != want {
+		t.Errorf("filtered stream %q, want %q", out, want)
+	}
+}
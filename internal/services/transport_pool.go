@@ -0,0 +1,205 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"llm-fusion-engine/internal/database"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Transport defaults used when a provider config does not override them.
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 32
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultDialTimeout         = 10 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// transportConfigFields are the provider config keys that shape a provider's transport.
+// Timeouts are in seconds. caCert, clientCert and clientKey hold PEM data, or a file path
+// in caCertFile, clientCertFile and clientKeyFile.
+var transportConfigFields = []string{
+	"maxIdleConns", "maxIdleConnsPerHost", "maxConnsPerHost", "idleConnTimeout",
+	"http2", "dialTimeout", "tlsHandshakeTimeout", "proxy",
+	"caCert", "caCertFile", "clientCert", "clientCertFile", "clientKey", "clientKeyFile",
+}
+
+// TransportPool implements core.ITransportPool. It keeps one HTTP client per provider so
+// upstream connections and TLS sessions are reused across requests, and rebuilds a client
+// when the provider's transport settings change.
+type TransportPool struct {
+	mu      sync.Mutex
+	clients map[uint]*pooledClient
+}
+
+type pooledClient struct {
+	signature string
+	client    *http.Client
+	transport *http.Transport
+}
+
+// NewTransportPool creates an empty TransportPool.
+func NewTransportPool() *TransportPool {
+	return &TransportPool{clients: make(map[uint]*pooledClient)}
+}
+
+// Client returns the shared client for the provider. The client has no overall timeout;
+// callers bound each request with its context.
+func (p *TransportPool) Client(provider *database.Provider, config map[string]interface{}) (*http.Client, error) {
+	signature := transportSignature(config)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pooled, ok := p.clients[provider.ID]; ok {
+		if pooled.signature == signature {
+			return pooled.client, nil
+		}
+		// Settings changed: let in-flight requests finish on the old transport
+		pooled.transport.CloseIdleConnections()
+		delete(p.clients, provider.ID)
+	}
+
+	transport, err := newProviderTransport(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transport settings for provider %s: %w", provider.Name, err)
+	}
+	pooled := &pooledClient{
+		signature: signature,
+		client:    &http.Client{Transport: transport},
+		transport: transport,
+	}
+	p.clients[provider.ID] = pooled
+	log.Printf("[TransportPool] Created transport for provider %s", provider.Name)
+	return pooled.client, nil
+}
+
+// transportSignature identifies the transport settings of a config.
+func transportSignature(config map[string]interface{}) string {
+	settings := make(map[string]interface{}, len(transportConfigFields))
+	for _, field := range transportConfigFields {
+		if value, ok := config[field]; ok {
+			settings[field] = value
+		}
+	}
+	signature, _ := json.Marshal(settings)
+	return string(signature)
+}
+
+// newProviderTransport builds an http.Transport from a provider config.
+func newProviderTransport(config map[string]interface{}) (*http.Transport, error) {
+	dialer := &net.Dialer{
+		Timeout:   configDuration(config, "dialTimeout", DefaultDialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          configInt(config, "maxIdleConns", DefaultMaxIdleConns),
+		MaxIdleConnsPerHost:   configInt(config, "maxIdleConnsPerHost", DefaultMaxIdleConnsPerHost),
+		MaxConnsPerHost:       configInt(config, "maxConnsPerHost", 0),
+		IdleConnTimeout:       configDuration(config, "idleConnTimeout", DefaultIdleConnTimeout),
+		TLSHandshakeTimeout:   configDuration(config, "tlsHandshakeTimeout", DefaultTLSHandshakeTimeout),
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+
+	if http2, ok := config["http2"].(bool); ok && !http2 {
+		// A non-nil empty map disables the HTTP/2 upgrade
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	if proxy, ok := config["proxy"].(string); ok && proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := providerTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// providerTLSConfig adds a custom CA and a client certificate for self-hosted backends.
+func providerTLSConfig(config map[string]interface{}) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	caPEM, err := configPEM(config, "caCert", "caCertFile")
+	if err != nil {
+		return nil, err
+	}
+	if caPEM != nil {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("caCert contains no valid PEM certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certPEM, err := configPEM(config, "clientCert", "clientCertFile")
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := configPEM(config, "clientKey", "clientKeyFile")
+	if err != nil {
+		return nil, err
+	}
+	if (certPEM == nil) != (keyPEM == nil) {
+		return nil, errors.New("clientCert and clientKey must be configured together")
+	}
+	if certPEM != nil {
+		certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// configPEM reads PEM data inline from field or from the file named by fileField.
+func configPEM(config map[string]interface{}, field, fileField string) ([]byte, error) {
+	if data, ok := config[field].(string); ok && data != "" {
+		return []byte(data), nil
+	}
+	if path, ok := config[fileField].(string); ok && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fileField, err)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+func configInt(config map[string]interface{}, field string, fallback int) int {
+	if value, ok := config[field].(float64); ok && value >= 0 {
+		return int(value)
+	}
+	return fallback
+}
+
+func configDuration(config map[string]interface{}, field string, fallback time.Duration) time.Duration {
+	if seconds, ok := config[field].(float64); ok && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return fallback
+}
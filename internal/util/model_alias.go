@@ -0,0 +1,56 @@
+package util
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ResolveModelAlias rewrites a model name through an alias table and reports whether an
+// alias matched. Exact aliases win over wildcard ones, and among wildcard aliases the one
+// with the longest literal part wins. A "*" in an alias matches any run of characters and
+// replaces the "*" in its target, so "gpt-4*" -> "gpt-4o" maps every gpt-4 variant and
+// "openai/*" -> "*" strips a prefix.
+func ResolveModelAlias(aliases map[string]string, model string) (string, bool) {
+	if target, ok := aliases[model]; ok && !strings.Contains(model, "*") {
+		return target, true
+	}
+
+	patterns := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		if strings.Count(alias, "*") == 1 {
+			patterns = append(patterns, alias)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	for _, pattern := range patterns {
+		star := strings.Index(pattern, "*")
+		prefix, suffix := pattern[:star], pattern[star+1:]
+		if len(model) < len(prefix)+len(suffix) || !strings.HasPrefix(model, prefix) || !strings.HasSuffix(model, suffix) {
+			continue
+		}
+		captured := model[len(prefix) : len(model)-len(suffix)]
+		return strings.Replace(aliases[pattern], "*", captured, 1), true
+	}
+	return model, false
+}
+
+// ValidateModelAlias checks that an alias and its target form a usable pair.
+func ValidateModelAlias(alias, target string) error {
+	if alias == "" || target == "" {
+		return errors.New("alias and target must not be empty")
+	}
+	if strings.Count(alias, "*") > 1 {
+		return errors.New("an alias may contain at most one wildcard")
+	}
+	if strings.Count(target, "*") > 1 || (strings.Contains(target, "*") && !strings.Contains(alias, "*")) {
+		return errors.New("a target may only contain a wildcard when its alias has one")
+	}
+	return nil
+}
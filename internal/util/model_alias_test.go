@@ -0,0 +1,73 @@
+package util
+
+import "testing"
+
+func TestResolveModelAlias(t *testing.T) {
+	aliases := map[string]string{
+		"gpt4":            "gpt-4o",
+		"gpt-4*":          "gpt-4o",
+		"gpt-4-turbo*":    "gpt-4-turbo-2024-04-09",
+		"openai/*":        "*",
+		"claude-*-latest": "claude-*-20250514",
+		"*-mini":          "gpt-4o-mini",
+	}
+
+	tests := []struct {
+		name    string
+		model   string
+		want    string
+		matched bool
+	}{
+		{"exact alias", "gpt4", "gpt-4o", true},
+		{"wildcard alias", "gpt-4-0613", "gpt-4o", true},
+		{"wildcard matches an empty capture", "gpt-4", "gpt-4o", true},
+		{"longest literal part wins", "gpt-4-turbo-preview", "gpt-4-turbo-2024-04-09", true},
+		{"capture replaces the target wildcard", "openai/gpt-3.5-turbo", "gpt-3.5-turbo", true},
+		{"wildcard between prefix and suffix", "claude-sonnet-4-latest", "claude-sonnet-4-20250514", true},
+		{"suffix wildcard", "o3-mini", "gpt-4o-mini", true},
+		{"prefix and suffix may not overlap", "claude-latest", "claude-latest", false},
+		{"no alias", "gemini-2.5-pro", "gemini-2.5-pro", false},
+		{"a wildcard model is not an exact alias", "gpt-4*", "gpt-4o", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matched := ResolveModelAlias(aliases, tt.model)
+			if got != tt.want || matched != tt.matched {
+				t.Errorf("ResolveModelAlias(%q) = %q, %v; want %q, %v", tt.model, got, matched, tt.want, tt.matched)
+			}
+		})
+	}
+}
+
+func TestResolveModelAliasTies(t *testing.T) {
+	// Patterns of equal length are tried in lexical order so the result does not depend on map order
+	aliases := map[string]string{"a*c": "first", "ab*": "second"}
+	for i := 0; i < 20; i++ {
+		if got, _ := ResolveModelAlias(aliases, "abc"); got != "first" {
+			t.Fatalf("ResolveModelAlias(abc) = %q, want first", got)
+		}
+	}
+}
+
+func TestValidateModelAlias(t *testing.T) {
+	tests := []struct {
+		alias, target string
+		valid         bool
+	}{
+		{"gpt4", "gpt-4o", true},
+		{"gpt-4*", "gpt-4o", true},
+		{"openai/*", "*", true},
+		{"claude-*-latest", "claude-*-20250514", true},
+		{"", "gpt-4o", false},
+		{"gpt4", "", false},
+		{"*gpt*", "gpt-4o", false},
+		{"gpt-4*", "*-*", false},
+		{"gpt4", "gpt-4*", false},
+	}
+	for _, tt := range tests {
+		err := ValidateModelAlias(tt.alias, tt.target)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateModelAlias(%q, %q) = %v, want valid %v", tt.alias, tt.target, err, tt.valid)
+		}
+	}
+}
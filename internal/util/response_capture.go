@@ -0,0 +1,418 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sync"
+)
+
+const (
+	// maxSSELineSize bounds the partial line kept for parsing; longer lines pass through unparsed
+	maxSSELineSize = 1 << 20
+	// captureTailSize is how much of the end of a non-streaming body is kept to find its usage
+	captureTailSize = 16 << 10
+)
+
+var finishReasonPattern = regexp.MustCompile(`"finish_reason"\s*:\s*"([^"]+)"`)
+
+// TokenUsage is the token usage an upstream reported for a response.
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// UnmarshalJSON reads both the chat usage shape and the Responses API one, which reports
+// input_tokens and output_tokens.
+func (u *TokenUsage) UnmarshalJSON(data []byte) error {
+	var usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		InputTokens      int `json:"input_tokens"`
+		OutputTokens     int `json:"output_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return err
+	}
+	*u = TokenUsage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens, TotalTokens: usage.TotalTokens}
+	if u.PromptTokens == 0 && u.CompletionTokens == 0 {
+		u.PromptTokens, u.CompletionTokens = usage.InputTokens, usage.OutputTokens
+	}
+	return nil
+}
+
+// ResponseCapture passes an upstream body through unchanged while retaining at most limit
+// bytes of it for logging. Streaming bodies are parsed event by event as they are read, so
+// usage and finish reasons are known without buffering the stream; for other bodies they are
+// taken from the retained prefix, or from the tail when the body exceeds the limit.
+type ResponseCapture struct {
+	body       io.ReadCloser
+	streaming  bool
+	limit      int
+	onComplete func(*ResponseCapture)
+	once       sync.Once
+
+	retained  bytes.Buffer
+	truncated bool
+	size      int64
+	completed bool // Whether the body was read to its end
+
+	line         []byte // Partial SSE line being received
+	lineOverflow bool   // Whether the current line exceeded maxSSELineSize
+	tail         []byte // Last bytes of a non-streaming body
+
+	usage         TokenUsage
+	finishReasons []string
+
+	tokenizer        Tokenizer // Counts generated text for the fallback estimate, if set
+	promptTokens     int       // Estimated prompt tokens for the fallback
+	completionTokens int       // Tokens of the generated text seen so far
+	estimated        bool
+}
+
+// responseSummary is the part of a Responses API response object a capture reads.
+type responseSummary struct {
+	Status            string      `json:"status"`
+	Usage             *TokenUsage `json:"usage"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
+	Output []struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"output"`
+}
+
+// completionDelta is the generated part of a streamed choice or of a response message.
+type completionDelta struct {
+	Content          interface{} `json:"content"`
+	ReasoningContent string      `json:"reasoning_content"`
+	ToolCalls        []struct {
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+}
+
+// NewResponseCapture wraps body. onComplete, if set, runs once when the body has been read to
+// its end or closed, whichever comes first. A limit of zero or less retains nothing.
+func NewResponseCapture(body io.ReadCloser, streaming bool, limit int, onComplete func(*ResponseCapture)) *ResponseCapture {
+	return &ResponseCapture{body: body, streaming: streaming, limit: limit, onComplete: onComplete}
+}
+
+// Read reads from the upstream body and inspects what was read.
+func (rc *ResponseCapture) Read(p []byte) (int, error) {
+	n, err := rc.body.Read(p)
+	if n > 0 {
+		rc.observe(p[:n])
+	}
+	if err != nil {
+		rc.completed = err == io.EOF
+		rc.complete()
+	}
+	return n, err
+}
+
+// Close closes the upstream body and completes the capture if it has not completed yet.
+func (rc *ResponseCapture) Close() error {
+	err := rc.body.Close()
+	rc.complete()
+	return err
+}
+
+// EstimateUsage enables the fallback for upstreams that report no usage: the generated text
+// is counted with tokenizer as it passes, and promptTokens is taken as the prompt size.
+// It must be called before the body is read.
+func (rc *ResponseCapture) EstimateUsage(promptTokens int, tokenizer Tokenizer) {
+	rc.promptTokens = promptTokens
+	rc.tokenizer = tokenizer
+}
+
+// Body returns the retained part of the body.
+func (rc *ResponseCapture) Body() []byte {
+	return rc.retained.Bytes()
+}
+
+// Truncated reports whether the body was longer than the retention limit.
+func (rc *ResponseCapture) Truncated() bool {
+	return rc.truncated
+}
+
+// Size returns the number of bytes passed through.
+func (rc *ResponseCapture) Size() int64 {
+	return rc.size
+}
+
+// Completed reports whether the body was read to its end rather than closed early.
+func (rc *ResponseCapture) Completed() bool {
+	return rc.completed
+}
+
+// Usage returns the token usage reported by the upstream, or the local estimate when it
+// reported none and EstimateUsage was called.
+func (rc *ResponseCapture) Usage() TokenUsage {
+	return rc.usage
+}
+
+// UsageEstimated reports whether Usage is a local estimate.
+func (rc *ResponseCapture) UsageEstimated() bool {
+	return rc.estimated
+}
+
+// FinishReasons returns the distinct finish reasons seen, in order of appearance.
+func (rc *ResponseCapture) FinishReasons() []string {
+	return rc.finishReasons
+}
+
+func (rc *ResponseCapture) observe(data []byte) {
+	rc.size += int64(len(data))
+	if room := rc.limit - rc.retained.Len(); room > 0 {
+		if len(data) > room {
+			rc.retained.Write(data[:room])
+			rc.truncated = true
+		} else {
+			rc.retained.Write(data)
+		}
+	} else {
+		rc.truncated = true
+	}
+
+	if rc.streaming {
+		rc.scanLines(data)
+		return
+	}
+	rc.tail = append(rc.tail, data...)
+	if len(rc.tail) > captureTailSize {
+		rc.tail = append(rc.tail[:0], rc.tail[len(rc.tail)-captureTailSize:]...)
+	}
+}
+
+// scanLines feeds complete SSE lines to parseLine.
+func (rc *ResponseCapture) scanLines(data []byte) {
+	for len(data) > 0 {
+		newline := bytes.IndexByte(data, '\n')
+		if newline < 0 {
+			rc.appendLine(data)
+			return
+		}
+		rc.appendLine(data[:newline])
+		if !rc.lineOverflow {
+			rc.parseLine(rc.line)
+		}
+		rc.line = rc.line[:0]
+		rc.lineOverflow = false
+		data = data[newline+1:]
+	}
+}
+
+func (rc *ResponseCapture) appendLine(data []byte) {
+	if rc.lineOverflow {
+		return
+	}
+	if len(rc.line)+len(data) > maxSSELineSize {
+		rc.lineOverflow = true
+		rc.line = rc.line[:0]
+		return
+	}
+	rc.line = append(rc.line, data...)
+}
+
+// parseLine extracts usage and finish reasons from a "data:" line of an OpenAI-format stream.
+func (rc *ResponseCapture) parseLine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return
+	}
+	data := bytes.TrimSpace(line[len("data:"):])
+	if len(data) == 0 || data[0] != '{' {
+		return
+	}
+	var chunk struct {
+		Usage   *TokenUsage `json:"usage"`
+		Choices []struct {
+			Delta        completionDelta `json:"delta"`
+			Text         string          `json:"text"`
+			FinishReason *string         `json:"finish_reason"`
+		} `json:"choices"`
+		// Responses API events
+		Type     string           `json:"type"`
+		Delta    interface{}      `json:"delta"`
+		Response *responseSummary `json:"response"`
+	}
+	if json.Unmarshal(data, &chunk) != nil {
+		return
+	}
+	if chunk.Usage != nil {
+		rc.usage = *chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		rc.countCompletion(choice.Delta)
+		rc.countText(choice.Text)
+		if choice.FinishReason != nil {
+			rc.addFinishReason(*choice.FinishReason)
+		}
+	}
+
+	switch chunk.Type {
+	case "response.output_text.delta", "response.refusal.delta", "response.function_call_arguments.delta", "response.reasoning_summary_text.delta":
+		if delta, ok := chunk.Delta.(string); ok {
+			rc.countText(delta)
+		}
+	case "response.completed", "response.incomplete", "response.failed":
+		if chunk.Response != nil {
+			rc.readResponseSummary(*chunk.Response)
+		}
+	}
+}
+
+// readResponseSummary takes usage and the finish reason from a Responses API response object.
+// An incomplete response is recorded with the reason it stopped.
+func (rc *ResponseCapture) readResponseSummary(response responseSummary) {
+	if response.Usage != nil {
+		rc.usage = *response.Usage
+	}
+	if response.IncompleteDetails != nil && response.IncompleteDetails.Reason != "" {
+		rc.addFinishReason(response.IncompleteDetails.Reason)
+	} else {
+		rc.addFinishReason(response.Status)
+	}
+}
+
+// countText adds the tokens of a piece of generated text to the fallback estimate.
+func (rc *ResponseCapture) countText(text string) {
+	if rc.tokenizer != nil && text != "" {
+		rc.completionTokens += rc.tokenizer.Count(text)
+	}
+}
+
+// countCompletion adds the tokens of generated text to the fallback estimate. Streamed
+// deltas are counted one at a time, which matches the upstream closely since each delta
+// usually carries whole tokens.
+func (rc *ResponseCapture) countCompletion(delta completionDelta) {
+	if rc.tokenizer == nil {
+		return
+	}
+	switch content := delta.Content.(type) {
+	case string:
+		rc.completionTokens += rc.tokenizer.Count(content)
+	case []interface{}:
+		rc.completionTokens += countValueTokens(content, rc.tokenizer.Count)
+	}
+	rc.completionTokens += rc.tokenizer.Count(delta.ReasoningContent)
+	for _, call := range delta.ToolCalls {
+		rc.completionTokens += rc.tokenizer.Count(call.Function.Name) + rc.tokenizer.Count(call.Function.Arguments)
+	}
+}
+
+// parseBody extracts usage and finish reasons from a non-streaming body.
+func (rc *ResponseCapture) parseBody() {
+	if !rc.truncated {
+		var response struct {
+			Usage   *TokenUsage `json:"usage"`
+			Choices []struct {
+				Message      completionDelta `json:"message"`
+				Text         string          `json:"text"`
+				FinishReason *string         `json:"finish_reason"`
+			} `json:"choices"`
+			Object string `json:"object"`
+			responseSummary
+		}
+		if json.Unmarshal(rc.retained.Bytes(), &response) == nil {
+			if response.Usage != nil {
+				rc.usage = *response.Usage
+			}
+			for _, choice := range response.Choices {
+				rc.countCompletion(choice.Message)
+				rc.countText(choice.Text)
+				if choice.FinishReason != nil {
+					rc.addFinishReason(*choice.FinishReason)
+				}
+			}
+			if response.Object == "response" {
+				for _, item := range response.Output {
+					for _, part := range item.Content {
+						rc.countText(part.Text)
+					}
+					rc.countText(item.Name)
+					rc.countText(item.Arguments)
+				}
+				rc.readResponseSummary(response.responseSummary)
+			}
+			return
+		}
+	}
+
+	// The usage object closes an OpenAI-format response, so the tail is enough to find it
+	if index := bytes.LastIndex(rc.tail, []byte(`"usage"`)); index >= 0 {
+		rest := rc.tail[index+len(`"usage"`):]
+		if colon := bytes.IndexByte(rest, ':'); colon >= 0 {
+			var usage TokenUsage
+			if json.NewDecoder(bytes.NewReader(rest[colon+1:])).Decode(&usage) == nil {
+				rc.usage = usage
+			}
+		}
+	}
+	for _, match := range finishReasonPattern.FindAllSubmatch(rc.tail, -1) {
+		rc.addFinishReason(string(match[1]))
+	}
+}
+
+func (rc *ResponseCapture) addFinishReason(reason string) {
+	if reason == "" {
+		return
+	}
+	for _, seen := range rc.finishReasons {
+		if seen == reason {
+			return
+		}
+	}
+	rc.finishReasons = append(rc.finishReasons, reason)
+}
+
+func (rc *ResponseCapture) complete() {
+	rc.once.Do(func() {
+		if rc.streaming {
+			if len(rc.line) > 0 && !rc.lineOverflow {
+				rc.parseLine(rc.line)
+			}
+			rc.line = nil
+		} else {
+			rc.parseBody()
+			rc.tail = nil
+		}
+		if rc.usage.TotalTokens == 0 && rc.usage.PromptTokens+rc.usage.CompletionTokens > 0 {
+			rc.usage.TotalTokens = rc.usage.PromptTokens + rc.usage.CompletionTokens
+		}
+		if rc.usage.TotalTokens == 0 && rc.tokenizer != nil && rc.size > 0 {
+			rc.usage = TokenUsage{
+				PromptTokens:     rc.promptTokens,
+				CompletionTokens: rc.completionTokens,
+				TotalTokens:      rc.promptTokens + rc.completionTokens,
+			}
+			rc.estimated = true
+		}
+		if rc.onComplete != nil {
+			rc.onComplete(rc)
+		}
+	})
+}
+
+// PeekBody reads up to limit bytes of body and returns them together with a body that still
+// yields the complete content. Closing the returned body closes the original.
+func PeekBody(body io.ReadCloser, limit int) ([]byte, io.ReadCloser) {
+	if limit <= 0 {
+		return nil, body
+	}
+	peeked, _ := io.ReadAll(io.LimitReader(body, int64(limit)))
+	return peeked, struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), body), body}
+}
@@ -0,0 +1,299 @@
+package util
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens of a text for a model family.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// Encodings of the OpenAI model families, named after their tiktoken rank files.
+const (
+	EncodingCL100K = "cl100k_base"
+	EncodingO200K  = "o200k_base"
+)
+
+var (
+	encodingsMu sync.RWMutex
+	encodings   = make(map[string]*BPETokenizer)
+)
+
+// LoadTokenizerEncodings loads the tiktoken rank files (cl100k_base.tiktoken and
+// o200k_base.tiktoken) found in dir. Encodings without a file keep using the approximation.
+func LoadTokenizerEncodings(dir string) error {
+	for _, name := range []string{EncodingCL100K, EncodingO200K} {
+		path := filepath.Join(dir, name+".tiktoken")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		tokenizer, err := LoadBPETokenizer(path)
+		if err != nil {
+			return err
+		}
+		encodingsMu.Lock()
+		encodings[name] = tokenizer
+		encodingsMu.Unlock()
+	}
+	return nil
+}
+
+// TokenizerForModel returns the tokenizer for a model: the tiktoken encoding of an OpenAI
+// model family when its rank file is loaded, and an approximation otherwise.
+func TokenizerForModel(model string) Tokenizer {
+	if encoding := encodingForModel(model); encoding != "" {
+		encodingsMu.RLock()
+		tokenizer := encodings[encoding]
+		encodingsMu.RUnlock()
+		if tokenizer != nil {
+			return tokenizer
+		}
+	}
+	return approximateTokenizer{}
+}
+
+// encodingForModel maps an OpenAI model name to its encoding, or "" for other families.
+func encodingForModel(model string) string {
+	model = strings.ToLower(model[strings.LastIndex(model, "/")+1:])
+	for _, prefix := range []string{"gpt-4o", "chatgpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "gpt-oss", "o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return EncodingO200K
+		}
+	}
+	for _, prefix := range []string{"gpt-4", "gpt-3.5", "gpt-35", "text-embedding-3", "text-embedding-ada-002"} {
+		if strings.HasPrefix(model, prefix) {
+			return EncodingCL100K
+		}
+	}
+	return ""
+}
+
+// BPETokenizer is a byte-pair encoder over a tiktoken rank table.
+type BPETokenizer struct {
+	ranks map[string]int
+}
+
+// LoadBPETokenizer reads a tiktoken rank file: one base64 token and its rank per line.
+func LoadBPETokenizer(path string) (*BPETokenizer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid token in %s: %w", path, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank in %s: %w", path, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("no tokens in %s", path)
+	}
+	return &BPETokenizer{ranks: ranks}, nil
+}
+
+// Count splits text into pre-tokens and byte-pair encodes each of them.
+func (t *BPETokenizer) Count(text string) int {
+	tokens := 0
+	for _, piece := range splitPreTokens(text) {
+		tokens += t.countPiece([]byte(piece))
+	}
+	return tokens
+}
+
+// countPiece merges the lowest-ranked adjacent pair until no pair is in the table.
+func (t *BPETokenizer) countPiece(piece []byte) int {
+	if _, ok := t.ranks[string(piece)]; ok {
+		return 1
+	}
+	parts := make([][]byte, len(piece))
+	for i := range piece {
+		parts[i] = piece[i : i+1]
+	}
+	for len(parts) > 1 {
+		best, bestRank := -1, 0
+		for i := 0; i < len(parts)-1; i++ {
+			merged := piece[offset(piece, parts[i]) : offset(piece, parts[i+1])+len(parts[i+1])]
+			if rank, ok := t.ranks[string(merged)]; ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		start := offset(piece, parts[best])
+		parts[best] = piece[start : start+len(parts[best])+len(parts[best+1])]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return len(parts)
+}
+
+// offset returns where part, a subslice of piece, starts.
+func offset(piece, part []byte) int {
+	return cap(piece) - cap(part)
+}
+
+// shortWordRunes is the longest word the approximation counts as a single token.
+const shortWordRunes = 6
+
+// approximateTokenizer estimates BPE counts from the pre-token shapes: short words are a
+// single token, longer runs take about four characters per token, and CJK characters one each.
+type approximateTokenizer struct{}
+
+func (approximateTokenizer) Count(text string) int {
+	tokens := 0
+	for _, piece := range splitPreTokens(text) {
+		word := strings.TrimLeft(piece, " ")
+		if word == "" {
+			tokens++
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(word)
+		switch {
+		case isCJK(first):
+			tokens += utf8.RuneCountInString(word)
+		case unicode.IsLetter(first) || unicode.IsDigit(first):
+			if runes := utf8.RuneCountInString(word); runes > shortWordRunes {
+				tokens += (runes + charsPerToken - 1) / charsPerToken
+			} else {
+				tokens++
+			}
+		default:
+			tokens += (utf8.RuneCountInString(word) + 1) / 2
+		}
+	}
+	return tokens
+}
+
+// splitPreTokens approximates the tiktoken pre-tokenizer: contractions, letter runs with an
+// optional leading space, digit groups of up to three, punctuation runs with an optional
+// leading space, and whitespace runs. CJK text is split per character.
+func splitPreTokens(text string) []string {
+	var pieces []string
+	i := 0
+	for i < len(text) {
+		start := i
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		if r == '\'' && i+1 < len(text) {
+			if n := contractionLength(text[i:]); n > 0 {
+				pieces = append(pieces, text[i:i+n])
+				i += n
+				continue
+			}
+		}
+
+		// A single leading space belongs to the word or punctuation run that follows it
+		if r == ' ' && i+1 < len(text) {
+			next, _ := utf8.DecodeRuneInString(text[i+1:])
+			if !unicode.IsSpace(next) && !unicode.IsDigit(next) {
+				i++
+				r, size = next, utf8.RuneLen(next)
+			}
+		}
+
+		switch {
+		case isCJK(r):
+			i += size
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			i += size
+			for i < len(text) {
+				next, n := utf8.DecodeRuneInString(text[i:])
+				if isCJK(next) || !(unicode.IsLetter(next) || unicode.IsMark(next)) {
+					break
+				}
+				i += n
+			}
+		case unicode.IsDigit(r):
+			for digits := 0; i < len(text) && digits < 3; digits++ {
+				next, n := utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsDigit(next) {
+					break
+				}
+				i += n
+			}
+		case unicode.IsSpace(r):
+			for i < len(text) {
+				next, n := utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsSpace(next) {
+					break
+				}
+				i += n
+			}
+			// Leave the last space to prefix the next word
+			if i < len(text) && i-start > 1 && text[i-1] == ' ' {
+				i--
+			}
+		default:
+			i += size
+			for i < len(text) {
+				next, n := utf8.DecodeRuneInString(text[i:])
+				if unicode.IsSpace(next) || unicode.IsLetter(next) || unicode.IsDigit(next) || isCJK(next) {
+					break
+				}
+				i += n
+			}
+		}
+		pieces = append(pieces, text[start:i])
+	}
+	return pieces
+}
+
+// contractionLength returns the length of an English contraction suffix at the start of s.
+func contractionLength(s string) int {
+	lower := strings.ToLower(s)
+	for _, suffix := range []string{"'ll", "'ve", "'re", "'s", "'t", "'m", "'d"} {
+		if strings.HasPrefix(lower, suffix) {
+			return len(suffix)
+		}
+	}
+	return 0
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// CountPromptTokens counts the prompt tokens of an OpenAI-format request for a model,
+// including the per-message overhead of the chat format.
+func CountPromptTokens(requestBody map[string]interface{}, model string) int {
+	tokenizer := TokenizerForModel(model)
+	tokens := 0
+	if messages, ok := requestBody["messages"].([]interface{}); ok {
+		for _, message := range messages {
+			tokens += chatTokensPerMessage + countValueTokens(message, tokenizer.Count)
+		}
+		tokens += chatReplyPrimingTokens
+	}
+	for _, field := range []string{"instructions", "prompt", "input", "tools"} {
+		if value, ok := requestBody[field]; ok {
+			tokens += countValueTokens(value, tokenizer.Count)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,179 @@
+package util
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitPreTokens(t *testing.T) {
+	// Splits of the cl100k_base pre-tokenizer pattern, except that CJK text is split per character
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello world", []string{"Hello", " world"}},
+		{"I'm fine", []string{"I", "'m", " fine"}},
+		{"they'll", []string{"they", "'ll"}},
+		{"12345", []string{"123", "45"}},
+		{"a  b", []string{"a", " ", " b"}},
+		{"hello!!! ok", []string{"hello", "!!!", " ok"}},
+		{"Hello, world!", []string{"Hello", ",", " world", "!"}},
+		{"x = 1", []string{"x", " =", " ", "1"}},
+		{"line\n\nnext", []string{"line", "\n\n", "next"}},
+		{"你好", []string{"你", "好"}},
+	}
+	for _, tt := range tests {
+		if got := splitPreTokens(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPreTokens(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// writeRanks writes a tiktoken rank file with the given tokens in rank order.
+func writeRanks(t *testing.T, dir, name string, tokens []string) string {
+	t.Helper()
+	var b strings.Builder
+	for rank, token := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBPETokenizerMergeOrder(t *testing.T) {
+	// "ab" outranks "bc", so "abc" merges to ab+c; "bcd" is only reachable through "bc"
+	path := writeRanks(t, t.TempDir(), "test.tiktoken", []string{"ab", "bc", "cd", "bcd", "abcd", " x"})
+	tokenizer, err := LoadBPETokenizer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},   // In the table as a whole
+		{"abc", 2},    // ab + c
+		{"bcd", 1},    // bc, then bcd
+		{"abcbcd", 3}, // ab, then bc, then bcd
+		{"zz", 2},     // Unknown bytes stay single tokens
+		{"abcd x", 2},
+	}
+	for _, tt := range tests {
+		if got := tokenizer.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLoadBPETokenizerErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"empty", ""},
+		{"bad token", "!!!! 0\n"},
+		{"bad rank", base64.StdEncoding.EncodeToString([]byte("a")) + " x\n"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_"))
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadBPETokenizer(path); err == nil {
+			t.Errorf("%s: LoadBPETokenizer succeeded", tt.name)
+		}
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o", EncodingO200K},
+		{"gpt-4o-mini", EncodingO200K},
+		{"openai/GPT-4.1", EncodingO200K},
+		{"o3-mini", EncodingO200K},
+		{"gpt-5", EncodingO200K},
+		{"gpt-4", EncodingCL100K},
+		{"gpt-4-turbo", EncodingCL100K},
+		{"gpt-3.5-turbo", EncodingCL100K},
+		{"text-embedding-3-small", EncodingCL100K},
+		{"claude-sonnet-4", ""},
+		{"gemini-2.5-pro", ""},
+	}
+	for _, tt := range tests {
+		if got := encodingForModel(tt.model); got != tt.want {
+			t.Errorf("encodingForModel(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestApproximateTokenizer(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello world", 2},
+		{"Hello, world!", 4},
+		{"The quick brown fox jumps over the lazy dog.", 10},
+		{"internationalization", 5},
+		{"你好世界", 4},
+	}
+	for _, tt := range tests {
+		if got := (approximateTokenizer{}).Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestTiktokenCounts checks the BPE tokenizer against counts produced by tiktoken. The rank
+// files are not vendored; set TIKTOKEN_DIR to a directory holding cl100k_base.tiktoken and
+// o200k_base.tiktoken to run it.
+func TestTiktokenCounts(t *testing.T) {
+	dir := os.Getenv("TIKTOKEN_DIR")
+	if dir == "" {
+		t.Skip("TIKTOKEN_DIR not set")
+	}
+
+	tests := []struct {
+		encoding string
+		text     string
+		want     int
+	}{
+		{EncodingCL100K, "hello world", 2},
+		{EncodingCL100K, "Hello, world!", 4},
+		{EncodingCL100K, "tiktoken is great!", 6},
+		{EncodingCL100K, "The quick brown fox jumps over the lazy dog.", 10},
+		{EncodingCL100K, "antidisestablishmentarianism", 6},
+		{EncodingO200K, "hello world", 2},
+		{EncodingO200K, "Hello, world!", 4},
+	}
+	tokenizers := make(map[string]*BPETokenizer)
+	for _, tt := range tests {
+		tokenizer, ok := tokenizers[tt.encoding]
+		if !ok {
+			var err error
+			tokenizer, err = LoadBPETokenizer(filepath.Join(dir, tt.encoding+".tiktoken"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokenizers[tt.encoding] = tokenizer
+		}
+		if got := tokenizer.Count(tt.text); got != tt.want {
+			t.Errorf("%s Count(%q) = %d, want %d", tt.encoding, tt.text, got, tt.want)
+		}
+	}
+}
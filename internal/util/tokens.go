@@ -0,0 +1,97 @@
+package util
+
+import (
+	"encoding/json"
+)
+
+// Approximate tokenization constants used before the upstream reports real usage.
+const (
+	charsPerToken    = 4
+	tokensPerMessage = 4
+	imageTokens      = 85
+	defaultMaxTokens = 256
+
+	// Chat format overhead counted by CountPromptTokens
+	chatTokensPerMessage   = 3
+	chatReplyPrimingTokens = 3
+)
+
+// EstimateTokens approximates the token count of a text.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (len([]rune(text)) + charsPerToken - 1) / charsPerToken
+}
+
+// EstimateRequestTokens approximates the tokens an OpenAI-format request will consume:
+// the prompt (messages, prompt or input) plus the completion budget it asks for.
+func EstimateRequestTokens(requestBody map[string]interface{}) int {
+	tokens := 0
+	if messages, ok := requestBody["messages"].([]interface{}); ok {
+		for _, message := range messages {
+			tokens += tokensPerMessage + countValueTokens(message, EstimateTokens)
+		}
+	}
+	for _, field := range []string{"instructions", "prompt", "input", "tools"} {
+		if value, ok := requestBody[field]; ok {
+			tokens += countValueTokens(value, EstimateTokens)
+		}
+	}
+
+	completionBudget := defaultMaxTokens
+	for _, field := range []string{"max_completion_tokens", "max_tokens", "max_output_tokens"} {
+		if value, ok := requestBody[field].(float64); ok && value > 0 {
+			completionBudget = int(value)
+			break
+		}
+		if value, ok := requestBody[field].(int); ok && value > 0 {
+			completionBudget = value
+			break
+		}
+	}
+	if _, isChat := requestBody["messages"]; isChat || requestBody["prompt"] != nil {
+		tokens += completionBudget
+	}
+	return tokens
+}
+
+// countValueTokens counts the text in a JSON value. Message and content-part shapes are
+// walked so inline images count as a flat amount rather than by their base64 size.
+func countValueTokens(value interface{}, count func(string) int) int {
+	switch v := value.(type) {
+	case string:
+		return count(v)
+	case []interface{}:
+		tokens := 0
+		for _, item := range v {
+			tokens += countValueTokens(item, count)
+		}
+		return tokens
+	case map[string]interface{}:
+		if content, ok := v["content"]; ok {
+			name, _ := v["name"].(string)
+			tokens := countValueTokens(content, count) + count(name)
+			if toolCalls, ok := v["tool_calls"]; ok {
+				tokens += countJSONTokens(toolCalls, count)
+			}
+			return tokens
+		}
+		switch v["type"] {
+		case "text", "input_text":
+			text, _ := v["text"].(string)
+			return count(text)
+		case "image_url", "input_image", "image", "input_audio":
+			return imageTokens
+		}
+	}
+	return countJSONTokens(value, count)
+}
+
+func countJSONTokens(value interface{}, count func(string) int) int {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return count(string(encoded))
+}
@@ -8,15 +8,10 @@ export const useAuth = () => {
 
   useEffect(() => {
     if (isAuthenticated) {
-      // 在实际应用中，您应该在此处获取用户个人资料。
-      // 目前，我们只设置一个模拟用户。
-      // 注意：此模拟用户数据应在实现真实的用户获取逻辑后移除。
-      const mockUser: User = {
-        id: 1,
-        username: 'admin',
-        isAdmin: true,
-      };
-      setUser(mockUser);
+      authApi
+        .getProfile()
+        .then(setUser)
+        .catch(() => setUser(null));
     } else {
       setUser(null);
     }
@@ -28,8 +23,8 @@ export const useAuth = () => {
     setIsAuthenticated(true);
   };
 
-  const logout = () => {
-    authApi.logout();
+  const logout = async () => {
+    await authApi.logout();
     setUser(null);
     setIsAuthenticated(false);
   };
@@ -39,6 +39,7 @@ api.interceptors.response.use(
     // 处理 401 未授权错误
     if (error.response?.status === 401) {
       localStorage.removeItem('token')
+      localStorage.removeItem('refreshToken')
       window.location.href = '/login'
       return Promise.reject(new Error('未授权,请重新登录'))
     }
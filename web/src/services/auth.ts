@@ -1,17 +1,46 @@
 import api from './api';
-import { LoginRequest, LoginResponse, UpdateProfileRequest } from '../types';
+import { LoginRequest, LoginResponse, UpdateProfileRequest, User } from '../types';
 
 export const authApi = {
   async login(credentials: LoginRequest): Promise<LoginResponse> {
     const response = (await api.post('/auth/login', credentials)) as LoginResponse;
+    this.saveSession(response);
+    return response;
+  },
+
+  async refresh(): Promise<LoginResponse> {
+    const refreshToken = localStorage.getItem('refreshToken');
+    const response = (await api.post('/auth/refresh', { refreshToken })) as LoginResponse;
+    this.saveSession(response);
+    return response;
+  },
+
+  async logout() {
+    try {
+      if (this.getToken()) {
+        await api.post('/admin/account/logout');
+      }
+    } finally {
+      this.clearSession();
+    }
+  },
+
+  saveSession(response: LoginResponse) {
     if (response.token) {
       localStorage.setItem('token', response.token);
     }
-    return response;
+    if (response.refreshToken) {
+      localStorage.setItem('refreshToken', response.refreshToken);
+    }
   },
 
-  logout() {
+  clearSession() {
     localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
+  },
+
+  async getProfile(): Promise<User> {
+    return api.get('/admin/account/profile');
   },
 
   getToken(): string | null {
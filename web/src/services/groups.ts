@@ -9,6 +9,7 @@ import type {
   GroupHealthStatus,
   PaginationResponse,
   PaginationParams,
+  Provider,
 } from '../types'
 
 export const groupsApi = {
@@ -37,6 +38,16 @@ export const groupsApi = {
     return api.delete(`/admin/groups/${id}`)
   },
 
+  // 获取分组内的提供商
+  async getGroupProviders(id: number): Promise<Provider[]> {
+    return api.get(`/admin/groups/${id}/providers`)
+  },
+
+  // 设置分组内的提供商
+  async setGroupProviders(id: number, providerIds: number[]): Promise<void> {
+    return api.put(`/admin/groups/${id}/providers`, { providerIds })
+  },
+
   // 启用/禁用分组
   async toggleGroup(id: number, enabled: boolean): Promise<Group> {
     return api.patch(`/admin/groups/${id}`, { enabled })
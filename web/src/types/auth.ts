@@ -7,10 +7,14 @@ export interface User {
   id: number;
   username: string;
   isAdmin: boolean;
+  role: 'owner' | 'operator' | 'viewer' | 'billing';
 }
 
 export interface LoginResponse {
   token: string;
+  refreshToken: string;
+  expiresAt: string;
+  refreshExpiresAt: string;
   user: User;
 }
 
@@ -3,7 +3,9 @@
 export interface Log {
   id: string
   proxy_key: string
-  model: string
+  model: string // 发送给上游的模型名
+  requested_model?: string // 客户端请求的模型名
+  resolved_model?: string // 别名解析后的模型名
   provider: string
   request_url: string
   response_status: number
@@ -13,6 +15,16 @@ export interface Log {
   prompt_tokens: number
   completion_tokens: number
   total_tokens: number
+  client_aborted?: boolean // 客户端在响应完成前断开
+  response_size?: number // 响应体字节数
+  response_truncated?: boolean // response_body 仅保留了响应的前一部分
+  finish_reason?: string // 各 choice 的结束原因，逗号分隔
+  estimated?: boolean // 上游未返回用量，token 数为本地估算
+  cost?: number // 请求费用（美元），按模型单价计算
+  parent_id?: string // 重试或故障转移时，指向同一请求第一次尝试的日志 ID
+  attempt?: number // 本次上游尝试在该请求中的序号，从 1 开始
+  cache_hit?: boolean // 由响应缓存返回，未请求上游
+  similarity?: number // 语义缓存中最相近提示词的余弦相似度
   request_body?: string;
   response_body?: string;
 }
@@ -23,6 +35,8 @@ export interface LogQueryParams {
   model?: string
   provider?: string
   status?: number
+  requestId?: string // 查询某次请求的全部尝试
+  cacheHit?: boolean // 仅查询缓存命中（或未命中）的请求
   startDate?: string
   endDate?: string
 }
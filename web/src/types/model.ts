@@ -1,5 +1,14 @@
 // 模型相关类型定义
 
+export type LoadBalancePolicy =
+  | 'failover'
+  | 'weighted_random'
+  | 'round_robin'
+  | 'least_latency'
+  | 'least_in_flight';
+
+export type StreamFailoverMode = 'off' | 'retry' | 'resume';
+
 export interface Model {
   id: number;
   name: string; // e.g., "GPT-4-Turbo"
@@ -7,6 +16,16 @@ export interface Model {
   maxRetry: number;
   timeout: number; // in seconds
   enabled: boolean;
+  loadBalancePolicy?: LoadBalancePolicy | '';
+  streamFailover?: StreamFailoverMode | '';
+  retryPolicy?: string; // 重试策略 JSON，如 {"sameProviderRetries":1,"backoffMs":500}
+  semanticCacheModel?: string; // 语义缓存使用的 embedding 模型，留空则不启用
+  semanticCacheThreshold?: number; // 语义缓存命中所需的余弦相似度，0 表示默认 0.95
+  inputPrice?: number; // 每百万输入 token 的价格（美元）
+  outputPrice?: number; // 每百万输出 token 的价格（美元）
+  imagePrice?: number; // 每张生成图片的价格（美元）
+  audioPrice?: number; // 每分钟转写音频的价格（美元）
+  speechPrice?: number; // 每百万合成字符的价格（美元）
   createdAt: string;
   updatedAt: string;
 }
@@ -17,6 +36,16 @@ export interface CreateModelRequest {
   maxRetry?: number;
   timeout?: number;
   enabled?: boolean;
+  loadBalancePolicy?: LoadBalancePolicy | '';
+  streamFailover?: StreamFailoverMode | '';
+  retryPolicy?: string;
+  semanticCacheModel?: string;
+  semanticCacheThreshold?: number;
+  inputPrice?: number;
+  outputPrice?: number;
+  imagePrice?: number;
+  audioPrice?: number;
+  speechPrice?: number;
 }
 
 export interface UpdateModelRequest extends Partial<CreateModelRequest> {}
\ No newline at end of file
@@ -16,6 +16,9 @@ export interface ModelProviderMapping {
   toolCall?: boolean;
   structuredOutput?: boolean;
   image?: boolean;
+  imageGeneration?: boolean; // 为 false 时不参与 /v1/images/generations 路由
+  audioTranscription?: boolean; // 为 false 时不参与 /v1/audio/transcriptions 路由
+  speech?: boolean; // 为 false 时不参与 /v1/audio/speech 路由
   weight: number;
   enabled: boolean;
   model?: Model; // Preloaded model details
@@ -30,6 +33,9 @@ export interface CreateModelProviderMappingRequest {
   toolCall?: boolean;
   structuredOutput?: boolean;
   image?: boolean;
+  imageGeneration?: boolean;
+  audioTranscription?: boolean;
+  speech?: boolean;
   weight?: number;
   enabled?: boolean;
 }
@@ -10,6 +10,9 @@ export interface ProxyKey {
   groupWeights: string // JSON object for weighted balancing
   rpmLimit: number
   tpmLimit: number
+  loadBalancePolicy: string // Overrides the model's policy when set
+  cacheEnabled: boolean // 相同的确定性请求直接由响应缓存返回
+  cacheTtl: number // 缓存有效期（秒），0 表示默认 1 小时
   createdAt: string
   updatedAt: string
 }
@@ -23,6 +26,9 @@ export interface CreateProxyKeyRequest {
   groupWeights?: string
   rpmLimit?: number
   tpmLimit?: number
+  loadBalancePolicy?: string
+  cacheEnabled?: boolean
+  cacheTtl?: number
 }
 
 export interface UpdateProxyKeyRequest {
@@ -32,4 +38,7 @@ export interface UpdateProxyKeyRequest {
   groupWeights?: string
   rpmLimit?: number
   tpmLimit?: number
+  loadBalancePolicy?: string
+  cacheEnabled?: boolean
+  cacheTtl?: number
 }
\ No newline at end of file
@@ -29,6 +29,15 @@ export interface SystemStats {
   requestsChange?: number
   providers?: ProviderStats[]
   startTime: string
+  cache?: CacheStats
+}
+
+// 响应缓存自启动以来的命中统计
+export interface CacheStats {
+  hits: number
+  misses: number
+  hitRate: number // 百分比
+  entries: number // 内存中的缓存条数
 }
 
 export interface ProviderStats {